@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
 	"github.com/PixelzOrg/PHOLE.git/pkg/api"
 	"github.com/PixelzOrg/PHOLE.git/pkg/config"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/importer"
+	"github.com/PixelzOrg/PHOLE.git/pkg/managers"
 	"github.com/PixelzOrg/PHOLE.git/pkg/middleware"
+	"github.com/PixelzOrg/PHOLE.git/pkg/migration"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/fetcher"
 	"github.com/PixelzOrg/PHOLE.git/pkg/utils/firebase"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
 	"github.com/PixelzOrg/PHOLE.git/pkg/utils/supabase"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/gin-gonic/gin"
@@ -41,7 +51,6 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize Firebase")
 	}
-	defer fa.Close()
 
 	supabaseAuth := supabase.NewSupabaseAuth(c.SupabaseProjectURL, c.SupabaseAPIKey, redisClient)
 	if supabaseAuth == nil {
@@ -53,14 +62,119 @@ func main() {
 		log.Fatal().Msg("Failed to initialize Algolia")
 	}
 
-	//ctx := context.Background()
-	//log.Printf("Starting migration")
-	//err = utils.MigrateFromFirestore(ctx, fa, h, algoliaClient, "hitbox-games-bucket")
-	//err = utils.MigrateFromCSV(ctx, h, algoliaClient, "hitbox-games-bucket", "./games.csv")
-	//if err != nil {
-	//	log.Fatal().Err(err).Msg("Failed to migrate from Firestore")
-	//}
-	//log.Print("Migration completed successfully")
+	var storageBackend storage.Backend
+	switch c.StorageBackend {
+	case "firebase":
+		bucket, err := fa.Storage.DefaultBucket()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open Firebase Storage bucket")
+		}
+		storageBackend = storage.NewBucket(bucket, firebase.StorageBucket)
+	default:
+		storageBackend, err = storage.NewLocalDisk(c.StorageLocalPath, c.StoragePublicURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize local storage")
+		}
+	}
+
+	if err := managers.NewGenreManager(h, storageBackend).EnsureGenres(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to seed genres")
+	}
+
+	var searchBackend gamesearch.Backend
+	switch c.SearchBackend {
+	case "algolia":
+		searchBackend = gamesearch.NewAlgolia(algoliaClient, c.AlgoliaIndex)
+	default:
+		searchBackend, err = gamesearch.NewPostgres(h.DB)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize Postgres search")
+		}
+	}
+
+	var documentFetcher fetcher.Fetcher = fetcher.NewHTTP(10 * time.Second)
+	if c.ClaimFetcher == "stub" {
+		documentFetcher = fetcher.NewStub()
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reindex":
+			// Also applies the backend's index settings, so run it after any
+			// release that adds a searchable or filterable field.
+			count, err := gamesearch.Reindex(context.Background(), h.DB, searchBackend)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to rebuild search index")
+			}
+			log.Info().Int("games", count).Msg("Search index rebuilt")
+			return
+		case "backfill-analytics":
+			rows, err := analytics.Backfill(context.Background(), h.DB)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to backfill analytics")
+			}
+			log.Info().Int64("days", rows).Msg("Analytics backfilled")
+			return
+		case "import":
+			flags := flag.NewFlagSet("import", flag.ExitOnError)
+			var opts importer.Options
+			flags.StringVar(&opts.Format, "format", "", "csv or json, taken from the file extension by default")
+			flags.IntVar(&opts.BatchSize, "batch-size", 100, "rows committed per batch")
+			flags.BoolVar(&opts.DryRun, "dry-run", false, "report what would change without writing anything")
+			flags.BoolVar(&opts.SkipSearch, "skip-search", false, "don't queue imported games for search indexing")
+			flags.BoolVar(&opts.Restart, "restart", false, "start over instead of resuming an unfinished import of the same file")
+			flags.Parse(os.Args[2:])
+			if flags.NArg() != 1 {
+				log.Fatal().Msg("Usage: import [flags] <catalog.csv|catalog.json>")
+			}
+			opts.Path = flags.Arg(0)
+
+			report, err := importer.Run(context.Background(), h.DB, opts)
+			if report != nil {
+				report.Print(os.Stdout)
+			}
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to import catalog")
+			}
+			return
+		case "migrate-firestore":
+			flags := flag.NewFlagSet("migrate-firestore", flag.ExitOnError)
+			var opts migration.Options
+			flags.IntVar(&opts.PageSize, "page-size", 200, "documents written per transaction")
+			flags.BoolVar(&opts.Restart, "restart", false, "discard checkpoints and migrate everything again")
+			flags.BoolVar(&opts.VerifyOnly, "verify-only", false, "only compare Firestore with Postgres")
+			flags.IntVar(&opts.SampleSize, "sample", 25, "documents per collection compared field by field")
+			flags.Parse(os.Args[2:])
+
+			ctx := context.Background()
+			firestoreClient, err := fa.Firestore(ctx)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to open Firestore")
+			}
+			defer firestoreClient.Close()
+
+			report, err := migration.Run(ctx, h.DB, migration.NewFirestoreSource(firestoreClient), opts)
+			if report != nil {
+				report.Print(os.Stdout)
+			}
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to migrate Firestore")
+			}
+			if !report.OK() {
+				log.Warn().Msg("Verification found differences between Firestore and Postgres")
+			}
+			return
+		default:
+			log.Fatal().Str("command", os.Args[1]).Msg("Unknown command")
+		}
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go gamesearch.NewSyncer(h.DB, searchBackend).Run(workerCtx)
+	go analytics.RunBackfill(workerCtx, h.DB)
+	go services.NewTrendingService(h, redisClient, storageBackend).Run(workerCtx)
+	go services.NewSimilarityService(h, storageBackend).Run(workerCtx)
 
 	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
 
@@ -71,13 +185,16 @@ func main() {
 	r.Static("/docs", "../docs")
 	swaggerURL := ginSwagger.URL("/docs/swagger.json")
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, swaggerURL))
+	if c.StorageBackend != "firebase" {
+		r.Static("/media", c.StorageLocalPath)
+	}
 	//health check
 	r.GET("/health-check", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "healthy",
 		})
 	})
-	api.SetupRoutes(r, h, supabaseAuth, redisClient, algoliaClient)
+	api.SetupRoutes(r, c, h, supabaseAuth, redisClient, searchBackend, storageBackend, documentFetcher)
 
 	log.Info().Msg("🚀🚀🚀 Hitbox P-HOLE is running 🚀🚀🚀")
 	if err := r.Run(c.Port); err != nil {
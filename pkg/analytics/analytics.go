@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Delta is a change to one game's rollup for one day. Negative values undo
+// earlier events; counters never drop below zero.
+type Delta struct {
+	Plays          int64
+	UniquePlayers  int64
+	Sessions       int64
+	SessionSeconds int64
+	Likes          int64
+	Bookmarks      int64
+	Comments       int64
+}
+
+// DateLayout is how rollup days are written and parsed.
+const DateLayout = "2006-01-02"
+
+// Day is the rollup bucket an event at t falls into. Days are in UTC.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// dayParam formats the day explicitly so the database session's time zone
+// can't shift it.
+func dayParam(t time.Time) string {
+	return Day(t).Format(DateLayout)
+}
+
+// Record applies delta to the game's rollup for the day of at. Pass the
+// transaction that writes the event so both commit together.
+func Record(tx *gorm.DB, gameId string, at time.Time, delta Delta) error {
+	err := tx.Exec(`
+		INSERT INTO game_daily_stats
+			(game_id, day, plays, unique_players, sessions, session_seconds, likes, bookmarks, comments, updated_at)
+		VALUES (?, ?, GREATEST(?, 0), GREATEST(?, 0), GREATEST(?, 0), GREATEST(?, 0), GREATEST(?, 0), GREATEST(?, 0), GREATEST(?, 0), NOW())
+		ON CONFLICT (game_id, day) DO UPDATE SET
+			plays = GREATEST(game_daily_stats.plays + ?, 0),
+			unique_players = GREATEST(game_daily_stats.unique_players + ?, 0),
+			sessions = GREATEST(game_daily_stats.sessions + ?, 0),
+			session_seconds = GREATEST(game_daily_stats.session_seconds + ?, 0),
+			likes = GREATEST(game_daily_stats.likes + ?, 0),
+			bookmarks = GREATEST(game_daily_stats.bookmarks + ?, 0),
+			comments = GREATEST(game_daily_stats.comments + ?, 0),
+			updated_at = NOW()
+	`,
+		gameId, dayParam(at),
+		delta.Plays, delta.UniquePlayers, delta.Sessions, delta.SessionSeconds, delta.Likes, delta.Bookmarks, delta.Comments,
+		delta.Plays, delta.UniquePlayers, delta.Sessions, delta.SessionSeconds, delta.Likes, delta.Bookmarks, delta.Comments,
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to record analytics: %w", err)
+	}
+	return nil
+}
+
+// RecordPlay counts a play, and a unique player the first time the user
+// plays the game that day.
+func RecordPlay(tx *gorm.DB, gameId, userId string, at time.Time) error {
+	result := tx.Exec(`
+		INSERT INTO game_player_days (game_id, user_id, day) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`, gameId, userId, dayParam(at))
+	if result.Error != nil {
+		return fmt.Errorf("failed to record player: %w", result.Error)
+	}
+
+	return Record(tx, gameId, at, Delta{Plays: 1, UniquePlayers: result.RowsAffected})
+}
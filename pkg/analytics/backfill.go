@@ -0,0 +1,140 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	backfillInterval = 24 * time.Hour
+	backfillLock     = "analytics_backfill"
+)
+
+// Backfill builds rollups for past days that have none, from the raw
+// interaction tables. Days that already have a rollup are left alone, so it
+// is safe to run repeatedly and never double counts the live recording.
+//
+// Likes, bookmarks, comments and sessions are exact. recently_played and
+// user_game_interactions only keep the first and last day a user played, so
+// plays and players before live recording started are approximate.
+//
+// Only one instance backfills at a time; the others return right away.
+func Backfill(ctx context.Context, db *gorm.DB) (int64, error) {
+	var rows int64
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := database.TryAdvisoryXactLock(tx, backfillLock)
+		if err != nil {
+			return fmt.Errorf("failed to lock analytics backfill: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		if err := tx.Exec(`
+			INSERT INTO game_player_days (game_id, user_id, day)
+			SELECT game_id, user_id, day FROM (
+				SELECT game_id, user_id, (last_played_at AT TIME ZONE 'UTC')::date AS day FROM recently_played
+				UNION
+				SELECT game_id, user_id, (created_at AT TIME ZONE 'UTC')::date FROM user_game_interactions WHERE play_count > 0
+				UNION
+				SELECT game_id, user_id, (last_interaction AT TIME ZONE 'UTC')::date FROM user_game_interactions WHERE play_count > 0
+				UNION
+				SELECT game_id, user_id, (started_at AT TIME ZONE 'UTC')::date FROM play_sessions
+			) players
+			WHERE day < (NOW() AT TIME ZONE 'UTC')::date
+			AND NOT EXISTS (
+				SELECT 1 FROM game_daily_stats s WHERE s.game_id = players.game_id AND s.day = players.day
+			)
+			ON CONFLICT DO NOTHING
+		`).Error; err != nil {
+			return fmt.Errorf("failed to backfill players: %w", err)
+		}
+
+		result := tx.Exec(`
+			INSERT INTO game_daily_stats
+				(game_id, day, plays, unique_players, sessions, session_seconds, likes, bookmarks, comments, updated_at)
+			SELECT game_id, day, SUM(plays), SUM(unique_players), SUM(sessions), SUM(session_seconds),
+				SUM(likes), SUM(bookmarks), SUM(comments), NOW()
+			FROM (
+				SELECT game_id, (last_played_at AT TIME ZONE 'UTC')::date AS day, play_count AS plays, 0 AS unique_players,
+					0 AS sessions, 0 AS session_seconds, 0 AS likes, 0 AS bookmarks, 0 AS comments
+				FROM recently_played
+				UNION ALL
+				SELECT game_id, day, 0, COUNT(*), 0, 0, 0, 0, 0 FROM game_player_days GROUP BY game_id, day
+				UNION ALL
+				SELECT game_id, (started_at AT TIME ZONE 'UTC')::date, 0, 0, COUNT(*), SUM(duration), 0, 0, 0
+				FROM play_sessions GROUP BY 1, 2
+				UNION ALL
+				SELECT game_id, (created_at AT TIME ZONE 'UTC')::date, 0, 0, 0, 0, COUNT(*), 0, 0
+				FROM likes GROUP BY 1, 2
+				UNION ALL
+				SELECT game_id, (created_at AT TIME ZONE 'UTC')::date, 0, 0, 0, 0, 0, COUNT(*), 0
+				FROM bookmarks GROUP BY 1, 2
+				UNION ALL
+				SELECT game_id, (created_at AT TIME ZONE 'UTC')::date, 0, 0, 0, 0, 0, 0, COUNT(*)
+				FROM comments WHERE is_deleted = false GROUP BY 1, 2
+			) events
+			WHERE day < (NOW() AT TIME ZONE 'UTC')::date
+			GROUP BY game_id, day
+			ON CONFLICT (game_id, day) DO NOTHING
+		`)
+		if result.Error != nil {
+			return fmt.Errorf("failed to backfill rollups: %w", result.Error)
+		}
+		rows = result.RowsAffected
+
+		marker := models.AnalyticsBackfill{ID: 1, CompletedAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&marker).Error; err != nil {
+			return fmt.Errorf("failed to record analytics backfill: %w", err)
+		}
+		return nil
+	})
+
+	return rows, err
+}
+
+// RunBackfill runs Backfill once a day until the context is cancelled. The
+// first run waits until a day has passed since the last completed backfill,
+// so restarts don't repeat it.
+func RunBackfill(ctx context.Context, db *gorm.DB) {
+	timer := time.NewTimer(untilNextBackfill(ctx, db))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if rows, err := Backfill(ctx, db); err != nil {
+			log.Error().Err(err).Msg("Analytics backfill failed")
+		} else if rows > 0 {
+			log.Info().Int64("days", rows).Msg("Backfilled analytics rollups")
+		}
+		timer.Reset(backfillInterval)
+	}
+}
+
+func untilNextBackfill(ctx context.Context, db *gorm.DB) time.Duration {
+	var marker models.AnalyticsBackfill
+	err := db.WithContext(ctx).First(&marker).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to check the last analytics backfill")
+		return 0
+	}
+	if wait := time.Until(marker.CompletedAt.Add(backfillInterval)); wait > 0 {
+		return wait
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+type AchievementHandler struct {
+	service *services.AchievementService
+}
+
+func NewAchievementHandler(service *services.AchievementService) *AchievementHandler {
+	return &AchievementHandler{service: service}
+}
+
+// ListAchievements godoc
+// @Summary List a game's achievements
+// @Description Get a game's achievements and when the viewer unlocked them. Hidden achievements only show their points until unlocked
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.AchievementsResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements [get]
+func (ah *AchievementHandler) ListAchievements(c *gin.Context) {
+	res, err := ah.service.ListAchievements(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to list achievements")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// AchievementStats godoc
+// @Summary Get achievement unlock rates
+// @Description Get the percentage of the game's players who unlocked each achievement. Only the game's creator or an admin can see this
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.AchievementStatsResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements/stats [get]
+func (ah *AchievementHandler) AchievementStats(c *gin.Context) {
+	res, err := ah.service.AchievementStats(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get achievement stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateAchievement godoc
+// @Summary Create an achievement
+// @Description Define an achievement for a game. The key defaults to a slug of the name
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.CreateAchievementRequest true "Achievement details"
+// @Success 201 {object} types.AchievementResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements [post]
+func (ah *AchievementHandler) CreateAchievement(c *gin.Context) {
+	var req types.CreateAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := ah.service.CreateAchievement(c.Param("gameId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create achievement")
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// UpdateAchievement godoc
+// @Summary Update an achievement
+// @Description Edit an achievement. Changing its points updates the totals of players who already unlocked it
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param key path string true "Achievement key"
+// @Param request body types.UpdateAchievementRequest true "Fields to change"
+// @Success 200 {object} types.AchievementResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements/{key} [patch]
+func (ah *AchievementHandler) UpdateAchievement(c *gin.Context) {
+	var req types.UpdateAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := ah.service.UpdateAchievement(c.Param("gameId"), c.Param("key"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// DeleteAchievement godoc
+// @Summary Delete an achievement
+// @Description Delete an achievement and remove it from every player who unlocked it
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param key path string true "Achievement key"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements/{key} [delete]
+func (ah *AchievementHandler) DeleteAchievement(c *gin.Context) {
+	if err := ah.service.DeleteAchievement(c.Request.Context(), c.Param("gameId"), c.Param("key"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to delete achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Achievement deleted"})
+}
+
+// UploadAchievementIcon godoc
+// @Summary Upload an achievement icon
+// @Description Upload a PNG, JPEG, WebP or GIF icon (max 5MB) for an achievement
+// @Tags achievements
+// @Accept multipart/form-data
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param key path string true "Achievement key"
+// @Param icon formData file true "Icon image"
+// @Success 200 {object} types.AchievementResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements/{key}/icon [post]
+func (ah *AchievementHandler) UploadAchievementIcon(c *gin.Context) {
+	fileHeader, err := c.FormFile("icon")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Icon file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxThumbnailSize {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Icon is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Failed to read icon"})
+		return
+	}
+	defer file.Close()
+
+	res, err := ah.service.UploadIcon(c.Request.Context(), c.Param("gameId"), c.Param("key"), c.GetString("userId"), file)
+	if err != nil {
+		respondWithError(c, err, "Failed to upload icon")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// UnlockAchievement godoc
+// @Summary Unlock an achievement
+// @Description Unlock an achievement for the authenticated user, who must have played the game. Unlocking it again has no effect. Achievements can require the unlock to be signed with the game's score secret
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param key path string true "Achievement key"
+// @Param request body types.UnlockAchievementRequest false "Signed unlock"
+// @Success 200 {object} types.UnlockAchievementResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/achievements/{key}/unlock [post]
+func (ah *AchievementHandler) UnlockAchievement(c *gin.Context) {
+	// The body is optional, so an empty one is fine.
+	var req types.UnlockAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := ah.service.UnlockAchievement(c.Param("gameId"), c.Param("key"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to unlock achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// ListUserAchievements godoc
+// @Summary List a user's achievements
+// @Description Get the achievements a user has unlocked, most recent first
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /users/{userId}/achievements [get]
+func (ah *AchievementHandler) ListUserAchievements(c *gin.Context) {
+	var query types.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid pagination parameters"})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	res, err := ah.service.ListUserAchievements(c.Param("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list achievements")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminHandler struct {
+	databaseHandler database.Handler
+}
+
+func NewAdminHandler(databaseHandler database.Handler) *AdminHandler {
+	return &AdminHandler{databaseHandler: databaseHandler}
+}
+
+// SearchSyncStatus godoc
+// @Summary Search index sync status
+// @Description Report how many game changes are still waiting to reach the search index. Admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} gamesearch.OutboxStats
+// @Failure 500 {object} types.ErrorResponse
+// @Router /admin/search/status [get]
+func (ah *AdminHandler) SearchSyncStatus(c *gin.Context) {
+	stats, err := gamesearch.Stats(ah.databaseHandler.DB)
+	if err != nil {
+		respondWithError(c, err, "Failed to get search sync status")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
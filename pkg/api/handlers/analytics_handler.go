@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AnalyticsHandler struct {
+	service *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(service *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetGameAnalytics godoc
+// @Summary Get analytics for a game
+// @Description Daily plays, unique players, average session length, likes, bookmarks, comments and D1/D7 retention for a game. Days are in UTC. Creator or admin only
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param from query string false "First day, YYYY-MM-DD. Defaults to 29 days before to"
+// @Param to query string false "Last day, YYYY-MM-DD. Defaults to today"
+// @Success 200 {object} types.GameAnalyticsResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/analytics [get]
+func (ah *AnalyticsHandler) GetGameAnalytics(c *gin.Context) {
+	var query types.GameAnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	res, err := ah.service.GetGameAnalytics(c.Param("gameId"), c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to get analytics")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ClaimHandler struct {
+	service *services.ClaimService
+}
+
+func NewClaimHandler(service *services.ClaimService) *ClaimHandler {
+	return &ClaimHandler{service: service}
+}
+
+// CreateClaimByGameId godoc
+// @Summary Claim an imported game
+// @Description Submit a claim for an unclaimed game. The response contains a verification token to publish at the verification URL on the game's origin
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.CreateClaimRequest true "Claim details"
+// @Success 201 {object} types.ClaimResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/claims [post]
+func (ch *ClaimHandler) CreateClaimByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+
+	var req types.CreateClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	claim, err := ch.service.CreateClaim(gameId, userId, req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create claim")
+		return
+	}
+
+	verificationURL, _ := services.ClaimVerificationURL(claim.Game)
+	c.JSON(http.StatusCreated, types.ClaimResponse{Claim: claim, VerificationURL: verificationURL})
+}
+
+// GetClaimById godoc
+// @Summary Get a claim
+// @Description Get a claim and its audit trail. Only the claimant or an admin can view it
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param claimId path string true "Claim ID"
+// @Success 200 {object} types.ClaimResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /claims/{claimId} [get]
+func (ch *ClaimHandler) GetClaimById(c *gin.Context) {
+	claim, err := ch.service.GetClaimById(c.Param("claimId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get claim")
+		return
+	}
+
+	verificationURL, _ := services.ClaimVerificationURL(claim.Game)
+	c.JSON(http.StatusOK, types.ClaimResponse{Claim: claim, VerificationURL: verificationURL})
+}
+
+// ListClaims godoc
+// @Summary List claims
+// @Description List claims filtered by status and game. Admins see every claim, other users only see their own
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, approved or rejected"
+// @Param game_id query string false "Game ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /claims [get]
+func (ch *ClaimHandler) ListClaims(c *gin.Context) {
+	var query types.ListClaimsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	claims, err := ch.service.ListClaims(c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list claims")
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+// VerifyClaim godoc
+// @Summary Verify a claim
+// @Description Check that the claim's verification token is published on the game's origin
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param claimId path string true "Claim ID"
+// @Success 200 {object} types.ClaimResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /claims/{claimId}/verify [post]
+func (ch *ClaimHandler) VerifyClaim(c *gin.Context) {
+	claim, err := ch.service.VerifyClaim(c.Request.Context(), c.Param("claimId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to verify claim")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ClaimResponse{Claim: claim})
+}
+
+// ApproveClaim godoc
+// @Summary Approve a claim
+// @Description Approve a pending claim, making the claimant the creator of the game. Admin only
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param claimId path string true "Claim ID"
+// @Param request body types.ReviewClaimRequest false "Review note"
+// @Success 200 {object} types.ClaimResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /claims/{claimId}/approve [post]
+func (ch *ClaimHandler) ApproveClaim(c *gin.Context) {
+	var req types.ReviewClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	claim, err := ch.service.ApproveClaim(c.Param("claimId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to approve claim")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ClaimResponse{Claim: claim})
+}
+
+// RejectClaim godoc
+// @Summary Reject a claim
+// @Description Reject a pending claim. Admin only
+// @Tags claims
+// @Accept json
+// @Produce json
+// @Param claimId path string true "Claim ID"
+// @Param request body types.ReviewClaimRequest false "Review note"
+// @Success 200 {object} types.ClaimResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /claims/{claimId}/reject [post]
+func (ch *ClaimHandler) RejectClaim(c *gin.Context) {
+	var req types.ReviewClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	claim, err := ch.service.RejectClaim(c.Param("claimId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to reject claim")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ClaimResponse{Claim: claim})
+}
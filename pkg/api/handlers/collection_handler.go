@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type CollectionHandler struct {
+	service *services.CurationService
+}
+
+func NewCollectionHandler(service *services.CurationService) *CollectionHandler {
+	return &CollectionHandler{service: service}
+}
+
+// ListCollections godoc
+// @Summary List collections
+// @Description Get the curated collections that are currently live, newest first
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /collections [get]
+func (ch *CollectionHandler) ListCollections(c *gin.Context) {
+	ch.listCollections(c, false)
+}
+
+// ListAllCollections godoc
+// @Summary List all collections
+// @Description Get every collection including scheduled and expired ones. Admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /admin/collections [get]
+func (ch *CollectionHandler) ListAllCollections(c *gin.Context) {
+	ch.listCollections(c, true)
+}
+
+func (ch *CollectionHandler) listCollections(c *gin.Context, includeAll bool) {
+	var query types.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid pagination parameters"})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	collections, err := ch.service.ListCollections(query, includeAll)
+	if err != nil {
+		respondWithError(c, err, "Failed to list collections")
+		return
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+
+// GetCollection godoc
+// @Summary Get a collection
+// @Description Get a live collection and its games in order. Admins can also preview collections outside their schedule through /admin/collections/{slug}
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param slug path string true "Collection slug"
+// @Success 200 {object} types.CollectionResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /collections/{slug} [get]
+func (ch *CollectionHandler) GetCollection(c *gin.Context) {
+	collection, err := ch.service.GetCollection(c.Param("slug"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// CreateCollection godoc
+// @Summary Create a collection
+// @Description Create a curated collection with an ordered list of games and an optional schedule. The slug defaults to one derived from the title. Admin only
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param request body types.CreateCollectionRequest true "Collection details"
+// @Success 201 {object} types.CollectionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /collections [post]
+func (ch *CollectionHandler) CreateCollection(c *gin.Context) {
+	var req types.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	collection, err := ch.service.CreateCollection(c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create collection")
+		return
+	}
+
+	c.JSON(http.StatusCreated, collection)
+}
+
+// UpdateCollection godoc
+// @Summary Update a collection
+// @Description Change the title, description or schedule of a collection. Admin only
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param slug path string true "Collection slug"
+// @Param request body types.UpdateCollectionRequest true "Fields to change"
+// @Success 200 {object} types.CollectionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /collections/{slug} [patch]
+func (ch *CollectionHandler) UpdateCollection(c *gin.Context) {
+	var req types.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	collection, err := ch.service.UpdateCollection(c.Param("slug"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// SetCollectionGames godoc
+// @Summary Set the games in a collection
+// @Description Replace the games in a collection. Games are shown in the order given. Admin only
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param slug path string true "Collection slug"
+// @Param request body types.SetCollectionGamesRequest true "Ordered game IDs"
+// @Success 200 {object} types.CollectionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /collections/{slug}/games [put]
+func (ch *CollectionHandler) SetCollectionGames(c *gin.Context) {
+	var req types.SetCollectionGamesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	collection, err := ch.service.SetCollectionGames(c.Param("slug"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to set collection games")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection godoc
+// @Summary Delete a collection
+// @Description Delete a collection. The games themselves are not affected. Admin only
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param slug path string true "Collection slug"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /collections/{slug} [delete]
+func (ch *CollectionHandler) DeleteCollection(c *gin.Context) {
+	if err := ch.service.DeleteCollection(c.Param("slug")); err != nil {
+		respondWithError(c, err, "Failed to delete collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Collection deleted"})
+}
+
+// ListFeaturedGames godoc
+// @Summary List featured game schedules
+// @Description Get featured game entries, optionally filtered by whether they are active, scheduled or expired. Admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param status query string false "active, scheduled or expired"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /admin/featured [get]
+func (ch *CollectionHandler) ListFeaturedGames(c *gin.Context) {
+	var query types.ListFeaturedGamesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	featured, err := ch.service.ListFeaturedGames(query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list featured games")
+		return
+	}
+
+	c.JSON(http.StatusOK, featured)
+}
+
+// CreateFeaturedGame godoc
+// @Summary Feature a game
+// @Description Schedule a game into the featured slots of the feed. startsAt defaults to now and a missing endsAt features the game until removed. Admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body types.CreateFeaturedGameRequest true "Featuring schedule"
+// @Success 201 {object} models.FeaturedGame
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /admin/featured [post]
+func (ch *CollectionHandler) CreateFeaturedGame(c *gin.Context) {
+	var req types.CreateFeaturedGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	featured, err := ch.service.CreateFeaturedGame(c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to feature game")
+		return
+	}
+
+	c.JSON(http.StatusCreated, featured)
+}
+
+// DeleteFeaturedGame godoc
+// @Summary Stop featuring a game
+// @Description Remove a featured game entry. Admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param featuredId path string true "Featured game entry ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /admin/featured/{featuredId} [delete]
+func (ch *CollectionHandler) DeleteFeaturedGame(c *gin.Context) {
+	if err := ch.service.DeleteFeaturedGame(c.Param("featuredId")); err != nil {
+		respondWithError(c, err, "Failed to remove featured game")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Featured game removed"})
+}
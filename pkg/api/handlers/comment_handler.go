@@ -57,7 +57,7 @@ func (cs *CommentHandler) CreateCommentByGameId(c *gin.Context) {
 
 	comment, err := cs.service.CreateCommentByGameId(gameId, userId, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithError(c, err, "Failed to create comment")
 		return
 	}
 
@@ -82,6 +82,7 @@ func (cs *CommentHandler) CreateCommentByGameId(c *gin.Context) {
 // @Param gameId path string true "Game ID"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
 // @Router /games/{gameId}/comments/get [post]
 func (cs *CommentHandler) GetCommentsByGameId(c *gin.Context) {
@@ -93,7 +94,7 @@ func (cs *CommentHandler) GetCommentsByGameId(c *gin.Context) {
 
 	comments, totalItems, err := cs.service.GetCommentsByGameId(req.GameID, req.Pagination.Page, req.Pagination.PageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+		respondWithError(c, err, "Failed to get comments")
 		return
 	}
 
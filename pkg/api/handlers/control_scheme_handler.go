@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type ControlSchemeHandler struct {
+	service *services.ControlSchemeService
+}
+
+func NewControlSchemeHandler(service *services.ControlSchemeService) *ControlSchemeHandler {
+	return &ControlSchemeHandler{service: service}
+}
+
+// GetControlScheme godoc
+// @Summary Get a game's control scheme
+// @Description Get the on-screen controls for a game, the current version unless one is given
+// @Tags controls
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param version query int false "Version to get"
+// @Success 200 {object} models.ControlScheme
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/controls [get]
+func (ch *ControlSchemeHandler) GetControlScheme(c *gin.Context) {
+	version := 0
+	if v := c.Query("version"); v != "" {
+		var err error
+		version, err = strconv.Atoi(v)
+		if err != nil || version < 1 {
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid version"})
+			return
+		}
+	}
+
+	scheme, err := ch.service.GetControlScheme(c.Param("gameId"), version)
+	if err != nil {
+		respondWithError(c, err, "Failed to get control scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, scheme)
+}
+
+// ListControlSchemeVersions godoc
+// @Summary List control scheme versions
+// @Description Get every saved version of a game's control scheme, newest first. Only the game's creator or an admin can see this
+// @Tags controls
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.ControlSchemeVersionsResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/controls/versions [get]
+func (ch *ControlSchemeHandler) ListControlSchemeVersions(c *gin.Context) {
+	res, err := ch.service.ListControlSchemeVersions(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to list control scheme versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// PutControlScheme godoc
+// @Summary Save a control scheme
+// @Description Save a new version of a game's on-screen controls. Send baseVersion to reject the edit if someone else saved in the meantime
+// @Tags controls
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.PutControlSchemeRequest true "Orientation and layout"
+// @Success 200 {object} models.ControlScheme
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/controls [put]
+func (ch *ControlSchemeHandler) PutControlScheme(c *gin.Context) {
+	var req types.PutControlSchemeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	scheme, err := ch.service.PutControlScheme(c.Param("gameId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to save control scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, scheme)
+}
+
+// DeleteControlScheme godoc
+// @Summary Delete a control scheme
+// @Description Delete every version of a game's control scheme
+// @Tags controls
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/controls [delete]
+func (ch *ControlSchemeHandler) DeleteControlScheme(c *gin.Context) {
+	if err := ch.service.DeleteControlScheme(c.Param("gameId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to delete control scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Control scheme deleted"})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// respondWithError maps the sentinel errors in types to a status code. Errors
+// that don't wrap one of them are reported as a 500 with the fallback message.
+func respondWithError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, types.ErrNotFound):
+		c.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrForbidden):
+		c.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrConflict):
+		c.JSON(http.StatusConflict, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrPreconditionRequired):
+		c.JSON(http.StatusPreconditionRequired, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, types.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: fallback})
+	}
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
 	"github.com/PixelzOrg/PHOLE.git/pkg/services"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 	"net/http"
 	"strconv"
 )
@@ -12,23 +14,30 @@ import (
 type GameHandler struct {
 	gameService           *services.GameService
 	recommendationService *services.RecommendationService
+	curationService       *services.CurationService
 }
 
-func NewGameHandler(gameService *services.GameService, recommendationService *services.RecommendationService) *GameHandler {
+func NewGameHandler(gameService *services.GameService, recommendationService *services.RecommendationService, curationService *services.CurationService) *GameHandler {
 	return &GameHandler{
 		gameService:           gameService,
 		recommendationService: recommendationService,
+		curationService:       curationService,
 	}
 }
 
 // Feed godoc
 // @Summary Get a feed of recommended games
-// @Description Get a paginated feed of recommended games for the user or fallback recommendations for anonymous users
+// @Description Get a paginated feed of recommended games for the user or fallback recommendations for anonymous users. The first page has featured games placed at the configured slots. Games the described device can't play are left out and ones that don't fit its screen come last
 // @Tags games
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number"
 // @Param limit query int false "Limit per page"
+// @Param orientation query string false "Orientation the screen is locked to, portrait or landscape"
+// @Param touch query bool false "The device has a touch screen"
+// @Param keyboard query bool false "The device has a keyboard"
+// @Param gamepad query bool false "The device has a gamepad"
+// @Param screen query string false "phone, tablet or desktop"
 // @Success 200 {object} types.FeedResponse
 // @Failure 400 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
@@ -37,15 +46,27 @@ func (gh *GameHandler) Feed(c *gin.Context) {
 	userId := c.GetString("userId")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "25"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 25
+	}
+
+	var device capability.Device
+	if err := c.ShouldBindQuery(&device); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
 
 	var games []models.Game
 	var totalGames int64
 	var err error
 
 	if userId != "" {
-		games, totalGames, err = gh.recommendationService.GetRecommendations(userId, page, limit)
+		games, totalGames, err = gh.recommendationService.GetRecommendations(userId, device, page, limit)
 	} else {
-		games, totalGames, err = gh.recommendationService.GetFallbackRecommendations(page, limit)
+		games, totalGames, err = gh.recommendationService.GetFallbackRecommendations(device, page, limit)
 	}
 
 	if err != nil {
@@ -53,8 +74,16 @@ func (gh *GameHandler) Feed(c *gin.Context) {
 		return
 	}
 
+	// Featured games only go on the first page, and are taken out of the
+	// others so they aren't repeated.
+	if featured, err := gh.curationService.InjectFeatured(games, device, page, limit); err != nil {
+		log.Warn().Err(err).Msg("Failed to inject featured games")
+	} else {
+		games = featured
+	}
+
 	res := types.FeedResponse{
-		Games:      games,
+		Games:      gh.gameService.Summaries(games),
 		TotalGames: totalGames,
 		Page:       page,
 		Limit:      limit,
@@ -63,26 +92,80 @@ func (gh *GameHandler) Feed(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+// SearchGames godoc
+// @Summary Search games
+// @Description Full text search over game titles and descriptions with optional filters. Matches are highlighted with <em> where the backend supports it. Games the described device can't play are left out and ones that don't fit its screen rank last
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param q query string false "Search text"
+// @Param genre_id query string false "Genre ID"
+// @Param tag query string false "Tag name"
+// @Param game_type query string false "html5, unity or godot"
+// @Param landscape query bool false "Only landscape or only portrait games"
+// @Param orientation query string false "Orientation the screen is locked to, portrait or landscape"
+// @Param touch query bool false "The device has a touch screen"
+// @Param keyboard query bool false "The device has a keyboard"
+// @Param gamepad query bool false "The device has a gamepad"
+// @Param screen query string false "phone, tablet or desktop"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /games/search [get]
+func (gh *GameHandler) SearchGames(c *gin.Context) {
+	var query types.SearchGamesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	results, err := gh.gameService.SearchGames(c.Request.Context(), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to search games")
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
 // GameDetailsByGameId godoc
 // @Summary Get details of a game by game ID
-// @Description Get details of a game by game ID
+// @Description Get details of a game by game ID, with its current on-screen control scheme if it has one
 // @Tags games
 // @Accept json
 // @Produce json
 // @Param gameId path string true "Game ID"
 // @Success 200 {object} types.GameDetailsResponse
+// @Failure 404 {object} types.ErrorResponse
 // @Router /games/{gameId} [get]
 func (gh *GameHandler) GameDetailsByGameId(c *gin.Context) {
 	gameId := c.Param("gameId")
 
 	game, err := gh.gameService.GameDetailsByGameId(gameId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game details"})
+		respondWithError(c, err, "Failed to get game details")
+		return
+	}
+
+	controlScheme, err := gh.gameService.ControlSchemeByGameId(gameId)
+	if err != nil {
+		respondWithError(c, err, "Failed to get game details")
 		return
 	}
 
 	res := types.GameDetailsResponse{
-		Game: game,
+		Game:          game,
+		ControlScheme: controlScheme,
+		Thumbnail:     gh.gameService.Thumbnail(game),
 	}
 
 	c.JSON(http.StatusOK, res)
@@ -90,7 +173,7 @@ func (gh *GameHandler) GameDetailsByGameId(c *gin.Context) {
 
 // CreateInteractionByGameId godoc
 // @Summary Create an interaction for a game
-// @Description Create an interaction (play, like, bookmark) for a game
+// @Description Create an interaction (play, like, bookmark) for a game. Repeating a like or bookmark has no effect. A play starts a play session, and has no effect while the user has a live one for the game
 // @Tags games
 // @Accept json
 // @Produce json
@@ -108,21 +191,27 @@ func (gh *GameHandler) CreateInteractionByGameId(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := gh.gameService.CreateInteractionByGameId(gameId, userId, req.Type)
+	created, err := gh.gameService.CreateInteractionByGameId(gameId, userId, req.Type)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create interaction"})
+		respondWithError(c, err, "Failed to create interaction")
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, types.CreateInteractionResponse{Status: "Interaction already recorded"})
 		return
 	}
 
+	// The play session a play starts has already counted it towards
+	// recommendations.
 	var interaction interface{}
 	switch req.Type {
 	case "play":
-		interaction = services.PlayInteraction{
-			GameID:   gameId,
-			PlayTime: req.PlayTime,
-		}
-	case "like", "bookmark":
+		c.JSON(http.StatusOK, types.CreateInteractionResponse{Status: "Interaction recorded successfully"})
+		return
+	case "like":
 		interaction = gameId
+	case "bookmark":
+		interaction = services.BookmarkInteraction{GameID: gameId}
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interaction type"})
 		return
@@ -141,6 +230,42 @@ func (gh *GameHandler) CreateInteractionByGameId(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+// DeleteInteractionByGameId godoc
+// @Summary Undo an interaction for a game
+// @Description Remove a like or bookmark from a game. Undoing an interaction that doesn't exist has no effect
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param type path string true "like or bookmark"
+// @Success 200 {object} types.CreateInteractionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/interactions/{type} [delete]
+func (gh *GameHandler) DeleteInteractionByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+	interactionType := c.Param("type")
+
+	removed, err := gh.gameService.DeleteInteractionByGameId(gameId, userId, interactionType)
+	if err != nil {
+		respondWithError(c, err, "Failed to remove interaction")
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusOK, types.CreateInteractionResponse{Status: "Interaction not found, nothing to remove"})
+		return
+	}
+
+	if err := gh.recommendationService.RemoveInteraction(userId, gameId, interactionType); err != nil {
+		log.Warn().Err(err).Str("gameId", gameId).Msg("Failed to reverse recommendation signal")
+		c.JSON(http.StatusOK, types.CreateInteractionResponse{Status: "Interaction removed, but failed to update recommendations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.CreateInteractionResponse{Status: "Interaction removed successfully"})
+}
+
 // RecordSeenGame godoc
 // @Summary Record a game as seen by the user
 // @Description Record a game as seen by the user to improve recommendations
@@ -167,3 +292,161 @@ func (gh *GameHandler) RecordSeenGame(c *gin.Context) {
 
 	c.JSON(http.StatusOK, res)
 }
+
+// CreateGame godoc
+// @Summary Publish a new game
+// @Description Create a game owned by the authenticated user
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param request body types.CreateGameRequest true "Game details"
+// @Success 201 {object} types.GameResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games [post]
+func (gh *GameHandler) CreateGame(c *gin.Context) {
+	userId := c.GetString("userId")
+
+	var req types.CreateGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	game, err := gh.gameService.CreateGame(userId, req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create game")
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.GameResponse{Game: game, Thumbnail: gh.gameService.Thumbnail(game)})
+}
+
+// UpdateGameByGameId godoc
+// @Summary Update a game
+// @Description Update a game owned by the authenticated user. Omitted fields are left unchanged
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.UpdateGameRequest true "Fields to update"
+// @Success 200 {object} types.GameResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId} [patch]
+func (gh *GameHandler) UpdateGameByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+
+	var req types.UpdateGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	game, err := gh.gameService.UpdateGameByGameId(gameId, userId, req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update game")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GameResponse{Game: game, Thumbnail: gh.gameService.Thumbnail(game)})
+}
+
+// UploadThumbnailByGameId godoc
+// @Summary Upload a game thumbnail
+// @Description Upload a PNG, JPEG, WebP or GIF thumbnail (max 5MB, at least 256x144) for a game owned by the authenticated user. It is stored as WebP and JPEG variants 320, 640 and 1280 pixels wide, never wider than the upload
+// @Tags games
+// @Accept multipart/form-data
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param thumbnail formData file true "Thumbnail image"
+// @Success 200 {object} types.GameResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/thumbnail [post]
+func (gh *GameHandler) UploadThumbnailByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+
+	fileHeader, err := c.FormFile("thumbnail")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Thumbnail file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxThumbnailSize {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Thumbnail is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Failed to read thumbnail"})
+		return
+	}
+	defer file.Close()
+
+	game, err := gh.gameService.UploadThumbnailByGameId(c.Request.Context(), gameId, userId, file)
+	if err != nil {
+		respondWithError(c, err, "Failed to upload thumbnail")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GameResponse{Game: game, Thumbnail: gh.gameService.Thumbnail(game)})
+}
+
+// DeleteGameByGameId godoc
+// @Summary Delete a game
+// @Description Soft delete a game. Only the creator or an admin can delete, and the game can be restored later
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId} [delete]
+func (gh *GameHandler) DeleteGameByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+
+	if err := gh.gameService.DeleteGameByGameId(gameId, userId); err != nil {
+		respondWithError(c, err, "Failed to delete game")
+		return
+	}
+	gh.invalidateFeeds(c)
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Game deleted successfully"})
+}
+
+// RestoreGameByGameId godoc
+// @Summary Restore a deleted game
+// @Description Restore a soft deleted game. Only the creator or an admin can restore
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/restore [post]
+func (gh *GameHandler) RestoreGameByGameId(c *gin.Context) {
+	gameId := c.Param("gameId")
+	userId := c.GetString("userId")
+
+	if err := gh.gameService.RestoreGameByGameId(gameId, userId); err != nil {
+		respondWithError(c, err, "Failed to restore game")
+		return
+	}
+	gh.invalidateFeeds(c)
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Game restored successfully"})
+}
+
+func (gh *GameHandler) invalidateFeeds(c *gin.Context) {
+	if err := gh.recommendationService.InvalidateCaches(c.Request.Context()); err != nil {
+		log.Warn().Err(err).Msg("Failed to invalidate recommendation caches")
+	}
+}
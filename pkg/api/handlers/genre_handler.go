@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/managers"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type GenreHandler struct {
+	manager *managers.GenreManager
+}
+
+func NewGenreHandler(manager *managers.GenreManager) *GenreHandler {
+	return &GenreHandler{manager: manager}
+}
+
+// ListGenres godoc
+// @Summary List genres
+// @Description Get every genre sorted by name
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Genre
+// @Failure 500 {object} types.ErrorResponse
+// @Router /genres [get]
+func (gh *GenreHandler) ListGenres(c *gin.Context) {
+	genres, err := gh.manager.ListGenres()
+	if err != nil {
+		respondWithError(c, err, "Failed to list genres")
+		return
+	}
+
+	c.JSON(http.StatusOK, genres)
+}
+
+// GetGamesByGenreId godoc
+// @Summary Get games in a genre
+// @Description Get a paginated list of games in a genre, most popular first
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genreId path string true "Genre ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /genres/{genreId}/games [get]
+func (gh *GenreHandler) GetGamesByGenreId(c *gin.Context) {
+	var query types.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid pagination parameters"})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	games, err := gh.manager.ListGamesByGenre(c.Param("genreId"), types.PaginationQuery{Page: query.Page, PageSize: query.PageSize})
+	if err != nil {
+		respondWithError(c, err, "Failed to get games for genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, games)
+}
+
+// CreateGenre godoc
+// @Summary Create a genre
+// @Description Create a new genre. Admin only
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param request body types.CreateGenreRequest true "Genre name"
+// @Success 201 {object} models.Genre
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /genres [post]
+func (gh *GenreHandler) CreateGenre(c *gin.Context) {
+	var req types.CreateGenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	genre, err := gh.manager.AddGenre(req.Name)
+	if err != nil {
+		respondWithError(c, err, "Failed to create genre")
+		return
+	}
+
+	c.JSON(http.StatusCreated, genre)
+}
+
+// RenameGenre godoc
+// @Summary Rename a genre
+// @Description Rename a genre. Admin only
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genreId path string true "Genre ID"
+// @Param request body types.RenameGenreRequest true "New name"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /genres/{genreId} [patch]
+func (gh *GenreHandler) RenameGenre(c *gin.Context) {
+	var req types.RenameGenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	genre, err := gh.manager.RenameGenre(c.Param("genreId"), req.Name)
+	if err != nil {
+		respondWithError(c, err, "Failed to rename genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// MergeGenre godoc
+// @Summary Merge a genre into another
+// @Description Move every game and user preference to the target genre and delete this one. Admin only
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genreId path string true "Genre ID to merge away"
+// @Param request body types.MergeGenreRequest true "Target genre"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /genres/{genreId}/merge [post]
+func (gh *GenreHandler) MergeGenre(c *gin.Context) {
+	var req types.MergeGenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	genre, err := gh.manager.MergeGenres(c.Param("genreId"), req.TargetGenreID)
+	if err != nil {
+		respondWithError(c, err, "Failed to merge genres")
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// DeleteGenre godoc
+// @Summary Delete a genre
+// @Description Delete a genre that has no games. Admin only
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genreId path string true "Genre ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /genres/{genreId} [delete]
+func (gh *GenreHandler) DeleteGenre(c *gin.Context) {
+	if err := gh.manager.DeleteGenre(c.Param("genreId")); err != nil {
+		respondWithError(c, err, "Failed to delete genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Genre deleted successfully"})
+}
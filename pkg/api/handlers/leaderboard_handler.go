@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type LeaderboardHandler struct {
+	service *services.LeaderboardService
+}
+
+func NewLeaderboardHandler(service *services.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{service: service}
+}
+
+// ListLeaderboards godoc
+// @Summary List a game's leaderboards
+// @Description Get the leaderboards defined for a game
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.LeaderboardsResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards [get]
+func (lh *LeaderboardHandler) ListLeaderboards(c *gin.Context) {
+	res, err := lh.service.ListLeaderboards(c.Param("gameId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to list leaderboards")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateLeaderboard godoc
+// @Summary Create a leaderboard
+// @Description Define a leaderboard for a game. Only the game's creator or an admin can do this
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.CreateLeaderboardRequest true "Leaderboard details"
+// @Success 201 {object} models.Leaderboard
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards [post]
+func (lh *LeaderboardHandler) CreateLeaderboard(c *gin.Context) {
+	var req types.CreateLeaderboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	leaderboard, err := lh.service.CreateLeaderboard(c.Param("gameId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusCreated, leaderboard)
+}
+
+// UpdateLeaderboard godoc
+// @Summary Update a leaderboard
+// @Description Rename a leaderboard or change whether it only accepts signed scores
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slug path string true "Leaderboard slug"
+// @Param request body types.UpdateLeaderboardRequest true "Fields to change"
+// @Success 200 {object} models.Leaderboard
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards/{slug} [patch]
+func (lh *LeaderboardHandler) UpdateLeaderboard(c *gin.Context) {
+	var req types.UpdateLeaderboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	leaderboard, err := lh.service.UpdateLeaderboard(c.Param("gameId"), c.Param("slug"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// DeleteLeaderboard godoc
+// @Summary Delete a leaderboard
+// @Description Delete a leaderboard and all of its scores
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slug path string true "Leaderboard slug"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards/{slug} [delete]
+func (lh *LeaderboardHandler) DeleteLeaderboard(c *gin.Context) {
+	if err := lh.service.DeleteLeaderboard(c.Request.Context(), c.Param("gameId"), c.Param("slug"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to delete leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Leaderboard deleted"})
+}
+
+// TopScores godoc
+// @Summary Get the top scores
+// @Description Get the best scores of the current period, best first
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slug path string true "Leaderboard slug"
+// @Param limit query int false "Number of entries" default(10)
+// @Success 200 {object} types.LeaderboardScoresResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards/{slug}/scores [get]
+func (lh *LeaderboardHandler) TopScores(c *gin.Context) {
+	var query types.LeaderboardTopQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	res, err := lh.service.TopScores(c.Request.Context(), c.Param("gameId"), c.Param("slug"), query.Limit)
+	if err != nil {
+		respondWithError(c, err, "Failed to get leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// ScoresAroundMe godoc
+// @Summary Get the scores around mine
+// @Description Get the authenticated user's entry in the current period with the entries just above and below it
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slug path string true "Leaderboard slug"
+// @Param radius query int false "Entries on either side" default(5)
+// @Success 200 {object} types.LeaderboardScoresResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards/{slug}/scores/me [get]
+func (lh *LeaderboardHandler) ScoresAroundMe(c *gin.Context) {
+	var query types.LeaderboardAroundQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Radius == 0 {
+		query.Radius = 5
+	}
+
+	res, err := lh.service.ScoresAroundUser(c.Request.Context(), c.Param("gameId"), c.Param("slug"), c.GetString("userId"), query.Radius)
+	if err != nil {
+		respondWithError(c, err, "Failed to get leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// SubmitScore godoc
+// @Summary Submit a score
+// @Description Submit a score for the current period. Only the player's best score is kept. Signed scores are verified against the game's score secret, and boards can require them
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slug path string true "Leaderboard slug"
+// @Param request body types.SubmitScoreRequest true "Score and optional signature"
+// @Success 200 {object} types.SubmitScoreResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/leaderboards/{slug}/scores [post]
+func (lh *LeaderboardHandler) SubmitScore(c *gin.Context) {
+	var req types.SubmitScoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := lh.service.SubmitScore(c.Request.Context(), c.Param("gameId"), c.Param("slug"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to submit score")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// GetScoreSecret godoc
+// @Summary Get the game's score secret
+// @Description Get the secret used to sign leaderboard scores, creating it if the game has none. Only the game's creator or an admin can see it
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.ScoreSecretResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/score-secret [get]
+func (lh *LeaderboardHandler) GetScoreSecret(c *gin.Context) {
+	res, err := lh.service.ScoreSecret(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get score secret")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// RotateScoreSecret godoc
+// @Summary Rotate the game's score secret
+// @Description Replace the secret used to sign leaderboard scores. Scores signed with the old secret are rejected afterwards
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.ScoreSecretResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/score-secret [post]
+func (lh *LeaderboardHandler) RotateScoreSecret(c *gin.Context) {
+	res, err := lh.service.RotateScoreSecret(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to rotate score secret")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"time"
+)
+
+type PlaySessionHandler struct {
+	service *services.PlaySessionService
+}
+
+func NewPlaySessionHandler(service *services.PlaySessionService) *PlaySessionHandler {
+	return &PlaySessionHandler{service: service}
+}
+
+// StartSession godoc
+// @Summary Start a play session
+// @Description Start a play session for a game. The client should ping the heartbeat endpoint at the returned interval while the game is open and call end when it closes. A user has one open session per game, so starting again while one is live returns it
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.StartSessionRequest false "Share link the player arrived through"
+// @Success 200 {object} types.PlaySessionResponse
+// @Success 201 {object} types.PlaySessionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/sessions [post]
+func (ph *PlaySessionHandler) StartSession(c *gin.Context) {
+	// The body is optional, so an empty one is fine.
+	var req types.StartSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	session, started, err := ph.service.StartSession(c.Param("gameId"), c.GetString("userId"), req.ShareCode)
+	if err != nil {
+		respondWithError(c, err, "Failed to start session")
+		return
+	}
+
+	status := http.StatusOK
+	if started {
+		status = http.StatusCreated
+	}
+	c.JSON(status, types.PlaySessionResponse{
+		Session:           session,
+		HeartbeatInterval: int(services.HeartbeatInterval / time.Second),
+	})
+}
+
+// HeartbeatSession godoc
+// @Summary Ping a play session
+// @Description Credit the time since the previous ping to the session. Long gaps are capped, and sessions that go quiet for too long expire
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} types.PlaySessionResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/sessions/{sessionId}/heartbeat [post]
+func (ph *PlaySessionHandler) HeartbeatSession(c *gin.Context) {
+	session, err := ph.service.Heartbeat(c.Param("gameId"), c.Param("sessionId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to record heartbeat")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.PlaySessionResponse{
+		Session:           session,
+		HeartbeatInterval: int(services.HeartbeatInterval / time.Second),
+	})
+}
+
+// EndSession godoc
+// @Summary End a play session
+// @Description Credit the time since the last ping and close the session
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} types.PlaySessionResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/sessions/{sessionId}/end [post]
+func (ph *PlaySessionHandler) EndSession(c *gin.Context) {
+	session, err := ph.service.EndSession(c.Param("gameId"), c.Param("sessionId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to end session")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.PlaySessionResponse{Session: session})
+}
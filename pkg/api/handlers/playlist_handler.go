@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PlaylistHandler struct {
+	service *services.PlaylistService
+}
+
+func NewPlaylistHandler(service *services.PlaylistService) *PlaylistHandler {
+	return &PlaylistHandler{service: service}
+}
+
+// ListPlaylistsByUserId godoc
+// @Summary List a user's playlists
+// @Description Get a user's playlists, newest first. The owner sees all of them, everyone else only public ones
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists [get]
+func (ph *PlaylistHandler) ListPlaylistsByUserId(c *gin.Context) {
+	var query types.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid pagination parameters"})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	playlists, err := ph.service.ListPlaylistsByUserId(c.Param("userId"), c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list playlists")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlists)
+}
+
+// ListFollowedPlaylistsByUserId godoc
+// @Summary List followed playlists
+// @Description Get the playlists the authenticated user follows, most recently followed first
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Router /users/{userId}/followedPlaylists [get]
+func (ph *PlaylistHandler) ListFollowedPlaylistsByUserId(c *gin.Context) {
+	var query types.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid pagination parameters"})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	playlists, err := ph.service.ListFollowedPlaylists(c.Param("userId"), c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list followed playlists")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlists)
+}
+
+// GetPlaylist godoc
+// @Summary Get a playlist
+// @Description Get a playlist and its games in order. Private playlists are only visible to their owner
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId} [get]
+func (ph *PlaylistHandler) GetPlaylist(c *gin.Context) {
+	playlist, err := ph.service.GetPlaylist(c.Param("userId"), c.Param("playlistId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// GetPlaylistBySlug godoc
+// @Summary Get a shared playlist
+// @Description Get a playlist by its share slug. Works for public and unlisted playlists
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param slug path string true "Playlist slug"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /playlists/{slug} [get]
+func (ph *PlaylistHandler) GetPlaylistBySlug(c *gin.Context) {
+	playlist, err := ph.service.GetPlaylistBySlug(c.Param("slug"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// CreatePlaylist godoc
+// @Summary Create a playlist
+// @Description Create a playlist for the authenticated user, optionally with an ordered list of games
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param request body types.CreatePlaylistRequest true "Playlist details"
+// @Success 201 {object} types.PlaylistResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists [post]
+func (ph *PlaylistHandler) CreatePlaylist(c *gin.Context) {
+	var req types.CreatePlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	playlist, err := ph.service.CreatePlaylist(c.Param("userId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create playlist")
+		return
+	}
+
+	c.JSON(http.StatusCreated, playlist)
+}
+
+// UpdatePlaylist godoc
+// @Summary Update a playlist
+// @Description Change the title, description or visibility of a playlist. Owner only
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Param request body types.UpdatePlaylistRequest true "Fields to change"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId} [patch]
+func (ph *PlaylistHandler) UpdatePlaylist(c *gin.Context) {
+	var req types.UpdatePlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	playlist, err := ph.service.UpdatePlaylist(c.Param("userId"), c.Param("playlistId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// DeletePlaylist godoc
+// @Summary Delete a playlist
+// @Description Delete a playlist and its cover. Owner only
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId} [delete]
+func (ph *PlaylistHandler) DeletePlaylist(c *gin.Context) {
+	if err := ph.service.DeletePlaylist(c.Request.Context(), c.Param("userId"), c.Param("playlistId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to delete playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Playlist deleted"})
+}
+
+// SetPlaylistGames godoc
+// @Summary Set the games in a playlist
+// @Description Replace the games in a playlist. Games are shown in the order given. Owner only
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Param request body types.SetPlaylistGamesRequest true "Ordered game IDs"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/games [put]
+func (ph *PlaylistHandler) SetPlaylistGames(c *gin.Context) {
+	var req types.SetPlaylistGamesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	playlist, err := ph.service.SetPlaylistGames(c.Param("userId"), c.Param("playlistId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to set playlist games")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// AddPlaylistGame godoc
+// @Summary Add a game to a playlist
+// @Description Insert a game at a position in the playlist, or append it. Owner only
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Param request body types.AddPlaylistGameRequest true "Game to add"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/games [post]
+func (ph *PlaylistHandler) AddPlaylistGame(c *gin.Context) {
+	var req types.AddPlaylistGameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	playlist, err := ph.service.AddPlaylistGame(c.Param("userId"), c.Param("playlistId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to add game to playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// RemovePlaylistGame godoc
+// @Summary Remove a game from a playlist
+// @Description Remove a game from a playlist. Owner only
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/games/{gameId} [delete]
+func (ph *PlaylistHandler) RemovePlaylistGame(c *gin.Context) {
+	playlist, err := ph.service.RemovePlaylistGame(c.Param("userId"), c.Param("playlistId"), c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to remove game from playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// UploadPlaylistCover godoc
+// @Summary Upload a playlist cover
+// @Description Upload a PNG, JPEG, WebP or GIF cover image (max 5MB) for a playlist. Owner only
+// @Tags playlists
+// @Accept multipart/form-data
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Param cover formData file true "Cover image"
+// @Success 200 {object} types.PlaylistResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/cover [post]
+func (ph *PlaylistHandler) UploadPlaylistCover(c *gin.Context) {
+	fileHeader, err := c.FormFile("cover")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Cover file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxThumbnailSize {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Cover is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Failed to read cover"})
+		return
+	}
+	defer file.Close()
+
+	playlist, err := ph.service.UploadCover(c.Request.Context(), c.Param("userId"), c.Param("playlistId"), c.GetString("userId"), file)
+	if err != nil {
+		respondWithError(c, err, "Failed to upload cover")
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+// FollowPlaylist godoc
+// @Summary Follow a playlist
+// @Description Follow another user's public or unlisted playlist. Following twice has no effect
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/followers [post]
+func (ph *PlaylistHandler) FollowPlaylist(c *gin.Context) {
+	if err := ph.service.FollowPlaylist(c.Param("userId"), c.Param("playlistId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to follow playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Playlist followed"})
+}
+
+// UnfollowPlaylist godoc
+// @Summary Unfollow a playlist
+// @Description Stop following a playlist
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param userId path string true "Owner user ID"
+// @Param playlistId path string true "Playlist ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /users/{userId}/playlists/{playlistId}/followers [delete]
+func (ph *PlaylistHandler) UnfollowPlaylist(c *gin.Context) {
+	if err := ph.service.UnfollowPlaylist(c.Param("userId"), c.Param("playlistId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to unfollow playlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Playlist unfollowed"})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+}
+
+func NewReviewHandler(service *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// ListReviewsByGameId godoc
+// @Summary List reviews of a game
+// @Description Get a game's reviews, most helpful or most recent first
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param sort query string false "helpful or recent" default(helpful)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews [get]
+func (rh *ReviewHandler) ListReviewsByGameId(c *gin.Context) {
+	var query types.ListReviewsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Sort == "" {
+		query.Sort = "helpful"
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	reviews, err := rh.service.ListReviewsByGameId(c.Param("gameId"), c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list reviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// CreateReview godoc
+// @Summary Review a game
+// @Description Rate a game from 1 to 5 stars with optional text. Users must have played the game and can review it once
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.CreateReviewRequest true "Rating and text"
+// @Success 201 {object} types.ReviewResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews [post]
+func (rh *ReviewHandler) CreateReview(c *gin.Context) {
+	var req types.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := rh.service.CreateReview(c.Param("gameId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create review")
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// UpdateReview godoc
+// @Summary Edit a review
+// @Description Change the rating or text of your own review
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param reviewId path string true "Review ID"
+// @Param request body types.UpdateReviewRequest true "Fields to change"
+// @Success 200 {object} types.ReviewResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews/{reviewId} [patch]
+func (rh *ReviewHandler) UpdateReview(c *gin.Context) {
+	var req types.UpdateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, err := rh.service.UpdateReview(c.Param("gameId"), c.Param("reviewId"), c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to update review")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// DeleteReview godoc
+// @Summary Delete a review
+// @Description Delete your own review
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews/{reviewId} [delete]
+func (rh *ReviewHandler) DeleteReview(c *gin.Context) {
+	if err := rh.service.DeleteReview(c.Param("gameId"), c.Param("reviewId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to delete review")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Review deleted"})
+}
+
+// MarkReviewHelpful godoc
+// @Summary Mark a review helpful
+// @Description Mark someone else's review as helpful. Marking twice has no effect
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews/{reviewId}/helpful [post]
+func (rh *ReviewHandler) MarkReviewHelpful(c *gin.Context) {
+	if err := rh.service.MarkReviewHelpful(c.Param("gameId"), c.Param("reviewId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to mark review helpful")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Review marked helpful"})
+}
+
+// UnmarkReviewHelpful godoc
+// @Summary Take back a helpful vote
+// @Description Remove your helpful vote from a review
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/reviews/{reviewId}/helpful [delete]
+func (rh *ReviewHandler) UnmarkReviewHelpful(c *gin.Context) {
+	if err := rh.service.UnmarkReviewHelpful(c.Param("gameId"), c.Param("reviewId"), c.GetString("userId")); err != nil {
+		respondWithError(c, err, "Failed to remove helpful vote")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Helpful vote removed"})
+}
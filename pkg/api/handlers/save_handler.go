@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SaveHandler struct {
+	service *services.SaveService
+}
+
+func NewSaveHandler(service *services.SaveService) *SaveHandler {
+	return &SaveHandler{service: service}
+}
+
+// ListSaves godoc
+// @Summary List save slots
+// @Description Get the authenticated user's saves for a game and how much of the quota they use
+// @Tags saves
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Success 200 {object} types.SavesResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/saves [get]
+func (sh *SaveHandler) ListSaves(c *gin.Context) {
+	res, err := sh.service.ListSaves(c.Param("gameId"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to list saves")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// GetSave godoc
+// @Summary Download a save
+// @Description Get the raw save data of a slot. The ETag header holds the save's version
+// @Tags saves
+// @Produce octet-stream
+// @Param gameId path string true "Game ID"
+// @Param slot path string true "Save slot"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {file} binary
+// @Success 304 {string} string "Not modified"
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/saves/{slot} [get]
+func (sh *SaveHandler) GetSave(c *gin.Context) {
+	save, blob, err := sh.service.GetSave(c.Request.Context(), c.Param("gameId"), c.GetString("userId"), c.Param("slot"))
+	if err != nil {
+		respondWithError(c, err, "Failed to get save")
+		return
+	}
+	defer blob.Close()
+
+	etag := services.SaveETag(save.Version)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, save.Size, "application/octet-stream", blob, map[string]string{
+		"X-Save-Checksum": save.Checksum,
+	})
+}
+
+// PutSave godoc
+// @Summary Write a save
+// @Description Store raw save data in a slot (max 1MB). Overwriting needs If-Match with the current ETag, so two devices can't clobber each other. Stale ETags get 412, overwrites without If-Match get 428
+// @Tags saves
+// @Accept octet-stream
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slot path string true "Save slot"
+// @Param If-Match header string false "ETag of the save being replaced, or *"
+// @Param If-None-Match header string false "* to only create a new save"
+// @Success 200 {object} types.SaveResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 412 {object} types.ErrorResponse
+// @Failure 413 {object} types.ErrorResponse
+// @Failure 428 {object} types.ErrorResponse
+// @Router /games/{gameId}/saves/{slot} [put]
+func (sh *SaveHandler) PutSave(c *gin.Context) {
+	if c.Request.ContentLength > services.MaxSaveSize {
+		c.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{Error: "Save is too large"})
+		return
+	}
+
+	condition := types.SaveCondition{
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+	save, err := sh.service.PutSave(c.Request.Context(), c.Param("gameId"), c.GetString("userId"), c.Param("slot"), condition, c.Request.Body)
+	if err != nil {
+		respondWithError(c, err, "Failed to write save")
+		return
+	}
+
+	c.Header("ETag", services.SaveETag(save.Version))
+	c.JSON(http.StatusOK, types.SaveResponse{Save: *save})
+}
+
+// DeleteSave godoc
+// @Summary Delete a save
+// @Description Delete a save slot. If-Match, when sent, must match the current ETag
+// @Tags saves
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param slot path string true "Save slot"
+// @Param If-Match header string false "ETag of the save being deleted"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 412 {object} types.ErrorResponse
+// @Router /games/{gameId}/saves/{slot} [delete]
+func (sh *SaveHandler) DeleteSave(c *gin.Context) {
+	condition := types.SaveCondition{IfMatch: c.GetHeader("If-Match")}
+	if err := sh.service.DeleteSave(c.Request.Context(), c.Param("gameId"), c.GetString("userId"), c.Param("slot"), condition); err != nil {
+		respondWithError(c, err, "Failed to delete save")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse{Status: "Save deleted"})
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"html/template"
+	"net/http"
+)
+
+// Share pages change when a game or profile is edited, so crawlers get a
+// shorter cache than oEmbed consumers, which are told ShareCacheAge anyway.
+const (
+	sharePageCacheControl  = "public, max-age=300"
+	shareErrorCacheControl = "public, max-age=60"
+)
+
+// sharePagePolicy only lets share pages run their own inline script and
+// style, and the embed card frame games over https.
+const sharePagePolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src * data:; frame-src https:; base-uri 'none'; form-action 'none'"
+
+var shareTemplateFuncs = template.FuncMap{
+	"siteName": func() string { return services.ShareSiteName },
+}
+
+// sharePageTemplate is what link previews are built from. People who open
+// the link are sent on to the web app.
+var sharePageTemplate = template.Must(template.New("share").Funcs(shareTemplateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} | {{siteName}}</title>
+<meta name="description" content="{{.Description}}">
+<link rel="canonical" href="{{.URL}}">
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Title}}">
+<meta property="og:site_name" content="{{siteName}}">
+<meta property="og:type" content="{{.Type}}">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{- if .ImageURL}}
+<meta property="og:image" content="{{.ImageURL}}">
+{{- if .ImageWidth}}
+<meta property="og:image:width" content="{{.ImageWidth}}">
+<meta property="og:image:height" content="{{.ImageHeight}}">
+{{- end}}
+<meta name="twitter:image" content="{{.ImageURL}}">
+{{- end}}
+<meta name="twitter:card" content="{{.TwitterCard}}">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+{{- if eq .TwitterCard "player"}}
+<meta name="twitter:player" content="{{.PlayerURL}}">
+<meta name="twitter:player:width" content="{{.PlayerWidth}}">
+<meta name="twitter:player:height" content="{{.PlayerHeight}}">
+{{- end}}
+{{- if .AppURL}}
+<script>location.replace({{.AppURL}});</script>
+{{- end}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Description}}</p>
+{{- if .AppURL}}
+<p><a href="{{.AppURL}}">Open on {{siteName}}</a></p>
+{{- end}}
+</body>
+</html>
+`))
+
+// gameEmbedTemplate is the playable card other sites frame. The game is only
+// loaded once someone presses play.
+var gameEmbedTemplate = template.Must(template.New("embed").Funcs(shareTemplateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} | {{siteName}}</title>
+<style>
+html, body { margin: 0; height: 100%; overflow: hidden; font-family: system-ui, sans-serif; }
+body { background: {{if .DominantColor}}{{.DominantColor}}{{else}}#111{{end}}; color: #fff; }
+#card { position: relative; width: 100%; height: 100%; display: flex; flex-direction: column; align-items: center; justify-content: center; gap: 12px; background-size: cover; background-position: center; }
+#card h1 { margin: 0; padding: 0 16px; font-size: 18px; text-align: center; text-shadow: 0 1px 4px rgba(0, 0, 0, .8); }
+#play { padding: 12px 32px; border: 0; border-radius: 999px; background: #fff; color: #111; font-size: 16px; font-weight: 600; cursor: pointer; }
+#credit { position: absolute; right: 8px; bottom: 8px; color: #fff; font-size: 12px; text-shadow: 0 1px 4px rgba(0, 0, 0, .8); }
+iframe { display: block; width: 100%; height: 100%; border: 0; }
+</style>
+</head>
+<body>
+<div id="card"{{if .ImageURL}} style="background-image: url('{{.ImageURL}}')"{{end}}>
+<h1>{{.Title}}</h1>
+<button id="play" type="button">Play</button>
+<a id="credit" href="{{.ShareURL}}" target="_blank" rel="noopener">{{siteName}}</a>
+</div>
+<script>
+document.getElementById("play").addEventListener("click", function () {
+	var frame = document.createElement("iframe");
+	frame.src = {{.EmbedLink}};
+	frame.title = {{.Title}};
+	frame.allow = "autoplay; fullscreen; gamepad";
+	frame.allowFullscreen = true;
+	document.body.replaceChildren(frame);
+});
+</script>
+</body>
+</html>
+`))
+
+var shareErrorTemplate = template.Must(template.New("error").Funcs(shareTemplateFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.}} | {{siteName}}</title>
+</head>
+<body>
+<h1>{{.}}</h1>
+</body>
+</html>
+`))
+
+type ShareHandler struct {
+	service *services.ShareService
+}
+
+func NewShareHandler(service *services.ShareService) *ShareHandler {
+	return &ShareHandler{service: service}
+}
+
+// GameSharePage renders the OpenGraph and Twitter card page of a game at
+// /share/games/{gameId}.
+func (sh *ShareHandler) GameSharePage(c *gin.Context) {
+	page, err := sh.service.GameSharePage(c.Param("gameId"))
+	if err != nil {
+		renderShareError(c, err)
+		return
+	}
+	sh.render(c, sharePageTemplate, page)
+}
+
+// GameEmbed renders the playable card of a game at
+// /share/games/{gameId}/embed.
+func (sh *ShareHandler) GameEmbed(c *gin.Context) {
+	embed, err := sh.service.GameEmbed(c.Param("gameId"))
+	if err != nil {
+		renderShareError(c, err)
+		return
+	}
+	sh.render(c, gameEmbedTemplate, embed)
+}
+
+// UserSharePage renders the OpenGraph and Twitter card page of a profile at
+// /share/users/{username}.
+func (sh *ShareHandler) UserSharePage(c *gin.Context) {
+	page, err := sh.service.UserSharePage(c.Param("username"))
+	if err != nil {
+		renderShareError(c, err)
+		return
+	}
+	sh.render(c, sharePageTemplate, page)
+}
+
+// OEmbed godoc
+// @Summary Describe a share link
+// @Description oEmbed endpoint for game and profile share URLs. Games are described as a playable card, profiles as a link. Only the json format is supported
+// @Tags share
+// @Produce json
+// @Param url query string true "Share URL"
+// @Param maxwidth query int false "Largest width the consumer can show"
+// @Param maxheight query int false "Largest height the consumer can show"
+// @Param format query string false "Response format, json"
+// @Success 200 {object} types.OEmbedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 501 {object} types.ErrorResponse
+// @Router /oembed [get]
+func (sh *ShareHandler) OEmbed(c *gin.Context) {
+	var query types.OEmbedQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid oEmbed request"})
+		return
+	}
+	if query.Format != "" && query.Format != "json" {
+		c.JSON(http.StatusNotImplemented, types.ErrorResponse{Error: "Only the json format is supported"})
+		return
+	}
+
+	res, err := sh.service.OEmbed(query)
+	if err != nil {
+		respondWithError(c, err, "Failed to describe URL")
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", services.ShareCacheAge))
+	c.JSON(http.StatusOK, res)
+}
+
+func (sh *ShareHandler) render(c *gin.Context, tmpl *template.Template, data interface{}) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Error().Err(err).Str("template", tmpl.Name()).Msg("Failed to render share page")
+		renderShareError(c, err)
+		return
+	}
+	c.Header("Cache-Control", sharePageCacheControl)
+	c.Header("Content-Security-Policy", sharePagePolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// renderShareError answers failed share page requests with an HTML page, since
+// they come from browsers and crawlers rather than API clients.
+func renderShareError(c *gin.Context, err error) {
+	status, message := http.StatusInternalServerError, "Something went wrong"
+	if errors.Is(err, types.ErrNotFound) {
+		status, message = http.StatusNotFound, "Not found"
+		c.Header("Cache-Control", shareErrorCacheControl)
+	} else {
+		c.Header("Cache-Control", "no-store")
+	}
+
+	var buf bytes.Buffer
+	if err := shareErrorTemplate.Execute(&buf, message); err != nil {
+		c.String(status, message)
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ShareLinkHandler struct {
+	service *services.ShareLinkService
+}
+
+func NewShareLinkHandler(service *services.ShareLinkService) *ShareLinkHandler {
+	return &ShareLinkHandler{service: service}
+}
+
+// CreateShareLink godoc
+// @Summary Create a share link
+// @Description Get a short link to a game, profile or playlist. Clicks and the plays they lead to are credited to the caller. Asking again for the same target returns the existing link
+// @Tags share
+// @Accept json
+// @Produce json
+// @Param request body types.CreateShareLinkRequest true "What to link to"
+// @Success 200 {object} types.ShareLinkResponse
+// @Success 201 {object} types.ShareLinkResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /share-links [post]
+func (sh *ShareLinkHandler) CreateShareLink(c *gin.Context) {
+	var req types.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
+		return
+	}
+
+	res, created, err := sh.service.CreateShareLink(c.GetString("userId"), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to create share link")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, res)
+}
+
+// ListShareLinks godoc
+// @Summary List your share links
+// @Description List the caller's share links, newest first, with the clicks and plays each one drove
+// @Tags share
+// @Accept json
+// @Produce json
+// @Param include_revoked query bool false "Include revoked links"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /share-links [get]
+func (sh *ShareLinkHandler) ListShareLinks(c *gin.Context) {
+	var query types.ListShareLinksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	links, err := sh.service.ListShareLinks(c.GetString("userId"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RevokeShareLink godoc
+// @Summary Revoke a share link
+// @Description Stop a share link from redirecting. Only its owner or an admin can revoke it
+// @Tags share
+// @Accept json
+// @Produce json
+// @Param code path string true "Share code"
+// @Success 200 {object} types.ShareLinkResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /share-links/{code} [delete]
+func (sh *ShareLinkHandler) RevokeShareLink(c *gin.Context) {
+	res, err := sh.service.RevokeShareLink(c.Param("code"), c.GetString("userId"))
+	if err != nil {
+		respondWithError(c, err, "Failed to revoke share link")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// FollowShareLink redirects /s/{code} to what the link points at, counting
+// the click on the side. Links can be revoked, so the redirect is never cached.
+func (sh *ShareLinkHandler) FollowShareLink(c *gin.Context) {
+	target, err := sh.service.FollowShareLink(c.Param("code"), c.Request.Referer())
+	if err != nil {
+		renderShareError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
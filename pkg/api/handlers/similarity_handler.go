@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SimilarityHandler struct {
+	service *services.SimilarityService
+}
+
+func NewSimilarityHandler(service *services.SimilarityService) *SimilarityHandler {
+	return &SimilarityHandler{service: service}
+}
+
+// RelatedGames godoc
+// @Summary Get games related to a game
+// @Description Games ranked by shared tags, same genre and how many players of this game also played them. Scores are rebuilt every hour
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param limit query int false "Number of games" default(10)
+// @Param orientation query string false "Orientation the screen is locked to, portrait or landscape"
+// @Param touch query bool false "The device has a touch screen"
+// @Param keyboard query bool false "The device has a keyboard"
+// @Param gamepad query bool false "The device has a gamepad"
+// @Param screen query string false "phone, tablet or desktop"
+// @Success 200 {object} types.RelatedGamesResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /games/{gameId}/related [get]
+func (sh *SimilarityHandler) RelatedGames(c *gin.Context) {
+	gameId := c.Param("gameId")
+
+	var query types.RelatedGamesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	games, err := sh.service.GetRelatedGames(c.Request.Context(), gameId, query.Device, query.Limit)
+	if err != nil {
+		respondWithError(c, err, "Failed to get related games")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.RelatedGamesResponse{Games: games})
+}
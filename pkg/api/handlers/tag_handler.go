@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type TagHandler struct {
+	service *services.TagService
+}
+
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+// ListTags godoc
+// @Summary List tags
+// @Description Get a paginated list of tags with the number of games using each, most used first
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(25)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Router /tags [get]
+func (th *TagHandler) ListTags(c *gin.Context) {
+	var query types.ListTagsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 25
+	}
+
+	tags, err := th.service.ListTags(query)
+	if err != nil {
+		respondWithError(c, err, "Failed to list tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// AutocompleteTags godoc
+// @Summary Autocomplete tag names
+// @Description Get tags whose name starts with the query, most used first
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param q query string true "Tag name prefix"
+// @Param limit query int false "Maximum number of suggestions" default(10)
+// @Success 200 {array} types.TagWithCount
+// @Failure 400 {object} types.ErrorResponse
+// @Router /tags/autocomplete [get]
+func (th *TagHandler) AutocompleteTags(c *gin.Context) {
+	var query types.AutocompleteTagsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	tags, err := th.service.AutocompleteTags(query)
+	if err != nil {
+		respondWithError(c, err, "Failed to autocomplete tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetGamesByTagName godoc
+// @Summary Get games with a tag
+// @Description Get a paginated list of games with a tag, sorted by popularity or newest first
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tagName path string true "Tag name"
+// @Param sort query string false "popular or newest" default(popular)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /tags/{tagName}/games [get]
+func (th *TagHandler) GetGamesByTagName(c *gin.Context) {
+	var query types.TagGamesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 10
+	}
+
+	games, err := th.service.GetGamesByTagName(c.Param("tagName"), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to get games for tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, games)
+}
+
+// AttachTagsByGameId godoc
+// @Summary Add tags to a game
+// @Description Attach tags to a game, creating any that don't exist yet. Only the creator or an admin can change tags
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param request body types.UpdateGameTagsRequest true "Tags to attach"
+// @Success 200 {object} types.GameTagsResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/tags [post]
+func (th *TagHandler) AttachTagsByGameId(c *gin.Context) {
+	var req types.UpdateGameTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	tags, err := th.service.AttachTagsByGameId(c.Param("gameId"), c.GetString("userId"), req.Tags)
+	if err != nil {
+		respondWithError(c, err, "Failed to attach tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GameTagsResponse{Tags: tags})
+}
+
+// DetachTagByGameId godoc
+// @Summary Remove a tag from a game
+// @Description Detach a tag from a game. Only the creator or an admin can change tags
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param gameId path string true "Game ID"
+// @Param tagName path string true "Tag name"
+// @Success 200 {object} types.GameTagsResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /games/{gameId}/tags/{tagName} [delete]
+func (th *TagHandler) DetachTagByGameId(c *gin.Context) {
+	tags, err := th.service.DetachTagByGameId(c.Param("gameId"), c.GetString("userId"), c.Param("tagName"))
+	if err != nil {
+		respondWithError(c, err, "Failed to detach tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GameTagsResponse{Tags: tags})
+}
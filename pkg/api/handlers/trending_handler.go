@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type TrendingHandler struct {
+	service *services.TrendingService
+}
+
+func NewTrendingHandler(service *services.TrendingService) *TrendingHandler {
+	return &TrendingHandler{service: service}
+}
+
+// TrendingGames godoc
+// @Summary Get trending games
+// @Description Games ranked by recent plays, likes, bookmarks and comments, with older events counting less. Rankings are refreshed every few minutes
+// @Tags games
+// @Accept json
+// @Produce json
+// @Param window query string false "1h, 24h or 7d" default(24h)
+// @Param genre_id query string false "Only rank games in this genre"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /games/trending [get]
+func (th *TrendingHandler) TrendingGames(c *gin.Context) {
+	var query types.TrendingGamesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	if query.Window == "" {
+		query.Window = "24h"
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	res, err := th.service.GetTrendingGames(c.Request.Context(), query)
+	if err != nil {
+		respondWithError(c, err, "Failed to get trending games")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
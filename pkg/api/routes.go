@@ -2,24 +2,70 @@ package api
 
 import (
 	"github.com/PixelzOrg/PHOLE.git/pkg/api/handlers"
+	"github.com/PixelzOrg/PHOLE.git/pkg/config"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/managers"
 	"github.com/PixelzOrg/PHOLE.git/pkg/middleware"
 	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/fetcher"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
 	"github.com/PixelzOrg/PHOLE.git/pkg/utils/supabase"
-	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 )
 
-func SetupRoutes(r *gin.Engine, databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth, redisClient *redis.Client, algoliaClient *search.Client) {
+func SetupRoutes(r *gin.Engine, c config.Config, databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth, redisClient *redis.Client, searchBackend gamesearch.Backend, storageBackend storage.Backend, documentFetcher fetcher.Fetcher) {
 	recommendationService := services.NewRecommendationService(databaseHandler, redisClient)
-	gameService := services.NewGameService(databaseHandler, supabaseAuth, algoliaClient)
-	gameHandler := handlers.NewGameHandler(gameService, recommendationService)
+	gameService := services.NewGameService(databaseHandler, supabaseAuth, searchBackend, storageBackend)
+	curationService := services.NewCurationService(databaseHandler, storageBackend, c.FeaturedSlots())
+	gameHandler := handlers.NewGameHandler(gameService, recommendationService, curationService)
 
 	commentService := services.NewCommentService(databaseHandler)
 	commentHandler := handlers.NewCommentHandler(commentService)
-	userService := services.NewUserService(databaseHandler, supabaseAuth)
+	userService := services.NewUserService(databaseHandler, supabaseAuth, storageBackend)
 	userHandler := handlers.NewUserHandler(userService)
+	claimService := services.NewClaimService(databaseHandler, documentFetcher)
+	claimHandler := handlers.NewClaimHandler(claimService)
+	tagService := services.NewTagService(databaseHandler, storageBackend)
+	tagHandler := handlers.NewTagHandler(tagService)
+	genreManager := managers.NewGenreManager(databaseHandler, storageBackend)
+	genreHandler := handlers.NewGenreHandler(genreManager)
+	adminHandler := handlers.NewAdminHandler(databaseHandler)
+	playSessionService := services.NewPlaySessionService(databaseHandler)
+	playSessionHandler := handlers.NewPlaySessionHandler(playSessionService)
+	analyticsService := services.NewAnalyticsService(databaseHandler)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	trendingService := services.NewTrendingService(databaseHandler, redisClient, storageBackend)
+	trendingHandler := handlers.NewTrendingHandler(trendingService)
+	similarityService := services.NewSimilarityService(databaseHandler, storageBackend)
+	similarityHandler := handlers.NewSimilarityHandler(similarityService)
+	reviewService := services.NewReviewService(databaseHandler)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	leaderboardService := services.NewLeaderboardService(databaseHandler, redisClient)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService)
+	achievementService := services.NewAchievementService(databaseHandler, storageBackend)
+	achievementHandler := handlers.NewAchievementHandler(achievementService)
+	saveService := services.NewSaveService(databaseHandler, storageBackend)
+	saveHandler := handlers.NewSaveHandler(saveService)
+	controlSchemeService := services.NewControlSchemeService(databaseHandler)
+	controlSchemeHandler := handlers.NewControlSchemeHandler(controlSchemeService)
+	collectionHandler := handlers.NewCollectionHandler(curationService)
+	playlistService := services.NewPlaylistService(databaseHandler, storageBackend)
+	playlistHandler := handlers.NewPlaylistHandler(playlistService)
+	shareService := services.NewShareService(databaseHandler, storageBackend, c.PublicURL, c.WebAppURL)
+	shareHandler := handlers.NewShareHandler(shareService)
+	shareLinkService := services.NewShareLinkService(databaseHandler, c.PublicURL, c.WebAppURL)
+	shareLinkHandler := handlers.NewShareLinkHandler(shareLinkService)
+
+	// Link previews live outside the API so share URLs stay short
+	share := r.Group("/share")
+	{
+		share.GET("/games/:gameId", shareHandler.GameSharePage)
+		share.GET("/games/:gameId/embed", shareHandler.GameEmbed)
+		share.GET("/users/:username", shareHandler.UserSharePage)
+	}
+	r.GET("/s/:code", shareLinkHandler.FollowShareLink)
 
 	// TODO: Use keyset pagination for everything
 	v1 := r.Group("/api/v1")
@@ -27,10 +73,79 @@ func SetupRoutes(r *gin.Engine, databaseHandler database.Handler, supabaseAuth *
 		games := v1.Group("/games")
 		{
 			games.GET("/feed", gameHandler.Feed)
+			games.GET("/search", gameHandler.SearchGames)
+			games.GET("/trending", trendingHandler.TrendingGames)
 			games.GET("/:gameId", gameHandler.GameDetailsByGameId)
+			games.GET("/:gameId/related", similarityHandler.RelatedGames)
+
+			// Publishing
+			games.POST("", middleware.AuthMiddleware(supabaseAuth), gameHandler.CreateGame)
+			games.PATCH("/:gameId", middleware.AuthMiddleware(supabaseAuth), gameHandler.UpdateGameByGameId)
+			games.POST("/:gameId/thumbnail", middleware.AuthMiddleware(supabaseAuth), gameHandler.UploadThumbnailByGameId)
+			games.DELETE("/:gameId", middleware.AuthMiddleware(supabaseAuth), gameHandler.DeleteGameByGameId)
+			games.POST("/:gameId/restore", middleware.AuthMiddleware(supabaseAuth), gameHandler.RestoreGameByGameId)
 
 			// Like, Bookmark, Play/View
 			games.POST("/:gameId/interactions", middleware.AuthMiddleware(supabaseAuth), gameHandler.CreateInteractionByGameId)
+			games.DELETE("/:gameId/interactions/:type", middleware.AuthMiddleware(supabaseAuth), gameHandler.DeleteInteractionByGameId)
+
+			// Play sessions
+			games.POST("/:gameId/sessions", middleware.AuthMiddleware(supabaseAuth), playSessionHandler.StartSession)
+			games.POST("/:gameId/sessions/:sessionId/heartbeat", middleware.AuthMiddleware(supabaseAuth), playSessionHandler.HeartbeatSession)
+			games.POST("/:gameId/sessions/:sessionId/end", middleware.AuthMiddleware(supabaseAuth), playSessionHandler.EndSession)
+
+			// Analytics
+			games.GET("/:gameId/analytics", middleware.AuthMiddleware(supabaseAuth), analyticsHandler.GetGameAnalytics)
+
+			// Tags
+			games.POST("/:gameId/tags", middleware.AuthMiddleware(supabaseAuth), tagHandler.AttachTagsByGameId)
+			games.DELETE("/:gameId/tags/:tagName", middleware.AuthMiddleware(supabaseAuth), tagHandler.DetachTagByGameId)
+
+			// Reviews
+			games.GET("/:gameId/reviews", middleware.OptionalAuthMiddleware(supabaseAuth), reviewHandler.ListReviewsByGameId)
+			games.POST("/:gameId/reviews", middleware.AuthMiddleware(supabaseAuth), reviewHandler.CreateReview)
+			games.PATCH("/:gameId/reviews/:reviewId", middleware.AuthMiddleware(supabaseAuth), reviewHandler.UpdateReview)
+			games.DELETE("/:gameId/reviews/:reviewId", middleware.AuthMiddleware(supabaseAuth), reviewHandler.DeleteReview)
+			games.POST("/:gameId/reviews/:reviewId/helpful", middleware.AuthMiddleware(supabaseAuth), reviewHandler.MarkReviewHelpful)
+			games.DELETE("/:gameId/reviews/:reviewId/helpful", middleware.AuthMiddleware(supabaseAuth), reviewHandler.UnmarkReviewHelpful)
+
+			// Leaderboards
+			games.GET("/:gameId/leaderboards", leaderboardHandler.ListLeaderboards)
+			games.POST("/:gameId/leaderboards", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.CreateLeaderboard)
+			games.PATCH("/:gameId/leaderboards/:slug", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.UpdateLeaderboard)
+			games.DELETE("/:gameId/leaderboards/:slug", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.DeleteLeaderboard)
+			games.GET("/:gameId/leaderboards/:slug/scores", leaderboardHandler.TopScores)
+			games.GET("/:gameId/leaderboards/:slug/scores/me", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.ScoresAroundMe)
+			games.POST("/:gameId/leaderboards/:slug/scores", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.SubmitScore)
+			games.GET("/:gameId/score-secret", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.GetScoreSecret)
+			games.POST("/:gameId/score-secret", middleware.AuthMiddleware(supabaseAuth), leaderboardHandler.RotateScoreSecret)
+
+			// Achievements
+			games.GET("/:gameId/achievements", middleware.OptionalAuthMiddleware(supabaseAuth), achievementHandler.ListAchievements)
+			games.GET("/:gameId/achievements/stats", middleware.AuthMiddleware(supabaseAuth), achievementHandler.AchievementStats)
+			games.POST("/:gameId/achievements", middleware.AuthMiddleware(supabaseAuth), achievementHandler.CreateAchievement)
+			games.PATCH("/:gameId/achievements/:key", middleware.AuthMiddleware(supabaseAuth), achievementHandler.UpdateAchievement)
+			games.DELETE("/:gameId/achievements/:key", middleware.AuthMiddleware(supabaseAuth), achievementHandler.DeleteAchievement)
+			games.POST("/:gameId/achievements/:key/icon", middleware.AuthMiddleware(supabaseAuth), achievementHandler.UploadAchievementIcon)
+			games.POST("/:gameId/achievements/:key/unlock", middleware.AuthMiddleware(supabaseAuth), achievementHandler.UnlockAchievement)
+
+			// On-screen controls
+			games.GET("/:gameId/controls", controlSchemeHandler.GetControlScheme)
+			games.GET("/:gameId/controls/versions", middleware.AuthMiddleware(supabaseAuth), controlSchemeHandler.ListControlSchemeVersions)
+			games.PUT("/:gameId/controls", middleware.AuthMiddleware(supabaseAuth), controlSchemeHandler.PutControlScheme)
+			games.DELETE("/:gameId/controls", middleware.AuthMiddleware(supabaseAuth), controlSchemeHandler.DeleteControlScheme)
+
+			// Cloud saves
+			saves := games.Group("/:gameId/saves", middleware.AuthMiddleware(supabaseAuth))
+			{
+				saves.GET("", saveHandler.ListSaves)
+				saves.GET("/:slot", saveHandler.GetSave)
+				saves.PUT("/:slot", saveHandler.PutSave)
+				saves.DELETE("/:slot", saveHandler.DeleteSave)
+			}
+
+			// Claiming imported games
+			games.POST("/:gameId/claims", middleware.AuthMiddleware(supabaseAuth), claimHandler.CreateClaimByGameId)
 
 			// Comments
 			comments := games.Group("/:gameId/comments")
@@ -41,6 +156,54 @@ func SetupRoutes(r *gin.Engine, databaseHandler database.Handler, supabaseAuth *
 			}
 		}
 
+		tags := v1.Group("/tags")
+		{
+			tags.GET("", tagHandler.ListTags)
+			tags.GET("/autocomplete", tagHandler.AutocompleteTags)
+			tags.GET("/:tagName/games", tagHandler.GetGamesByTagName)
+		}
+
+		genres := v1.Group("/genres")
+		{
+			genres.GET("", genreHandler.ListGenres)
+			genres.GET("/:genreId/games", genreHandler.GetGamesByGenreId)
+			genres.POST("", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), genreHandler.CreateGenre)
+			genres.PATCH("/:genreId", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), genreHandler.RenameGenre)
+			genres.POST("/:genreId/merge", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), genreHandler.MergeGenre)
+			genres.DELETE("/:genreId", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), genreHandler.DeleteGenre)
+		}
+
+		claims := v1.Group("/claims", middleware.AuthMiddleware(supabaseAuth))
+		{
+			claims.GET("", claimHandler.ListClaims)
+			claims.GET("/:claimId", claimHandler.GetClaimById)
+			claims.POST("/:claimId/verify", claimHandler.VerifyClaim)
+			claims.POST("/:claimId/approve", middleware.AdminMiddleware(databaseHandler), claimHandler.ApproveClaim)
+			claims.POST("/:claimId/reject", middleware.AdminMiddleware(databaseHandler), claimHandler.RejectClaim)
+		}
+
+		collections := v1.Group("/collections")
+		{
+			collections.GET("", collectionHandler.ListCollections)
+			collections.GET("/:slug", collectionHandler.GetCollection)
+			collections.POST("", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), collectionHandler.CreateCollection)
+			collections.PATCH("/:slug", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), collectionHandler.UpdateCollection)
+			collections.PUT("/:slug/games", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), collectionHandler.SetCollectionGames)
+			collections.DELETE("/:slug", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler), collectionHandler.DeleteCollection)
+		}
+
+		admin := v1.Group("/admin", middleware.AuthMiddleware(supabaseAuth), middleware.AdminMiddleware(databaseHandler))
+		{
+			admin.GET("/search/status", adminHandler.SearchSyncStatus)
+
+			admin.GET("/collections", collectionHandler.ListAllCollections)
+			admin.GET("/collections/:slug", collectionHandler.GetCollection)
+
+			admin.GET("/featured", collectionHandler.ListFeaturedGames)
+			admin.POST("/featured", collectionHandler.CreateFeaturedGame)
+			admin.DELETE("/featured/:featuredId", collectionHandler.DeleteFeaturedGame)
+		}
+
 		users := v1.Group("/users")
 		{
 			// TODO: USER PROFILE PICTURES!?!?!?!
@@ -53,11 +216,41 @@ func SetupRoutes(r *gin.Engine, databaseHandler database.Handler, supabaseAuth *
 			users.GET("/:userId/bookmarkedGames", middleware.AuthMiddleware(supabaseAuth), userHandler.GetBookmarkedGamesByUserId)
 			users.GET("/:userId/recentlyPlayedGames", middleware.AuthMiddleware(supabaseAuth), userHandler.GetRecentlyPlayedGamesByUserId)
 
+			users.GET("/:userId/achievements", achievementHandler.ListUserAchievements)
+
 			// Following and Followers
 			users.POST("/:userId/follows", middleware.AuthMiddleware(supabaseAuth), userHandler.CreateFollow)
 			users.DELETE("/:userId/follows/:followId", middleware.AuthMiddleware(supabaseAuth), userHandler.DeleteFollow)
 			users.GET("/:userId/followers", userHandler.GetFollowersByUserId)
 			users.GET("/:userId/following", userHandler.GetFollowingByUserId)
+
+			// Playlists
+			users.GET("/:userId/playlists", middleware.OptionalAuthMiddleware(supabaseAuth), playlistHandler.ListPlaylistsByUserId)
+			users.POST("/:userId/playlists", middleware.AuthMiddleware(supabaseAuth), playlistHandler.CreatePlaylist)
+			users.GET("/:userId/playlists/:playlistId", middleware.OptionalAuthMiddleware(supabaseAuth), playlistHandler.GetPlaylist)
+			users.PATCH("/:userId/playlists/:playlistId", middleware.AuthMiddleware(supabaseAuth), playlistHandler.UpdatePlaylist)
+			users.DELETE("/:userId/playlists/:playlistId", middleware.AuthMiddleware(supabaseAuth), playlistHandler.DeletePlaylist)
+			users.PUT("/:userId/playlists/:playlistId/games", middleware.AuthMiddleware(supabaseAuth), playlistHandler.SetPlaylistGames)
+			users.POST("/:userId/playlists/:playlistId/games", middleware.AuthMiddleware(supabaseAuth), playlistHandler.AddPlaylistGame)
+			users.DELETE("/:userId/playlists/:playlistId/games/:gameId", middleware.AuthMiddleware(supabaseAuth), playlistHandler.RemovePlaylistGame)
+			users.POST("/:userId/playlists/:playlistId/cover", middleware.AuthMiddleware(supabaseAuth), playlistHandler.UploadPlaylistCover)
+			users.POST("/:userId/playlists/:playlistId/followers", middleware.AuthMiddleware(supabaseAuth), playlistHandler.FollowPlaylist)
+			users.DELETE("/:userId/playlists/:playlistId/followers", middleware.AuthMiddleware(supabaseAuth), playlistHandler.UnfollowPlaylist)
+			users.GET("/:userId/followedPlaylists", middleware.AuthMiddleware(supabaseAuth), playlistHandler.ListFollowedPlaylistsByUserId)
+		}
+
+		playlists := v1.Group("/playlists")
+		{
+			playlists.GET("/:slug", middleware.OptionalAuthMiddleware(supabaseAuth), playlistHandler.GetPlaylistBySlug)
+		}
+
+		v1.GET("/oembed", shareHandler.OEmbed)
+
+		shareLinks := v1.Group("/share-links", middleware.AuthMiddleware(supabaseAuth))
+		{
+			shareLinks.GET("", shareLinkHandler.ListShareLinks)
+			shareLinks.POST("", shareLinkHandler.CreateShareLink)
+			shareLinks.DELETE("/:code", shareLinkHandler.RevokeShareLink)
 		}
 	}
 }
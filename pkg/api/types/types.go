@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
 	"time"
 )
@@ -16,19 +17,28 @@ type SuccessResponse struct {
 
 // --- Games ---
 type FeedResponse struct {
-	Games      []models.Game `json:"games"`
+	Games      []GameSummary `json:"games"`
 	TotalGames int64         `json:"totalGames"`
 	Page       int           `json:"page"`
 	Limit      int           `json:"limit"`
 }
 
+// GameSummary is a game in a list, with its thumbnail resolved.
+type GameSummary struct {
+	models.Game
+	Thumbnail *Thumbnail `json:"thumbnail,omitempty"`
+}
+
 type GameDetailsResponse struct {
 	Game models.Game `json:"game"`
+	// ControlScheme is the latest on-screen control layout, if the creator
+	// has defined one.
+	ControlScheme *models.ControlScheme `json:"controlScheme,omitempty"`
+	Thumbnail     *Thumbnail            `json:"thumbnail,omitempty"`
 }
 
 type CreateInteractionRequest struct {
-	Type     string `json:"type" binding:"required,oneof=play like bookmark"`
-	PlayTime *int   `json:"playtime,omitempty"`
+	Type string `json:"type" binding:"required,oneof=play like bookmark"`
 }
 
 type CreateInteractionResponse struct {
@@ -39,6 +49,540 @@ type RecordSeenGameResponse struct {
 	Status string `json:"status"`
 }
 
+type CreateGameRequest struct {
+	Title       string   `json:"title" binding:"required,max=120"`
+	Description string   `json:"description" binding:"max=5000"`
+	EmbedLink   string   `json:"embedLink" binding:"required,url"`
+	GameType    string   `json:"gameType" binding:"required,oneof=html5 unity godot"`
+	GenreID     string   `json:"genreId" binding:"required,uuid"`
+	Tags        []string `json:"tags" binding:"max=10,dive,min=1,max=32"`
+	IsLandscape *bool    `json:"isLandscape" binding:"required"`
+	// TouchSupport and GamepadSupport say whether the game takes touch or
+	// gamepad input itself. Keyboard and mouse are assumed. When left out,
+	// they default by game type.
+	TouchSupport   *bool `json:"touchSupport"`
+	GamepadSupport *bool `json:"gamepadSupport"`
+}
+
+type UpdateGameRequest struct {
+	Title          *string   `json:"title" binding:"omitempty,max=120"`
+	Description    *string   `json:"description" binding:"omitempty,max=5000"`
+	EmbedLink      *string   `json:"embedLink" binding:"omitempty,url"`
+	GameType       *string   `json:"gameType" binding:"omitempty,oneof=html5 unity godot"`
+	GenreID        *string   `json:"genreId" binding:"omitempty,uuid"`
+	Tags           *[]string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=32"`
+	IsLandscape    *bool     `json:"isLandscape"`
+	TouchSupport   *bool     `json:"touchSupport"`
+	GamepadSupport *bool     `json:"gamepadSupport"`
+}
+
+type GameResponse struct {
+	Game      models.Game `json:"game"`
+	Thumbnail *Thumbnail  `json:"thumbnail,omitempty"`
+}
+
+// Thumbnail is a game's thumbnail with every stored size resolved to a URL.
+// URL is the largest JPEG variant. Thumbnails uploaded before variants
+// existed only have a URL.
+type Thumbnail struct {
+	URL           string             `json:"url"`
+	Variants      []ThumbnailVariant `json:"variants,omitempty"`
+	Blurhash      string             `json:"blurhash,omitempty"`
+	DominantColor string             `json:"dominantColor,omitempty"`
+}
+
+type ThumbnailVariant struct {
+	URL         string `json:"url"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"contentType"`
+}
+
+type SearchGamesQuery struct {
+	Query       string `form:"q" binding:"max=100"`
+	GenreID     string `form:"genre_id" binding:"omitempty,uuid"`
+	Tag         string `form:"tag" binding:"max=32"`
+	GameType    string `form:"game_type" binding:"omitempty,oneof=html5 unity godot"`
+	IsLandscape *bool  `form:"landscape"`
+	Page        int    `form:"page" binding:"omitempty,min=1"`
+	PageSize    int    `form:"page_size" binding:"omitempty,min=1,max=50"`
+	capability.Device
+}
+
+type TrendingGamesQuery struct {
+	Window   string `form:"window" binding:"omitempty,oneof=1h 24h 7d"`
+	GenreID  string `form:"genre_id" binding:"omitempty,uuid"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=50"`
+}
+
+type TrendingGame struct {
+	Game      models.Game `json:"game"`
+	Thumbnail *Thumbnail  `json:"thumbnail,omitempty"`
+	Score     float64     `json:"score"`
+}
+
+type RelatedGamesQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=20"`
+	capability.Device
+}
+
+type RelatedGame struct {
+	Game      models.Game `json:"game"`
+	Thumbnail *Thumbnail  `json:"thumbnail,omitempty"`
+	Score     float64     `json:"score"`
+}
+
+type RelatedGamesResponse struct {
+	Games []RelatedGame `json:"games"`
+}
+
+type SearchResult struct {
+	Game       models.Game       `json:"game"`
+	Thumbnail  *Thumbnail        `json:"thumbnail,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type StartSessionRequest struct {
+	// ShareCode is the code of the share link the player arrived through.
+	ShareCode string `json:"shareCode" binding:"omitempty,max=16"`
+}
+
+type PlaySessionResponse struct {
+	Session models.PlaySession `json:"session"`
+	// HeartbeatInterval is how often the client should ping, in seconds.
+	HeartbeatInterval int `json:"heartbeatInterval"`
+}
+
+type GameAnalyticsQuery struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type GameAnalyticsDay struct {
+	Day               string  `json:"day"`
+	Plays             int64   `json:"plays"`
+	UniquePlayers     int64   `json:"uniquePlayers"`
+	NewPlayers        int64   `json:"newPlayers"`
+	Sessions          int64   `json:"sessions"`
+	AvgSessionSeconds float64 `json:"avgSessionSeconds"`
+	Likes             int64   `json:"likes"`
+	Bookmarks         int64   `json:"bookmarks"`
+	Comments          int64   `json:"comments"`
+	// D1Retention and D7Retention are the share of the day's new players who
+	// came back one and seven days later. They are null until that day has
+	// passed or when there were no new players.
+	D1Retention *float64 `json:"d1Retention"`
+	D7Retention *float64 `json:"d7Retention"`
+}
+
+type GameAnalyticsResponse struct {
+	GameID string             `json:"gameId"`
+	From   string             `json:"from"`
+	To     string             `json:"to"`
+	Days   []GameAnalyticsDay `json:"days"`
+}
+
+// --- Tags ---
+type TagWithCount struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	GameCount int64  `json:"gameCount"`
+}
+
+type ListTagsQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type AutocompleteTagsQuery struct {
+	Query string `form:"q" binding:"required,min=1,max=32"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=25"`
+}
+
+type TagGamesQuery struct {
+	Sort     string `form:"sort" binding:"omitempty,oneof=popular newest"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type UpdateGameTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=10,dive,min=1,max=32"`
+}
+
+type GameTagsResponse struct {
+	Tags []models.Tag `json:"tags"`
+}
+
+// --- Genres ---
+type CreateGenreRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type RenameGenreRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type MergeGenreRequest struct {
+	TargetGenreID string `json:"targetGenreId" binding:"required,uuid"`
+}
+
+type PageQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// --- Collections ---
+type CreateCollectionRequest struct {
+	Title       string     `json:"title" binding:"required,max=120"`
+	Slug        string     `json:"slug" binding:"omitempty,max=64"`
+	Description string     `json:"description" binding:"max=2000"`
+	StartsAt    *time.Time `json:"startsAt"`
+	EndsAt      *time.Time `json:"endsAt"`
+	GameIDs     []string   `json:"gameIds" binding:"max=100,dive,uuid"`
+}
+
+type UpdateCollectionRequest struct {
+	Title       *string    `json:"title" binding:"omitempty,max=120"`
+	Description *string    `json:"description" binding:"omitempty,max=2000"`
+	StartsAt    *time.Time `json:"startsAt"`
+	EndsAt      *time.Time `json:"endsAt"`
+}
+
+type SetCollectionGamesRequest struct {
+	GameIDs []string `json:"gameIds" binding:"required,max=100,dive,uuid"`
+}
+
+type CollectionSummary struct {
+	Collection models.Collection `json:"collection"`
+	GameCount  int64             `json:"gameCount"`
+}
+
+type CollectionResponse struct {
+	Collection models.Collection `json:"collection"`
+	Games      []GameSummary     `json:"games"`
+}
+
+type CreateFeaturedGameRequest struct {
+	GameID   string     `json:"gameId" binding:"required,uuid"`
+	Priority int        `json:"priority"`
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt"`
+}
+
+type ListFeaturedGamesQuery struct {
+	Status   string `form:"status" binding:"omitempty,oneof=active scheduled expired"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// --- Playlists ---
+type CreatePlaylistRequest struct {
+	Title       string   `json:"title" binding:"required,max=120"`
+	Description string   `json:"description" binding:"max=2000"`
+	Visibility  string   `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	GameIDs     []string `json:"gameIds" binding:"max=200,dive,uuid"`
+}
+
+type UpdatePlaylistRequest struct {
+	Title       *string `json:"title" binding:"omitempty,max=120"`
+	Description *string `json:"description" binding:"omitempty,max=2000"`
+	Visibility  *string `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+}
+
+type SetPlaylistGamesRequest struct {
+	GameIDs []string `json:"gameIds" binding:"required,max=200,dive,uuid"`
+}
+
+type AddPlaylistGameRequest struct {
+	GameID string `json:"gameId" binding:"required,uuid"`
+	// Position is where to insert the game, starting at 0. It defaults to
+	// the end of the playlist.
+	Position *int `json:"position" binding:"omitempty,min=0"`
+}
+
+type PlaylistSummary struct {
+	Playlist  models.Playlist `json:"playlist"`
+	CoverURL  string          `json:"coverUrl,omitempty"`
+	GameCount int64           `json:"gameCount"`
+}
+
+type PlaylistResponse struct {
+	Playlist    models.Playlist `json:"playlist"`
+	CoverURL    string          `json:"coverUrl,omitempty"`
+	Games       []GameSummary   `json:"games"`
+	IsFollowing bool            `json:"isFollowing"`
+}
+
+// --- Claims ---
+type CreateClaimRequest struct {
+	Message string `json:"message" binding:"max=2000"`
+}
+
+type ReviewClaimRequest struct {
+	Note string `json:"note" binding:"max=2000"`
+	// Override lets an admin approve a claim whose token was never verified.
+	Override bool `json:"override"`
+}
+
+type ClaimResponse struct {
+	Claim           models.GameClaim `json:"claim"`
+	VerificationURL string           `json:"verificationUrl,omitempty"`
+}
+
+type ListClaimsQuery struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	GameID   string `form:"game_id" binding:"omitempty,uuid"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// --- Leaderboards ---
+type CreateLeaderboardRequest struct {
+	Name             string `json:"name" binding:"required,max=80"`
+	Slug             string `json:"slug" binding:"omitempty,max=64"`
+	Order            string `json:"order" binding:"omitempty,oneof=asc desc"`
+	Period           string `json:"period" binding:"omitempty,oneof=all_time daily weekly"`
+	RequireSignature bool   `json:"requireSignature"`
+}
+
+type UpdateLeaderboardRequest struct {
+	Name             *string `json:"name" binding:"omitempty,max=80"`
+	RequireSignature *bool   `json:"requireSignature"`
+}
+
+type LeaderboardsResponse struct {
+	Leaderboards []models.Leaderboard `json:"leaderboards"`
+}
+
+type ScoreSecretResponse struct {
+	Secret string `json:"secret"`
+}
+
+// SubmitScoreRequest is a player's score. Signature is the hex HMAC-SHA256,
+// keyed with the game's score secret, of "<slug>:<userId>:<score>:<timestamp>",
+// where timestamp is the Unix time in seconds the score was signed.
+type SubmitScoreRequest struct {
+	Score     *int64 `json:"score" binding:"required"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature" binding:"omitempty,hexadecimal"`
+}
+
+type LeaderboardEntry struct {
+	Rank        int64   `json:"rank"`
+	UserID      string  `json:"userId"`
+	Username    string  `json:"username"`
+	DisplayName *string `json:"displayName,omitempty"`
+	Score       int64   `json:"score"`
+}
+
+type SubmitScoreResponse struct {
+	// Best is the player's best score this period, which may be an earlier one.
+	Best        LeaderboardEntry `json:"best"`
+	IsNewRecord bool             `json:"isNewRecord"`
+}
+
+type LeaderboardTopQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type LeaderboardAroundQuery struct {
+	Radius int `form:"radius" binding:"omitempty,min=1,max=25"`
+}
+
+type LeaderboardScoresResponse struct {
+	Leaderboard models.Leaderboard `json:"leaderboard"`
+	PeriodKey   string             `json:"periodKey"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
+
+// --- Achievements ---
+type CreateAchievementRequest struct {
+	Key         string `json:"key" binding:"omitempty,max=64"`
+	Name        string `json:"name" binding:"required,max=80"`
+	Description string `json:"description" binding:"max=500"`
+	Hidden      bool   `json:"hidden"`
+	Points      int    `json:"points" binding:"min=0,max=1000"`
+	// RequireSignature only accepts unlocks signed with the score secret.
+	RequireSignature bool `json:"requireSignature"`
+}
+
+type UpdateAchievementRequest struct {
+	Name             *string `json:"name" binding:"omitempty,max=80"`
+	Description      *string `json:"description" binding:"omitempty,max=500"`
+	Hidden           *bool   `json:"hidden"`
+	Points           *int    `json:"points" binding:"omitempty,min=0,max=1000"`
+	RequireSignature *bool   `json:"requireSignature"`
+}
+
+type AchievementResponse struct {
+	Achievement models.Achievement `json:"achievement"`
+	IconURL     string             `json:"iconUrl,omitempty"`
+	// UnlockedAt is when the viewer unlocked the achievement, if they have.
+	UnlockedAt *time.Time `json:"unlockedAt,omitempty"`
+}
+
+type AchievementsResponse struct {
+	Achievements []AchievementResponse `json:"achievements"`
+}
+
+// UnlockAchievementRequest optionally signs an unlock. Signature is the hex
+// HMAC-SHA256, keyed with the game's score secret, of
+// "achievement:<key>:<userId>:<timestamp>", where timestamp is the Unix time
+// in seconds the unlock was signed.
+type UnlockAchievementRequest struct {
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature" binding:"omitempty,hexadecimal"`
+}
+
+type UnlockAchievementResponse struct {
+	Achievement AchievementResponse `json:"achievement"`
+	// NewlyUnlocked is false when the user already had the achievement.
+	NewlyUnlocked bool `json:"newlyUnlocked"`
+}
+
+type AchievementStat struct {
+	Achievement      models.Achievement `json:"achievement"`
+	IconURL          string             `json:"iconUrl,omitempty"`
+	UnlockPercentage float64            `json:"unlockPercentage"`
+}
+
+// AchievementStatsResponse reports how many of the game's players unlocked
+// each achievement.
+type AchievementStatsResponse struct {
+	Players      int64             `json:"players"`
+	Achievements []AchievementStat `json:"achievements"`
+}
+
+// --- Control schemes ---
+type PutControlSchemeRequest struct {
+	Orientation string               `json:"orientation" binding:"required,oneof=portrait landscape any"`
+	Layout      models.ControlLayout `json:"layout"`
+	// BaseVersion is the version the edit started from. When set, the edit
+	// is rejected if someone saved a newer version in the meantime.
+	BaseVersion *int `json:"baseVersion" binding:"omitempty,min=0"`
+}
+
+type ControlSchemeVersionsResponse struct {
+	Versions []models.ControlScheme `json:"versions"`
+}
+
+// --- Saves ---
+// SaveCondition carries the If-Match and If-None-Match headers of a save
+// write. Versions are sent as ETags, e.g. "3".
+type SaveCondition struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+type SaveResponse struct {
+	Save models.SaveState `json:"save"`
+}
+
+type SavesResponse struct {
+	Saves      []models.SaveState `json:"saves"`
+	UsedBytes  int64              `json:"usedBytes"`
+	QuotaBytes int64              `json:"quotaBytes"`
+	MaxSlots   int                `json:"maxSlots"`
+}
+
+// --- Reviews ---
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Content string `json:"content" binding:"max=5000"`
+}
+
+type UpdateReviewRequest struct {
+	Rating  *int    `json:"rating" binding:"omitempty,min=1,max=5"`
+	Content *string `json:"content" binding:"omitempty,max=5000"`
+}
+
+type ListReviewsQuery struct {
+	Sort     string `form:"sort" binding:"omitempty,oneof=helpful recent"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type ReviewResponse struct {
+	Review models.Review `json:"review"`
+	// MarkedHelpful is whether the viewer marked the review helpful.
+	MarkedHelpful bool `json:"markedHelpful"`
+}
+
+// SharePage is what a share page renders into its OpenGraph and Twitter card
+// tags.
+type SharePage struct {
+	Title       string
+	Description string
+	// URL is the canonical share URL and AppURL where people are sent to
+	// actually open the page, if a web app is configured.
+	URL         string
+	AppURL      string
+	OEmbedURL   string
+	Type        string
+	TwitterCard string
+	ImageURL    string
+	ImageWidth  int
+	ImageHeight int
+	// PlayerURL is the embeddable card of a game, used by Twitter's player
+	// card.
+	PlayerURL    string
+	PlayerWidth  int
+	PlayerHeight int
+}
+
+// GameEmbed is the playable card other sites embed through oEmbed.
+type GameEmbed struct {
+	Title         string
+	EmbedLink     string
+	ShareURL      string
+	ImageURL      string
+	DominantColor string
+}
+
+type OEmbedQuery struct {
+	URL       string `form:"url" binding:"required,url"`
+	MaxWidth  int    `form:"maxwidth" binding:"omitempty,min=1"`
+	MaxHeight int    `form:"maxheight" binding:"omitempty,min=1"`
+	Format    string `form:"format"`
+}
+
+// OEmbedResponse follows https://oembed.com. Games are "rich" embeds of
+// their playable card; profiles are "link" embeds.
+type OEmbedResponse struct {
+	Type            string `json:"type"`
+	Version         string `json:"version"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name,omitempty"`
+	AuthorURL       string `json:"author_url,omitempty"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+	HTML            string `json:"html,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+}
+
+type CreateShareLinkRequest struct {
+	TargetType string `json:"targetType" binding:"required,oneof=game user playlist"`
+	// TargetID is the game ID, user ID or playlist ID.
+	TargetID string `json:"targetId" binding:"required,max=255"`
+}
+
+type ShareLinkResponse struct {
+	Link models.ShareLink `json:"link"`
+	URL  string           `json:"url"`
+}
+
+type ListShareLinksQuery struct {
+	IncludeRevoked bool `form:"include_revoked"`
+	Page           int  `form:"page" binding:"omitempty,min=1"`
+	PageSize       int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
 // --- Comments ---
 // TODO: Update the types below to use errors.Is() instead of string comparison
 const (
@@ -51,6 +595,14 @@ const (
 var (
 	ErrNotFound     = errors.New("not found")
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+	ErrTooLarge     = errors.New("too large")
+	// ErrPreconditionFailed and ErrPreconditionRequired report a failed or
+	// missing optimistic concurrency check, e.g. a stale If-Match header.
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
 )
 
 type PaginationQuery struct {
@@ -116,6 +668,10 @@ type GetUserProfileResponse struct {
 	Bio             *string
 	FollowersCount  int `gorm:"default:0"`
 	FollowingCount  int `gorm:"default:0"`
+	// AchievementPoints and AchievementCount total the user's unlocked
+	// achievements across all games.
+	AchievementPoints int
+	AchievementCount  int
 }
 
 type UpdateUserProfileRequest struct {
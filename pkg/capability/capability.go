@@ -0,0 +1,155 @@
+// Package capability decides which games suit the device a client runs on.
+//
+// Games that can't be controlled at all on the device are filtered out.
+// Games that work but not well, such as a landscape game on a phone locked
+// to portrait, are demoted below the rest.
+package capability
+
+import (
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"sort"
+	"strings"
+)
+
+const (
+	ScreenPhone   = "phone"
+	ScreenTablet  = "tablet"
+	ScreenDesktop = "desktop"
+)
+
+// Device describes a client. Clients list every input they have; one that
+// sends none is assumed to manage any game, so older clients see the full
+// catalog.
+type Device struct {
+	// Orientation is the orientation the screen is locked to, if any.
+	Orientation string `form:"orientation" binding:"omitempty,oneof=portrait landscape"`
+	Touch       bool   `form:"touch"`
+	Keyboard    bool   `form:"keyboard"`
+	Gamepad     bool   `form:"gamepad"`
+	Screen      string `form:"screen" binding:"omitempty,oneof=phone tablet desktop"`
+}
+
+// RestrictsInput reports whether some games can't be controlled on the
+// device. Every game takes keyboard and mouse.
+func (d Device) RestrictsInput() bool {
+	return (d.Touch || d.Gamepad) && !d.Keyboard
+}
+
+// FitOrientation is the orientation games should have to fit the screen, or
+// empty if any fits. Phones are held upright unless the client says
+// otherwise.
+func (d Device) FitOrientation() string {
+	if d.Orientation != "" {
+		return d.Orientation
+	}
+	if d.Screen == ScreenPhone {
+		return models.ControlOrientationPortrait
+	}
+	return ""
+}
+
+// Key identifies what the device changes about a list of games, for cache
+// keys. Devices that get the same games share a key.
+func (d Device) Key() string {
+	orientation := d.FitOrientation()
+	if orientation == "" {
+		orientation = "any"
+	}
+
+	inputs := "any"
+	if d.RestrictsInput() {
+		var names []string
+		if d.Touch {
+			names = append(names, "touch")
+		}
+		if d.Gamepad {
+			names = append(names, "gamepad")
+		}
+		inputs = strings.Join(names, "+")
+	}
+	return orientation + ":" + inputs
+}
+
+// Keys lists every key a device can have, so caches kept per device can be
+// cleared without searching for their entries.
+func Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, orientation := range []string{"", models.ControlOrientationPortrait, models.ControlOrientationLandscape} {
+		for inputs := 0; inputs < 8; inputs++ {
+			d := Device{Orientation: orientation, Touch: inputs&1 != 0, Gamepad: inputs&2 != 0, Keyboard: inputs&4 != 0}
+			if key := d.Key(); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// CanPlay reports whether the game can be controlled on the device. Touch
+// screens manage games with native touch controls or an on-screen control
+// scheme.
+func (d Device) CanPlay(game models.Game) bool {
+	if !d.RestrictsInput() {
+		return true
+	}
+	return (d.Touch && (game.TouchSupport || game.ButtonMapping)) || (d.Gamepad && game.GamepadSupport)
+}
+
+// Demoted reports whether the game runs on the device but doesn't fit its
+// screen.
+func (d Device) Demoted(game models.Game) bool {
+	switch d.FitOrientation() {
+	case models.ControlOrientationPortrait:
+		return game.IsLandscape
+	case models.ControlOrientationLandscape:
+		return !game.IsLandscape
+	}
+	return false
+}
+
+// PlayableFilter is the SQL condition on the games table, under the given
+// alias, that matches CanPlay. It is empty when the device can play anything.
+func (d Device) PlayableFilter(alias string) string {
+	if !d.RestrictsInput() {
+		return ""
+	}
+	var conditions []string
+	if d.Touch {
+		conditions = append(conditions, fmt.Sprintf("%[1]s.touch_support = true OR %[1]s.button_mapping = true", alias))
+	}
+	if d.Gamepad {
+		conditions = append(conditions, fmt.Sprintf("%s.gamepad_support = true", alias))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// DemotionOrder is an ORDER BY term that puts demoted games last. It has to
+// come before the list's own ordering, and is empty when nothing is demoted.
+func (d Device) DemotionOrder(alias string) string {
+	switch d.FitOrientation() {
+	case models.ControlOrientationPortrait:
+		return fmt.Sprintf("%s.is_landscape ASC", alias)
+	case models.ControlOrientationLandscape:
+		return fmt.Sprintf("%s.is_landscape DESC", alias)
+	}
+	return ""
+}
+
+// Arrange drops the games the device can't play and moves demoted ones to the
+// end, keeping the order otherwise. It is for lists assembled in memory; SQL
+// queries should use PlayableFilter and DemotionOrder instead.
+func (d Device) Arrange(games []models.Game) []models.Game {
+	result := make([]models.Game, 0, len(games))
+	for _, game := range games {
+		if d.CanPlay(game) {
+			result = append(result, game)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return !d.Demoted(result[i]) && d.Demoted(result[j])
+	})
+	return result
+}
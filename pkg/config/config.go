@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -18,8 +21,33 @@ type Config struct {
 	ConnectionString        string
 	AlgoliaKey              string `mapstructure:"ALGOLIA_KEY"`
 	AlgoliaAppId            string `mapstructure:"ALGOLIA_APP_ID"`
+	AlgoliaIndex            string `mapstructure:"ALGOLIA_INDEX"`
+	SearchBackend           string `mapstructure:"SEARCH_BACKEND"`
 	SupabaseProjectURL      string `mapstructure:"SUPABASE_PROJECT_URL"`
 	SupabaseAPIKey          string `mapstructure:"SUPABASE_API_KEY"`
+	StorageBackend          string `mapstructure:"STORAGE_BACKEND"`
+	StorageLocalPath        string `mapstructure:"STORAGE_LOCAL_PATH"`
+	StoragePublicURL        string `mapstructure:"STORAGE_PUBLIC_URL"`
+	ClaimFetcher            string `mapstructure:"CLAIM_FETCHER"`
+	FeedFeaturedSlots       string `mapstructure:"FEED_FEATURED_SLOTS"`
+	PublicURL               string `mapstructure:"PUBLIC_URL"`
+	WebAppURL               string `mapstructure:"WEB_APP_URL"`
+}
+
+// FeaturedSlots parses FeedFeaturedSlots, a comma separated list of 1-based
+// feed positions that featured games are placed at. Invalid entries are
+// skipped.
+func (c Config) FeaturedSlots() []int {
+	var slots []int
+	for _, part := range strings.Split(c.FeedFeaturedSlots, ",") {
+		slot, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || slot < 1 {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return slots
 }
 
 func getConfigValue(key string) string {
@@ -41,6 +69,15 @@ func LoadConfig() (config Config, err error) {
 
 	viper.AutomaticEnv()
 
+	viper.SetDefault("STORAGE_BACKEND", "local")
+	viper.SetDefault("STORAGE_LOCAL_PATH", "./uploads")
+	viper.SetDefault("STORAGE_PUBLIC_URL", "http://localhost:8080/media")
+	viper.SetDefault("CLAIM_FETCHER", "http")
+	viper.SetDefault("ALGOLIA_INDEX", "games")
+	viper.SetDefault("SEARCH_BACKEND", "postgres")
+	viper.SetDefault("FEED_FEATURED_SLOTS", "3,10")
+	viper.SetDefault("PUBLIC_URL", "http://localhost:8080")
+
 	err = viper.ReadInConfig()
 
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"strings"
 )
 
 type Handler struct {
@@ -19,6 +20,25 @@ func Init(url string) Handler {
 		log.Fatalf("Failed to open database connection: %v", err)
 	}
 
+	err = dedupeInteractions(db)
+	if err != nil {
+		log.Fatalf("Failed to dedupe interactions: %v", err)
+	}
+
+	err = convertGamePlayTime(db)
+	if err != nil {
+		log.Fatalf("Failed to convert games.play_time: %v", err)
+	}
+
+	err = mergeTagVariants(db)
+	if err != nil {
+		log.Fatalf("Failed to merge tag variants: %v", err)
+	}
+
+	// Checked before AutoMigrate adds the columns, so the backfill below only
+	// runs once.
+	backfillInput := db.Migrator().HasTable(&models.Game{}) && !db.Migrator().HasColumn(&models.Game{}, "TouchSupport")
+
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.Game{},
@@ -33,11 +53,43 @@ func Init(url string) Handler {
 		&models.GenrePreference{},
 		&models.UserGameInteraction{},
 		&models.UserSeenGame{},
+		&models.GameClaim{},
+		&models.GameClaimEvent{},
+		&models.SearchOutbox{},
+		&models.PlaySession{},
+		&models.GameDailyStat{},
+		&models.GamePlayerDay{},
+		&models.AnalyticsBackfill{},
+		&models.Collection{},
+		&models.CollectionItem{},
+		&models.FeaturedGame{},
+		&models.Playlist{},
+		&models.PlaylistItem{},
+		&models.PlaylistFollow{},
+		&models.GameSimilarity{},
+		&models.Review{},
+		&models.ReviewHelpfulVote{},
+		&models.Leaderboard{},
+		&models.LeaderboardScore{},
+		&models.Achievement{},
+		&models.UserAchievement{},
+		&models.SaveState{},
+		&models.ControlScheme{},
+		&models.ImportRun{},
+		&models.MigrationCheckpoint{},
+		&models.ShareLink{},
+		&models.ShareClick{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to auto-migrate: %v", err)
 	}
 
+	if backfillInput {
+		if err := backfillInputSupport(db); err != nil {
+			log.Fatalf("Failed to backfill input support: %v", err)
+		}
+	}
+
 	err = AddIndexes(db)
 	if err != nil {
 		log.Fatalf("Failed to add indexes: %v", err)
@@ -46,6 +98,142 @@ func Init(url string) Handler {
 	return Handler{DB: db}
 }
 
+// dedupeInteractions removes duplicate likes and bookmarks left over from
+// before they were unique per user and game, so AutoMigrate can add the
+// unique indexes. Counters on the affected games are recomputed from the
+// remaining rows.
+func dedupeInteractions(db *gorm.DB) error {
+	for _, t := range []struct{ table, counter string }{
+		{"likes", "like_count"},
+		{"bookmarks", "bookmark_count"},
+	} {
+		if !db.Migrator().HasTable(t.table) {
+			continue
+		}
+
+		result := db.Exec(`
+			DELETE FROM ` + t.table + ` a
+			USING ` + t.table + ` b
+			WHERE a.user_id = b.user_id AND a.game_id = b.game_id
+			AND (a.created_at, a.id) > (b.created_at, b.id)
+		`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		logga.Warn().Int64("rows", result.RowsAffected).Msgf("Removed duplicate %s", t.table)
+		if err := db.Exec(`
+			UPDATE games SET ` + t.counter + ` = (
+				SELECT COUNT(*) FROM ` + t.table + ` WHERE ` + t.table + `.game_id = games.id
+			)
+		`).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeTagVariants folds tags from before names were normalized into one
+// tag per normalized name, the way services.NormalizeTagName spells it. The
+// oldest tag of each group is kept and renamed, and games move over to it.
+func mergeTagVariants(db *gorm.DB) error {
+	if !db.Migrator().HasTable("tags") || !db.Migrator().HasTable("game_tags") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := AdvisoryXactLock(tx, "merge_tag_variants"); err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			CREATE TEMP TABLE tag_merges ON COMMIT DROP AS
+			SELECT id, name, MIN(id) OVER (PARTITION BY name) AS keep_id
+			FROM (SELECT id, btrim(regexp_replace(lower(name), '\s+', ' ', 'g')) AS name FROM tags) normalized
+		`).Error; err != nil {
+			return err
+		}
+
+		// A game tagged with several variants keeps one row, which then
+		// moves to the kept tag.
+		if err := tx.Exec(`
+			DELETE FROM game_tags
+			USING tag_merges m, game_tags other, tag_merges om
+			WHERE game_tags.tag_id = m.id
+			AND other.game_id = game_tags.game_id AND other.tag_id = om.id
+			AND om.keep_id = m.keep_id AND om.id < m.id
+		`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			UPDATE game_tags SET tag_id = m.keep_id
+			FROM tag_merges m
+			WHERE game_tags.tag_id = m.id AND m.id <> m.keep_id
+		`).Error; err != nil {
+			return err
+		}
+
+		merged := tx.Exec(`DELETE FROM tags USING tag_merges m WHERE tags.id = m.id AND m.id <> m.keep_id`)
+		if merged.Error != nil {
+			return merged.Error
+		}
+		renamed := tx.Exec(`UPDATE tags SET name = m.name FROM tag_merges m WHERE tags.id = m.id AND tags.name <> m.name`)
+		if renamed.Error != nil {
+			return renamed.Error
+		}
+		if merged.RowsAffected > 0 || renamed.RowsAffected > 0 {
+			logga.Warn().Int64("merged", merged.RowsAffected).Int64("renamed", renamed.RowsAffected).Msg("Normalized tag names")
+		}
+		return nil
+	})
+}
+
+// convertGamePlayTime turns games.play_time from the timestamp it was
+// created as into a number of seconds. The old column never held a usable
+// value, so it is reset to zero.
+func convertGamePlayTime(db *gorm.DB) error {
+	var dataType string
+	if err := db.Raw(`
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = CURRENT_SCHEMA() AND table_name = 'games' AND column_name = 'play_time'
+	`).Scan(&dataType).Error; err != nil {
+		return err
+	}
+	if !strings.HasPrefix(dataType, "timestamp") {
+		return nil
+	}
+
+	return db.Exec(`
+		ALTER TABLE games
+		ALTER COLUMN play_time DROP DEFAULT,
+		ALTER COLUMN play_time TYPE bigint USING 0,
+		ALTER COLUMN play_time SET DEFAULT 0
+	`).Error
+}
+
+// backfillInputSupport gives games from before touch_support and
+// gamepad_support existed the defaults for their type, so devices without a
+// keyboard don't lose the whole catalog.
+func backfillInputSupport(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, gameType := range []string{models.GameTypeHTML5, models.GameTypeUnity, models.GameTypeGodot} {
+			touch, gamepad := models.DefaultInputSupport(gameType)
+			result := tx.Model(&models.Game{}).Where("game_type = ?", gameType).UpdateColumns(map[string]interface{}{
+				"touch_support":   touch,
+				"gamepad_support": gamepad,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			logga.Warn().Int64("games", result.RowsAffected).Msgf("Backfilled input support of %s games", gameType)
+		}
+		return nil
+	})
+}
+
 func AddIndexes(db *gorm.DB) error {
 	//if err := db.Exec("CREATE INDEX idx_user_seen_games_user_id_game_id_seen_at ON user_seen_games(user_id, game_id, seen_at)").Error; err != nil {
 	//	return err
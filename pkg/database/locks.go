@@ -0,0 +1,20 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// TryAdvisoryXactLock takes the advisory lock named by key for the rest of the
+// transaction, without waiting for it. It reports false when another
+// transaction holds the lock.
+func TryAdvisoryXactLock(tx *gorm.DB, key string) (bool, error) {
+	var locked bool
+	err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", key).Scan(&locked).Error
+	return locked, err
+}
+
+// AdvisoryXactLock waits for the advisory lock named by key and holds it for
+// the rest of the transaction.
+func AdvisoryXactLock(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// ActiveGameFilter is the condition that hides soft deleted games. Raw SQL that
+// aliases the games table should pass the alias, everything else should use
+// the ActiveGames scope.
+func ActiveGameFilter(alias string) string {
+	return fmt.Sprintf("%s.is_deleted = false", alias)
+}
+
+func ActiveGames(db *gorm.DB) *gorm.DB {
+	return db.Where(ActiveGameFilter("games"))
+}
@@ -0,0 +1,177 @@
+package gamesearch
+
+import (
+	"context"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"strconv"
+	"strings"
+)
+
+// Algolia searches the hosted Algolia index. Records use the game ID as their
+// objectID and expose genreId, tags, gameType, isLandscape, hasControls,
+// touchSupport, gamepadSupport and isDeleted as filterable attributes.
+//
+// Replace is what applies algoliaSettings, so run the reindex command after
+// deploying a release that adds a filterable attribute; until then records
+// lack it and filters on it match nothing.
+type Algolia struct {
+	index *search.Index
+}
+
+// algoliaSettings declares every attribute Search filters or boosts on, which
+// Algolia requires before it will filter on them.
+var algoliaSettings = search.Settings{
+	AttributesForFaceting: opt.AttributesForFaceting(
+		"filterOnly(genreId)",
+		"filterOnly(tags)",
+		"filterOnly(gameType)",
+		"filterOnly(isLandscape)",
+		"filterOnly(hasControls)",
+		"filterOnly(touchSupport)",
+		"filterOnly(gamepadSupport)",
+		"filterOnly(isDeleted)",
+	),
+}
+
+func NewAlgolia(client *search.Client, indexName string) *Algolia {
+	return &Algolia{index: client.InitIndex(indexName)}
+}
+
+func (a *Algolia) Search(ctx context.Context, query Query) (Result, error) {
+	filters := []string{"isDeleted:false"}
+	if query.GenreID != "" {
+		filters = append(filters, fmt.Sprintf("genreId:%q", query.GenreID))
+	}
+	if query.Tag != "" {
+		filters = append(filters, fmt.Sprintf("tags:%q", query.Tag))
+	}
+	if query.GameType != "" {
+		filters = append(filters, fmt.Sprintf("gameType:%q", query.GameType))
+	}
+	if query.IsLandscape != nil {
+		filters = append(filters, "isLandscape:"+strconv.FormatBool(*query.IsLandscape))
+	}
+	if playable := deviceFilter(query.Device); playable != "" {
+		filters = append(filters, playable)
+	}
+
+	// Algolia can't sort on an expression, so games that fit the screen are
+	// boosted instead of the others being demoted.
+	var boost []interface{}
+	if fits := fittingOrientation(query.Device); fits != "" {
+		boost = append(boost, opt.OptionalFilter(fits))
+	}
+
+	res, err := a.index.Search(query.Text, append([]interface{}{
+		opt.Filters(strings.Join(filters, " AND ")),
+		opt.Page(query.Page - 1),
+		opt.HitsPerPage(query.PageSize),
+		opt.AttributesToHighlight("title", "description"),
+		opt.HighlightPreTag("<em>"),
+		opt.HighlightPostTag("</em>"),
+		ctx,
+	}, boost...)...)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to search algolia: %w", err)
+	}
+
+	result := Result{Total: int64(res.NbHits), Hits: make([]Hit, 0, len(res.Hits))}
+	for _, raw := range res.Hits {
+		id, _ := raw["objectID"].(string)
+		if id == "" {
+			continue
+		}
+		result.Hits = append(result.Hits, Hit{GameID: id, Highlights: highlightsOf(raw)})
+	}
+	return result, nil
+}
+
+// deviceFilter mirrors capability.Device.PlayableFilter on the indexed
+// attributes.
+func deviceFilter(device capability.Device) string {
+	if !device.RestrictsInput() {
+		return ""
+	}
+	var conditions []string
+	if device.Touch {
+		conditions = append(conditions, "touchSupport:true", "hasControls:true")
+	}
+	if device.Gamepad {
+		conditions = append(conditions, "gamepadSupport:true")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+func fittingOrientation(device capability.Device) string {
+	switch device.FitOrientation() {
+	case models.ControlOrientationPortrait:
+		return "isLandscape:false"
+	case models.ControlOrientationLandscape:
+		return "isLandscape:true"
+	}
+	return ""
+}
+
+func highlightsOf(raw map[string]interface{}) map[string]string {
+	fields, ok := raw["_highlightResult"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	highlights := make(map[string]string)
+	for name, field := range fields {
+		detail, ok := field.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := detail["value"].(string); ok {
+			highlights[name] = value
+		}
+	}
+	return highlights
+}
+
+func (a *Algolia) Index(ctx context.Context, documents []Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	if _, err := a.index.SaveObjects(documents, ctx); err != nil {
+		return fmt.Errorf("failed to save algolia objects: %w", err)
+	}
+	return nil
+}
+
+func (a *Algolia) Remove(ctx context.Context, gameIDs []string) error {
+	if len(gameIDs) == 0 {
+		return nil
+	}
+	if _, err := a.index.DeleteObjects(gameIDs, ctx); err != nil {
+		return fmt.Errorf("failed to delete algolia objects: %w", err)
+	}
+	return nil
+}
+
+// Replace applies algoliaSettings and then swaps in a new copy of the index,
+// which carries the settings over.
+func (a *Algolia) Replace(ctx context.Context, documents []Document) error {
+	res, err := a.index.SetSettings(algoliaSettings, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update algolia settings: %w", err)
+	}
+	if err := res.Wait(); err != nil {
+		return fmt.Errorf("failed to update algolia settings: %w", err)
+	}
+
+	g, err := a.index.ReplaceAllObjects(documents, opt.Safe(true), ctx)
+	if err != nil {
+		return fmt.Errorf("failed to replace algolia index: %w", err)
+	}
+	if g != nil {
+		return g.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gamesearch
+
+import (
+	"context"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"time"
+)
+
+type Query struct {
+	Text        string
+	GenreID     string
+	Tag         string
+	GameType    string
+	IsLandscape *bool
+	// Device drops games the client can't play and ranks the ones that don't
+	// fit its screen last.
+	Device   capability.Device
+	Page     int
+	PageSize int
+}
+
+// Hit identifies a matching game. Backends only return IDs so that callers
+// load the games from Postgres, which keeps deleted games out of results even
+// when an index is stale.
+type Hit struct {
+	GameID     string
+	Highlights map[string]string
+}
+
+type Result struct {
+	Hits  []Hit
+	Total int64
+}
+
+// Document is the searchable projection of a game.
+type Document struct {
+	ObjectID    string   `json:"objectID"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	GenreID     string   `json:"genreId"`
+	Tags        []string `json:"tags"`
+	GameType    string   `json:"gameType"`
+	IsLandscape bool     `json:"isLandscape"`
+	// HasControls is set when the game has an on-screen control scheme.
+	HasControls    bool      `json:"hasControls"`
+	TouchSupport   bool      `json:"touchSupport"`
+	GamepadSupport bool      `json:"gamepadSupport"`
+	IsDeleted      bool      `json:"isDeleted"`
+	PlayCount      int       `json:"playCount"`
+	LikeCount      int       `json:"likeCount"`
+	CommentCount   int       `json:"commentCount"`
+	BookmarkCount  int       `json:"bookmarkCount"`
+	RatingCount    int       `json:"ratingCount"`
+	AverageRating  float64   `json:"averageRating"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func NewDocument(game models.Game) Document {
+	tags := make([]string, 0, len(game.Tags))
+	for _, tag := range game.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return Document{
+		ObjectID:       game.ID,
+		Title:          game.Title,
+		Description:    game.Description,
+		GenreID:        game.GenreID,
+		Tags:           tags,
+		GameType:       game.GameType,
+		IsLandscape:    game.IsLandscape,
+		HasControls:    game.ButtonMapping,
+		TouchSupport:   game.TouchSupport,
+		GamepadSupport: game.GamepadSupport,
+		IsDeleted:      game.IsDeleted,
+		PlayCount:      game.PlayCount,
+		LikeCount:      game.LikeCount,
+		CommentCount:   game.CommentCount,
+		BookmarkCount:  game.BookmarkCount,
+		RatingCount:    game.RatingCount,
+		AverageRating:  game.AverageRating,
+		CreatedAt:      game.CreatedAt,
+	}
+}
+
+type Backend interface {
+	Search(ctx context.Context, query Query) (Result, error)
+	// Index creates or replaces the given documents.
+	Index(ctx context.Context, documents []Document) error
+	Remove(ctx context.Context, gameIDs []string) error
+	// Replace swaps the whole index for the given documents.
+	Replace(ctx context.Context, documents []Document) error
+}
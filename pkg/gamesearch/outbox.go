@@ -0,0 +1,65 @@
+package gamesearch
+
+import (
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Enqueue marks games as needing a search index update. Pass the transaction
+// that changes the games so the outbox row commits or rolls back with them.
+func Enqueue(tx *gorm.DB, gameIDs ...string) error {
+	if len(gameIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]models.SearchOutbox, 0, len(gameIDs))
+	for _, id := range gameIDs {
+		rows = append(rows, models.SearchOutbox{GameID: id, AvailableAt: now})
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to enqueue search sync: %w", err)
+	}
+	return nil
+}
+
+type OutboxStats struct {
+	Pending       int64      `json:"pending"`
+	Retrying      int64      `json:"retrying"`
+	OldestPending *time.Time `json:"oldestPending,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// Stats reports how far the search index is behind Postgres.
+func Stats(db *gorm.DB) (OutboxStats, error) {
+	var stats OutboxStats
+	pending := db.Model(&models.SearchOutbox{}).Where("processed_at IS NULL")
+
+	if err := pending.Session(&gorm.Session{}).Count(&stats.Pending).Error; err != nil {
+		return stats, err
+	}
+	if err := pending.Session(&gorm.Session{}).Where("attempts > 0").Count(&stats.Retrying).Error; err != nil {
+		return stats, err
+	}
+
+	var oldest models.SearchOutbox
+	err := pending.Session(&gorm.Session{}).Order("created_at ASC").Limit(1).Find(&oldest).Error
+	if err != nil {
+		return stats, err
+	}
+	if oldest.ID != 0 {
+		stats.OldestPending = &oldest.CreatedAt
+	}
+
+	var failed models.SearchOutbox
+	err = pending.Session(&gorm.Session{}).Where("last_error <> ''").Order("id DESC").Limit(1).Find(&failed).Error
+	if err != nil {
+		return stats, err
+	}
+	stats.LastError = failed.LastError
+
+	return stats, nil
+}
@@ -0,0 +1,176 @@
+package gamesearch
+
+import (
+	"context"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"html"
+	"strings"
+)
+
+// ts_headline marks matches with control characters, which are stripped from
+// the game text beforehand, so the text can be escaped before the markers
+// become <em> tags.
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+	gameDocument   = "to_tsvector('english', coalesce(games.title, '') || ' ' || coalesce(games.description, ''))"
+	headlineStyle  = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=1, MaxWords=20, MinWords=5"
+	headlineText   = "translate(coalesce(%s, ''), chr(1) || chr(2), '')"
+)
+
+// Postgres searches games with full text search on title and description,
+// falling back to trigram similarity on the title so typos still match.
+// It needs no external service, which makes it the default for local dev.
+type Postgres struct {
+	db *gorm.DB
+	// trigram is false when the pg_trgm extension is missing and the
+	// database user may not create it. Titles then only match by full text.
+	trigram bool
+}
+
+func NewPostgres(db *gorm.DB) (*Postgres, error) {
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_games_search_document ON games USING GIN (" + gameDocument + ")").Error; err != nil {
+		return nil, fmt.Errorf("failed to prepare search indexes: %w", err)
+	}
+
+	trigram, err := enableTrigram(db)
+	if err != nil {
+		return nil, err
+	}
+	if trigram {
+		if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_games_title_trgm ON games USING GIN (title gin_trgm_ops)").Error; err != nil {
+			return nil, fmt.Errorf("failed to prepare search indexes: %w", err)
+		}
+	}
+	return &Postgres{db: db, trigram: trigram}, nil
+}
+
+// enableTrigram reports whether pg_trgm is available, creating it when the
+// database user is allowed to.
+func enableTrigram(db *gorm.DB) (bool, error) {
+	var installed bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").Scan(&installed).Error; err != nil {
+		return false, fmt.Errorf("failed to check for pg_trgm: %w", err)
+	}
+	if installed {
+		return true, nil
+	}
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Warn().Err(err).Msg("pg_trgm is unavailable, search will not match typos in titles")
+		return false, nil
+	}
+	return true, nil
+}
+
+func (p *Postgres) Search(ctx context.Context, query Query) (Result, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(database.ActiveGames)
+		if query.Text != "" {
+			if p.trigram {
+				db = db.Where("("+gameDocument+" @@ plainto_tsquery('english', ?) OR games.title % ?)", query.Text, query.Text)
+			} else {
+				db = db.Where(gameDocument+" @@ plainto_tsquery('english', ?)", query.Text)
+			}
+		}
+		if query.GenreID != "" {
+			db = db.Where("games.genre_id = ?", query.GenreID)
+		}
+		if query.GameType != "" {
+			db = db.Where("games.game_type = ?", query.GameType)
+		}
+		if query.IsLandscape != nil {
+			db = db.Where("games.is_landscape = ?", *query.IsLandscape)
+		}
+		if playable := query.Device.PlayableFilter("games"); playable != "" {
+			db = db.Where(playable)
+		}
+		if query.Tag != "" {
+			db = db.Where(`EXISTS (
+				SELECT 1 FROM game_tags
+				JOIN tags ON tags.id = game_tags.tag_id
+				WHERE game_tags.game_id = games.id AND LOWER(tags.name) = ?
+			)`, query.Tag)
+		}
+		return db
+	}
+
+	var total int64
+	if err := p.db.WithContext(ctx).Table("games").Scopes(filter).Count(&total).Error; err != nil {
+		return Result{}, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	var rows []struct {
+		ID                   string
+		TitleHighlight       string
+		DescriptionHighlight string
+	}
+
+	db := p.db.WithContext(ctx).Table("games").Scopes(filter)
+	if demotion := query.Device.DemotionOrder("games"); demotion != "" {
+		db = db.Order(demotion)
+	}
+	if query.Text != "" {
+		db = db.Select(
+			"games.id, "+
+				"ts_headline('english', "+fmt.Sprintf(headlineText, "games.title")+", plainto_tsquery('english', ?), ?) AS title_highlight, "+
+				"ts_headline('english', "+fmt.Sprintf(headlineText, "games.description")+", plainto_tsquery('english', ?), ?) AS description_highlight",
+			query.Text, headlineStyle, query.Text, headlineStyle,
+		)
+		if p.trigram {
+			db = db.Order(gorm.Expr(
+				"ts_rank("+gameDocument+", plainto_tsquery('english', ?)) + similarity(games.title, ?) DESC, games.play_count DESC",
+				query.Text, query.Text,
+			))
+		} else {
+			db = db.Order(gorm.Expr(
+				"ts_rank("+gameDocument+", plainto_tsquery('english', ?)) DESC, games.play_count DESC",
+				query.Text,
+			))
+		}
+	} else {
+		db = db.Select("games.id").Order("games.play_count DESC, games.like_count DESC")
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Offset(offset).Limit(query.PageSize).Scan(&rows).Error; err != nil {
+		return Result{}, fmt.Errorf("failed to search games: %w", err)
+	}
+
+	result := Result{Total: total, Hits: make([]Hit, 0, len(rows))}
+	for _, row := range rows {
+		hit := Hit{GameID: row.ID}
+		if query.Text != "" {
+			hit.Highlights = map[string]string{
+				"title":       highlight(row.TitleHighlight),
+				"description": highlight(row.DescriptionHighlight),
+			}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, nil
+}
+
+// highlight escapes a ts_headline result and turns its match markers into
+// <em> tags.
+func highlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<em>")
+	return strings.ReplaceAll(escaped, highlightStop, "</em>")
+}
+
+// Index, Remove and Replace are no-ops because Postgres searches the games
+// table directly and is never out of date.
+func (p *Postgres) Index(ctx context.Context, documents []Document) error {
+	return nil
+}
+
+func (p *Postgres) Remove(ctx context.Context, gameIDs []string) error {
+	return nil
+}
+
+func (p *Postgres) Replace(ctx context.Context, documents []Document) error {
+	return nil
+}
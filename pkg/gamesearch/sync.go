@@ -0,0 +1,202 @@
+package gamesearch
+
+import (
+	"context"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	syncBatchSize    = 100
+	syncPollInterval = 5 * time.Second
+	maxRetryDelay    = time.Hour
+	processedTTL     = 7 * 24 * time.Hour
+	// syncLease is how long a worker owns the rows it picked up.
+	syncLease = 5 * time.Minute
+)
+
+// Syncer drains the search outbox into the search backend.
+type Syncer struct {
+	db      *gorm.DB
+	backend Backend
+}
+
+func NewSyncer(db *gorm.DB, backend Backend) *Syncer {
+	return &Syncer{db: db, backend: backend}
+}
+
+// Run processes the outbox until the context is cancelled.
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncPollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		for {
+			processed, err := s.ProcessBatch(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Search sync batch failed")
+				break
+			}
+			if processed < syncBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			if err := s.db.Where("processed_at < ?", time.Now().Add(-processedTTL)).Delete(&models.SearchOutbox{}).Error; err != nil {
+				log.Warn().Err(err).Msg("Failed to clean up search outbox")
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch syncs one batch of due outbox rows and returns how many it
+// picked up. Rows are leased with SKIP LOCKED so several instances can run
+// the worker at once, and the lease is committed before the backend is
+// called so no transaction stays open across the network. A worker that dies
+// mid-batch leaves its rows to be picked up again once the lease runs out.
+// Failed rows are retried with exponential backoff.
+func (s *Syncer) ProcessBatch(ctx context.Context) (int, error) {
+	db := s.db.WithContext(ctx)
+
+	var rows []models.SearchOutbox
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("processed_at IS NULL AND available_at <= ?", now).
+			Order("id ASC").
+			Limit(syncBatchSize).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		return tx.Model(&models.SearchOutbox{}).Where("id IN ?", ids).
+			Update("available_at", now.Add(syncLease)).Error
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if !seen[row.GameID] {
+			seen[row.GameID] = true
+			ids = append(ids, row.GameID)
+		}
+	}
+
+	syncErr := s.syncGames(ctx, db, ids)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, row := range rows {
+			updates := map[string]interface{}{"processed_at": now, "last_error": ""}
+			if syncErr != nil {
+				updates = map[string]interface{}{
+					"attempts":     row.Attempts + 1,
+					"last_error":   syncErr.Error(),
+					"available_at": now.Add(retryDelay(row.Attempts + 1)),
+				}
+			}
+			if err := tx.Model(&models.SearchOutbox{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if syncErr != nil {
+		log.Warn().Err(syncErr).Int("games", len(ids)).Msg("Search sync failed, will retry")
+	}
+	return len(rows), err
+}
+
+// syncGames pushes the current state of the games to the backend. Games that
+// are deleted or no longer exist are removed from the index.
+func (s *Syncer) syncGames(ctx context.Context, db *gorm.DB, ids []string) error {
+	var games []models.Game
+	if err := db.Preload("Tags").Where("id IN ?", ids).Find(&games).Error; err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+
+	found := make(map[string]bool, len(games))
+	var documents []Document
+	var removed []string
+	for _, game := range games {
+		found[game.ID] = true
+		if game.IsDeleted {
+			removed = append(removed, game.ID)
+			continue
+		}
+		documents = append(documents, NewDocument(game))
+	}
+	for _, id := range ids {
+		if !found[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	if err := s.backend.Index(ctx, documents); err != nil {
+		return err
+	}
+	return s.backend.Remove(ctx, removed)
+}
+
+// Reindex rebuilds the whole index from Postgres and clears the outbox rows
+// it made redundant.
+func Reindex(ctx context.Context, db *gorm.DB, backend Backend) (int, error) {
+	startedAt := time.Now()
+
+	var documents []Document
+	var games []models.Game
+	err := db.WithContext(ctx).Preload("Tags").Where("is_deleted = false").
+		FindInBatches(&games, 500, func(tx *gorm.DB, batch int) error {
+			for _, game := range games {
+				documents = append(documents, NewDocument(game))
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load games: %w", err)
+	}
+
+	if err := backend.Replace(ctx, documents); err != nil {
+		return 0, err
+	}
+
+	if err := db.WithContext(ctx).Model(&models.SearchOutbox{}).
+		Where("processed_at IS NULL AND created_at < ?", startedAt).
+		Update("processed_at", time.Now()).Error; err != nil {
+		return len(documents), fmt.Errorf("failed to clear outbox: %w", err)
+	}
+
+	return len(documents), nil
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := syncPollInterval << uint(attempts)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,285 @@
+// Package importer loads game catalogs from CSV or JSON files, upserting
+// games by their external ID.
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBatchSize = 100
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+type Options struct {
+	Path string
+	// Format is csv or json. It is taken from the file extension when empty.
+	Format    string
+	BatchSize int
+	// DryRun reports what the import would change without writing anything.
+	DryRun bool
+	// SkipSearch leaves imported games out of the search sync outbox.
+	SkipSearch bool
+	// Restart ignores the checkpoint of an unfinished import of the same
+	// file and starts over from the first row.
+	Restart bool
+}
+
+type importer struct {
+	opts   Options
+	report *Report
+	names  *services.NameResolver
+}
+
+// Run imports the catalog at opts.Path. Rows are committed in batches and
+// each batch moves the file's checkpoint forward, so a failed import resumes
+// after the last committed batch when it is run again. Invalid rows, and rows
+// that would change a claimed game, are skipped and listed in the report.
+func Run(ctx context.Context, db *gorm.DB, opts Options) (*Report, error) {
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	if opts.Format == "" {
+		opts.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.Path)), ".")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	records, err := readRecords(data, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		opts:   opts,
+		report: &Report{DryRun: opts.DryRun, Rows: len(records)},
+		names:  services.NewNameResolver(),
+	}
+	db = db.WithContext(ctx)
+
+	if opts.DryRun {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := im.importBatches(tx, records, nil); err != nil {
+				return err
+			}
+			return errDryRun
+		})
+		if !errors.Is(err, errDryRun) {
+			return nil, err
+		}
+		return im.report, nil
+	}
+
+	sum := sha256.Sum256(data)
+	run, err := checkpoint(db, opts.Path, hex.EncodeToString(sum[:]), opts.Restart)
+	if err != nil {
+		return nil, err
+	}
+	im.report.ResumedAt = run.NextRow
+	if err := im.importBatches(db, records[run.NextRow:], run); err != nil {
+		return im.report, err
+	}
+
+	now := time.Now()
+	if err := db.Model(run).Update("completed_at", now).Error; err != nil {
+		return im.report, fmt.Errorf("failed to complete import run: %w", err)
+	}
+	return im.report, nil
+}
+
+// checkpoint returns the unfinished run of the same file, or starts a new one.
+func checkpoint(db *gorm.DB, source, checksum string, restart bool) (*models.ImportRun, error) {
+	open := db.Where("checksum = ? AND completed_at IS NULL", checksum)
+	if restart {
+		if err := open.Delete(&models.ImportRun{}).Error; err != nil {
+			return nil, fmt.Errorf("failed to discard import checkpoint: %w", err)
+		}
+	} else {
+		var run models.ImportRun
+		err := open.Order("id DESC").First(&run).Error
+		if err == nil {
+			return &run, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load import checkpoint: %w", err)
+		}
+	}
+
+	run := models.ImportRun{Source: source, Checksum: checksum}
+	if err := db.Create(&run).Error; err != nil {
+		return nil, fmt.Errorf("failed to start import run: %w", err)
+	}
+	return &run, nil
+}
+
+// importBatches imports the records one transaction per batch. With a run,
+// each batch also moves its checkpoint past the batch.
+func (im *importer) importBatches(db *gorm.DB, records []record, run *models.ImportRun) error {
+	for start := 0; start < len(records); start += im.opts.BatchSize {
+		end := start + im.opts.BatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		batch := records[start:end]
+
+		before := im.report.counts()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var changed []string
+			for _, rec := range batch {
+				if rec.Err != nil {
+					im.report.Invalid = append(im.report.Invalid, RowError{
+						Row:        rec.Number,
+						ExternalID: rec.Row.ExternalID,
+						Error:      rec.Err.Error(),
+					})
+					continue
+				}
+				gameId, err := im.importRow(tx, rec)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", rec.Number, err)
+				}
+				if gameId != "" {
+					changed = append(changed, gameId)
+				}
+			}
+
+			if !im.opts.SkipSearch {
+				if err := gamesearch.Enqueue(tx, changed...); err != nil {
+					return err
+				}
+			}
+			if run == nil {
+				return nil
+			}
+
+			after := im.report.counts()
+			return tx.Model(run).UpdateColumns(map[string]interface{}{
+				"next_row":   batch[len(batch)-1].Number,
+				"created":    gorm.Expr("created + ?", after.created-before.created),
+				"updated":    gorm.Expr("updated + ?", after.updated-before.updated),
+				"unchanged":  gorm.Expr("unchanged + ?", after.unchanged-before.unchanged),
+				"invalid":    gorm.Expr("invalid + ?", after.invalid-before.invalid),
+				"conflicts":  gorm.Expr("conflicts + ?", after.conflicts-before.conflicts),
+				"updated_at": time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importRow creates or updates the row's game. It returns the game ID when
+// the game changed.
+func (im *importer) importRow(tx *gorm.DB, rec record) (string, error) {
+	row := rec.Row
+	genreId, newGenre, err := im.names.Genre(tx, row.Genre)
+	if err != nil {
+		return "", err
+	}
+	if newGenre {
+		im.report.NewGenres = append(im.report.NewGenres, row.Genre)
+	}
+	tags, createdTags, err := im.names.Tags(tx, row.Tags)
+	if err != nil {
+		return "", err
+	}
+	im.report.NewTags = append(im.report.NewTags, createdTags...)
+
+	var game models.Game
+	err = tx.Preload("Genre").Preload("Tags").First(&game, "external_id = ?", row.ExternalID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		externalId := row.ExternalID
+		game = models.Game{
+			ExternalID:  &externalId,
+			Title:       row.Title,
+			Description: row.Description,
+			EmbedLink:   row.EmbedLink,
+			GameType:    row.GameType,
+			GenreID:     genreId,
+			IsLandscape: row.IsLandscape,
+			Tags:        tags,
+		}
+		game.TouchSupport, game.GamepadSupport = models.DefaultInputSupport(row.GameType)
+		if err := tx.Create(&game).Error; err != nil {
+			return "", fmt.Errorf("failed to create game: %w", err)
+		}
+		im.report.Created = append(im.report.Created, Change{Row: rec.Number, ExternalID: row.ExternalID, Title: row.Title})
+		return game.ID, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find game: %w", err)
+	}
+
+	var fields []FieldChange
+	updates := map[string]interface{}{}
+	compare := func(field, column, from, to string, value interface{}) {
+		if from != to {
+			fields = append(fields, FieldChange{Field: field, From: from, To: to})
+			updates[column] = value
+		}
+	}
+	compare("title", "title", game.Title, row.Title, row.Title)
+	compare("description", "description", game.Description, row.Description, row.Description)
+	compare("embedLink", "embed_link", game.EmbedLink, row.EmbedLink, row.EmbedLink)
+	compare("gameType", "game_type", game.GameType, row.GameType, row.GameType)
+	if game.GenreID != genreId {
+		compare("genre", "genre_id", game.Genre.Name, row.Genre, genreId)
+	}
+	compare("isLandscape", "is_landscape", strconv.FormatBool(game.IsLandscape), strconv.FormatBool(row.IsLandscape), row.IsLandscape)
+
+	oldTags, newTags := tagNames(game.Tags), tagNames(tags)
+	tagsChanged := oldTags != newTags
+	if tagsChanged {
+		fields = append(fields, FieldChange{Field: "tags", From: oldTags, To: newTags})
+	}
+
+	if len(fields) == 0 {
+		im.report.Unchanged++
+		return "", nil
+	}
+	// A claimed game belongs to its developer, whose edits win over the
+	// catalog.
+	if game.IsClaimed || game.CreatorID != nil {
+		im.report.Conflicts = append(im.report.Conflicts, Change{Row: rec.Number, ExternalID: row.ExternalID, Title: game.Title, Fields: fields})
+		return "", nil
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&game).Updates(updates).Error; err != nil {
+			return "", fmt.Errorf("failed to update game: %w", err)
+		}
+	}
+	if tagsChanged {
+		if err := tx.Model(&game).Association("Tags").Replace(tags); err != nil {
+			return "", fmt.Errorf("failed to update tags: %w", err)
+		}
+	}
+	im.report.Updated = append(im.report.Updated, Change{Row: rec.Number, ExternalID: row.ExternalID, Title: row.Title, Fields: fields})
+	return game.ID, nil
+}
+
+func tagNames(tags []models.Tag) string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, strings.ToLower(tag.Name))
+	}
+	sort.Strings(names)
+	return strings.Join(names, csvTagSeparator)
+}
@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxReportedValue is how much of a changed value the report prints.
+const maxReportedValue = 60
+
+// Report describes what an import changed, or would change in a dry run.
+type Report struct {
+	DryRun bool
+	// Rows is the number of rows in the catalog.
+	Rows int
+	// ResumedAt is how many rows an earlier, unfinished run of the same
+	// catalog already committed. Those rows are not in the report.
+	ResumedAt int
+	Created   []Change
+	Updated   []Change
+	Unchanged int
+	// Conflicts are rows that differ from a game its developer has claimed.
+	// They are left alone, since the developer's edits win.
+	Conflicts []Change
+	Invalid   []RowError
+	NewGenres []string
+	NewTags   []string
+}
+
+type Change struct {
+	Row        int
+	ExternalID string
+	Title      string
+	Fields     []FieldChange
+}
+
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+type RowError struct {
+	Row        int
+	ExternalID string
+	Error      string
+}
+
+type reportCounts struct {
+	created, updated, unchanged, conflicts, invalid int
+}
+
+func (r *Report) counts() reportCounts {
+	return reportCounts{len(r.Created), len(r.Updated), r.Unchanged, len(r.Conflicts), len(r.Invalid)}
+}
+
+// Print writes the report as a diff-like listing: + for created games, ~ for
+// updated ones, ≠ for claimed games the catalog disagrees with and ! for rows
+// that were skipped.
+func (r *Report) Print(w io.Writer) {
+	if r.DryRun {
+		fmt.Fprintln(w, "Dry run, nothing was written.")
+	}
+	if r.ResumedAt > 0 {
+		fmt.Fprintf(w, "Resumed after row %d of %d.\n", r.ResumedAt, r.Rows)
+	}
+
+	for _, change := range r.Created {
+		fmt.Fprintf(w, "+ row %d %s %q\n", change.Row, change.ExternalID, change.Title)
+	}
+	for _, change := range r.Updated {
+		fmt.Fprintf(w, "~ row %d %s %q\n", change.Row, change.ExternalID, change.Title)
+		for _, field := range change.Fields {
+			fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, truncate(field.From), truncate(field.To))
+		}
+	}
+	for _, conflict := range r.Conflicts {
+		fmt.Fprintf(w, "≠ row %d %s %q is claimed, not updated\n", conflict.Row, conflict.ExternalID, conflict.Title)
+		for _, field := range conflict.Fields {
+			fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, truncate(field.From), truncate(field.To))
+		}
+	}
+	for _, invalid := range r.Invalid {
+		fmt.Fprintf(w, "! row %d %s: %s\n", invalid.Row, invalid.ExternalID, invalid.Error)
+	}
+	if len(r.NewGenres) > 0 {
+		fmt.Fprintf(w, "New genres: %s\n", strings.Join(r.NewGenres, ", "))
+	}
+	if len(r.NewTags) > 0 {
+		fmt.Fprintf(w, "New tags: %s\n", strings.Join(r.NewTags, ", "))
+	}
+
+	fmt.Fprintf(w, "%d created, %d updated, %d unchanged, %d conflicts, %d invalid\n",
+		len(r.Created), len(r.Updated), r.Unchanged, len(r.Conflicts), len(r.Invalid))
+}
+
+func truncate(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxReportedValue {
+		return value
+	}
+	return string(runes[:maxReportedValue]) + "…"
+}
@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxTitleLength       = 120
+	maxDescriptionLength = 5000
+	maxTags              = 10
+	maxTagLength         = 32
+	// csvTagSeparator splits the tags column of a CSV row.
+	csvTagSeparator = "|"
+)
+
+// Row is one game of a catalog. JSON catalogs are an array of these objects;
+// CSV catalogs use the same names as column headers.
+type Row struct {
+	ExternalID  string   `json:"externalId"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	EmbedLink   string   `json:"embedLink"`
+	GameType    string   `json:"gameType"`
+	Genre       string   `json:"genre"`
+	Tags        []string `json:"tags"`
+	IsLandscape bool     `json:"isLandscape"`
+}
+
+// record is a parsed row and its 1-based position in the catalog. Err is set
+// when the row can't be imported.
+type record struct {
+	Number int
+	Row    Row
+	Err    error
+}
+
+var csvColumns = []string{"externalId", "title", "description", "embedLink", "gameType", "genre", "tags", "isLandscape"}
+
+// readRecords parses a whole catalog. A file that can't be parsed at all is
+// an error; a row that can't be parsed or fails validation is a record with
+// Err set, so the rest of the catalog can still be imported.
+func readRecords(data []byte, format string) ([]record, error) {
+	var records []record
+	var err error
+	switch format {
+	case "csv":
+		records, err = readCSV(data)
+	case "json":
+		records, err = readJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, use csv or json", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen := make(map[string]int)
+	for i := range records {
+		rec := &records[i]
+		if rec.Err != nil {
+			continue
+		}
+		rec.Row = normalizeRow(rec.Row)
+		if rec.Err = validateRow(rec.Row); rec.Err != nil {
+			continue
+		}
+		if first, ok := firstSeen[rec.Row.ExternalID]; ok {
+			rec.Err = fmt.Errorf("duplicate externalId, first used on row %d", first)
+			continue
+		}
+		firstSeen[rec.Row.ExternalID] = rec.Number
+	}
+	return records, nil
+}
+
+func readCSV(data []byte) ([]record, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", name)
+		}
+	}
+
+	var records []record
+	for number := 1; ; number++ {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				records = append(records, record{Number: number, Err: err})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		value := func(name string) string {
+			if i := columns[name]; i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		rec := record{Number: number, Row: Row{
+			ExternalID:  value("externalId"),
+			Title:       value("title"),
+			Description: value("description"),
+			EmbedLink:   value("embedLink"),
+			GameType:    value("gameType"),
+			Genre:       value("genre"),
+		}}
+		if tags := strings.TrimSpace(value("tags")); tags != "" {
+			rec.Row.Tags = strings.Split(tags, csvTagSeparator)
+		}
+		if landscape := strings.TrimSpace(value("isLandscape")); landscape != "" {
+			rec.Row.IsLandscape, rec.Err = strconv.ParseBool(landscape)
+			if rec.Err != nil {
+				rec.Err = fmt.Errorf("isLandscape must be true or false")
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func readJSON(data []byte) ([]record, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("catalog must be a JSON array: %w", err)
+	}
+
+	records := make([]record, 0, len(raw))
+	for i, message := range raw {
+		rec := record{Number: i + 1}
+		if err := json.Unmarshal(message, &rec.Row); err != nil {
+			rec.Err = fmt.Errorf("malformed row: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func normalizeRow(row Row) Row {
+	row.ExternalID = strings.TrimSpace(row.ExternalID)
+	row.Title = strings.TrimSpace(row.Title)
+	row.EmbedLink = strings.TrimSpace(row.EmbedLink)
+	row.GameType = strings.ToLower(strings.TrimSpace(row.GameType))
+	row.Genre = strings.TrimSpace(row.Genre)
+
+	tags := make([]string, 0, len(row.Tags))
+	seen := make(map[string]bool)
+	for _, tag := range row.Tags {
+		tag = services.NormalizeTagName(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	row.Tags = tags
+	return row
+}
+
+// validateRow applies the same rules as creating a game through the API.
+func validateRow(row Row) error {
+	if row.ExternalID == "" {
+		return errors.New("externalId is required")
+	}
+	if row.Title == "" {
+		return errors.New("title is required")
+	}
+	if len([]rune(row.Title)) > maxTitleLength {
+		return fmt.Errorf("title can be at most %d characters", maxTitleLength)
+	}
+	if len([]rune(row.Description)) > maxDescriptionLength {
+		return fmt.Errorf("description can be at most %d characters", maxDescriptionLength)
+	}
+	if err := services.ValidateEmbedLink(row.EmbedLink); err != nil {
+		return errors.New("embedLink must be an https URL")
+	}
+	switch row.GameType {
+	case models.GameTypeHTML5, models.GameTypeUnity, models.GameTypeGodot:
+	default:
+		return errors.New("gameType must be html5, unity or godot")
+	}
+	if row.Genre == "" {
+		return errors.New("genre is required")
+	}
+	if len(row.Tags) > maxTags {
+		return fmt.Errorf("a game can have at most %d tags", maxTags)
+	}
+	for _, tag := range row.Tags {
+		if len([]rune(tag)) > maxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+	}
+	return nil
+}
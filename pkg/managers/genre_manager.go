@@ -1,21 +1,40 @@
 package managers
 
 import (
+	"errors"
 	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"strings"
 )
 
 type GenreManager struct {
-	db database.Handler
+	db      database.Handler
+	storage storage.Backend
 }
 
-func NewGenreManager(db database.Handler) *GenreManager {
-	return &GenreManager{db: db}
+func NewGenreManager(db database.Handler, storageBackend storage.Backend) *GenreManager {
+	return &GenreManager{db: db, storage: storageBackend}
 }
 
+// EnsureGenres seeds the default genres into an empty table. Once genres
+// exist they are managed through the API, so renamed or merged defaults are
+// not brought back on the next boot.
 func (gm *GenreManager) EnsureGenres() error {
+	var existing int64
+	if err := gm.db.DB.Model(&models.Genre{}).Count(&existing).Error; err != nil {
+		return fmt.Errorf("failed to count genres: %v", err)
+	}
+	if existing > 0 {
+		return nil
+	}
+
 	genres := []string{
 		"Action", "Adventure", "Arcade", "Puzzle", "Strategy",
 		"RPG", "Simulation", "Sports", "Racing", "Shooter",
@@ -37,20 +56,208 @@ func (gm *GenreManager) EnsureGenres() error {
 
 func (gm *GenreManager) ListGenres() ([]models.Genre, error) {
 	var genres []models.Genre
-	result := gm.db.DB.Find(&genres)
+	result := gm.db.DB.Order("name ASC").Find(&genres)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to list genres: %v", result.Error)
 	}
 	return genres, nil
 }
 
-func (gm *GenreManager) AddGenre(name string) error {
+func (gm *GenreManager) AddGenre(name string) (models.Genre, error) {
+	name = strings.TrimSpace(name)
+	if err := gm.ensureNameAvailable(gm.db.DB, name, ""); err != nil {
+		return models.Genre{}, err
+	}
+
 	genre := models.Genre{Name: name}
 	result := gm.db.DB.Create(&genre)
 	if result.Error != nil {
-		return fmt.Errorf("failed to add genre %s: %v", name, result.Error)
+		return genre, fmt.Errorf("failed to add genre %s: %v", name, result.Error)
 	}
 	log.Printf("Added new genre: %s", name)
+	return genre, nil
+}
+
+func (gm *GenreManager) GetGenre(genreID string) (models.Genre, error) {
+	var genre models.Genre
+	if err := gm.db.DB.First(&genre, "id = ?", genreID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return genre, fmt.Errorf("%w: genre %s not found", types.ErrNotFound, genreID)
+		}
+		return genre, fmt.Errorf("failed to get genre: %v", err)
+	}
+	return genre, nil
+}
+
+func (gm *GenreManager) RenameGenre(genreID string, name string) (models.Genre, error) {
+	genre, err := gm.GetGenre(genreID)
+	if err != nil {
+		return genre, err
+	}
+
+	name = strings.TrimSpace(name)
+	if err := gm.ensureNameAvailable(gm.db.DB, name, genreID); err != nil {
+		return genre, err
+	}
+
+	if err := gm.db.DB.Model(&genre).Update("name", name).Error; err != nil {
+		return genre, fmt.Errorf("failed to rename genre: %v", err)
+	}
+	genre.Name = name
+	log.Printf("Renamed genre %s to %s", genreID, name)
+	return genre, nil
+}
+
+// MergeGenres moves every game and user preference from the source genre to
+// the target genre and then removes the source. Users that had a preference
+// for both genres keep the stronger of the two.
+func (gm *GenreManager) MergeGenres(sourceID string, targetID string) (models.Genre, error) {
+	var target models.Genre
+	if sourceID == targetID {
+		return target, fmt.Errorf("%w: cannot merge a genre into itself", types.ErrInvalidInput)
+	}
+
+	err := gm.db.DB.Transaction(func(tx *gorm.DB) error {
+		var source models.Genre
+		if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: genre %s not found", types.ErrNotFound, sourceID)
+			}
+			return err
+		}
+		if err := tx.First(&target, "id = ?", targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: genre %s not found", types.ErrNotFound, targetID)
+			}
+			return err
+		}
+
+		var movedGameIDs []string
+		if err := tx.Model(&models.Game{}).Where("genre_id = ?", sourceID).Pluck("id", &movedGameIDs).Error; err != nil {
+			return fmt.Errorf("failed to find games to move: %v", err)
+		}
+		if err := tx.Model(&models.Game{}).Where("genre_id = ?", sourceID).Update("genre_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move games: %v", err)
+		}
+		if err := gamesearch.Enqueue(tx, movedGameIDs...); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+			UPDATE genre_preferences t
+			SET preference = GREATEST(t.preference, s.preference)
+			FROM genre_preferences s
+			WHERE t.user_preference_id = s.user_preference_id
+			AND t.genre_id = ? AND s.genre_id = ?
+		`, targetID, sourceID).Error; err != nil {
+			return fmt.Errorf("failed to combine preferences: %v", err)
+		}
+		if err := tx.Exec(`
+			DELETE FROM genre_preferences s
+			USING genre_preferences t
+			WHERE t.user_preference_id = s.user_preference_id
+			AND t.genre_id = ? AND s.genre_id = ?
+		`, targetID, sourceID).Error; err != nil {
+			return fmt.Errorf("failed to drop duplicate preferences: %v", err)
+		}
+		if err := tx.Model(&models.GenrePreference{}).Where("genre_id = ?", sourceID).Update("genre_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move preferences: %v", err)
+		}
+
+		if err := tx.Delete(&source).Error; err != nil {
+			return fmt.Errorf("failed to delete merged genre: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return target, err
+	}
+
+	log.Printf("Merged genre %s into %s", sourceID, targetID)
+	return target, nil
+}
+
+// DeleteGenre removes a genre that no game uses anymore. Genres that still
+// have games must be merged into another genre instead.
+func (gm *GenreManager) DeleteGenre(genreID string) error {
+	err := gm.db.DB.Transaction(func(tx *gorm.DB) error {
+		var genre models.Genre
+		if err := tx.First(&genre, "id = ?", genreID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: genre %s not found", types.ErrNotFound, genreID)
+			}
+			return err
+		}
+
+		var gameCount int64
+		if err := tx.Model(&models.Game{}).Where("genre_id = ?", genreID).Count(&gameCount).Error; err != nil {
+			return err
+		}
+		if gameCount > 0 {
+			return fmt.Errorf("%w: genre still has %d games, merge it instead", types.ErrConflict, gameCount)
+		}
+
+		if err := tx.Where("genre_id = ?", genreID).Delete(&models.GenrePreference{}).Error; err != nil {
+			return fmt.Errorf("failed to delete preferences: %v", err)
+		}
+		return tx.Delete(&genre).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Deleted genre %s", genreID)
+	return nil
+}
+
+func (gm *GenreManager) ListGamesByGenre(genreID string, pagination types.PaginationQuery) (*types.PaginatedResponse, error) {
+	if _, err := gm.GetGenre(genreID); err != nil {
+		return nil, err
+	}
+
+	var totalItems int64
+	if err := gm.db.DB.Model(&models.Game{}).Scopes(database.ActiveGames).Where("genre_id = ?", genreID).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count games: %v", err)
+	}
+
+	var games []models.Game
+	offset := (pagination.Page - 1) * pagination.PageSize
+	if err := gm.db.DB.Scopes(database.ActiveGames).Where("genre_id = ?", genreID).
+		Order("play_count DESC, like_count DESC").
+		Offset(offset).
+		Limit(pagination.PageSize).
+		Find(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to list games: %v", err)
+	}
+
+	totalPages := (int(totalItems) + pagination.PageSize - 1) / pagination.PageSize
+
+	return &types.PaginatedResponse{
+		Data:       services.GameSummaries(gm.storage, games),
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Page:       pagination.Page,
+		PageSize:   pagination.PageSize,
+	}, nil
+}
+
+func (gm *GenreManager) ensureNameAvailable(tx *gorm.DB, name string, exceptID string) error {
+	if name == "" {
+		return fmt.Errorf("%w: genre name is required", types.ErrInvalidInput)
+	}
+
+	query := tx.Model(&models.Genre{}).Where("LOWER(name) = LOWER(?)", name)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: genre %s already exists", types.ErrConflict, name)
+	}
 	return nil
 }
 
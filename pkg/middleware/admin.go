@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+)
+
+// AdminMiddleware must run after AuthMiddleware. It rejects users that don't
+// have the admin flag set on their profile.
+func AdminMiddleware(databaseHandler database.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetString("userId")
+		if userId == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var user models.User
+		if err := databaseHandler.DB.Select("uid", "is_admin").First(&user, "uid = ?", userId).Error; err != nil || !user.IsAdmin {
+			log.Warn().Str("userId", userId).Msg("Rejected non-admin request")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+
+		c.Set("isAdmin", true)
+		c.Next()
+	}
+}
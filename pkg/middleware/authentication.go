@@ -38,6 +38,22 @@ func AuthMiddleware(supabaseAuth *supabase.SupabaseAuth) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware sets the user context when a valid bearer token is
+// present and lets the request through anonymously otherwise, for routes
+// that show more to signed in users.
+func OptionalAuthMiddleware(supabaseAuth *supabase.SupabaseAuth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bearerToken := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(bearerToken) == 2 && strings.EqualFold(bearerToken[0], "Bearer") {
+			if user, err := supabaseAuth.VerifyToken(bearerToken[1]); err == nil {
+				setUserContext(c, user)
+			}
+		}
+
+		c.Next()
+	}
+}
+
 func setUserContext(c *gin.Context, user *supabase.User) {
 	c.Set("userId", user.ID)
 	c.Set("userEmail", user.Email)
@@ -0,0 +1,216 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const (
+	// legacyExternalIDPrefix marks games that came from Firestore.
+	legacyExternalIDPrefix = "firestore:"
+	// maxTitleLength matches what the API and the importer accept.
+	maxTitleLength = 120
+)
+
+// legacyID derives a stable UUID for a Firestore document so that re-running
+// the migration updates the rows it wrote before instead of duplicating them,
+// and references between collections can be resolved without lookups.
+func legacyID(collection, documentID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("firestore/"+collection+"/"+documentID)).String()
+}
+
+// legacyGame is a game document with the names it references.
+type legacyGame struct {
+	Game  models.Game
+	Genre string
+	Tags  []string
+}
+
+func toUser(doc Document) (models.User, error) {
+	user := models.User{
+		UID:             doc.ID,
+		Email:           strings.TrimSpace(stringField(doc.Data, "email")),
+		Username:        strings.TrimSpace(stringField(doc.Data, "username")),
+		DisplayName:     optionalString(doc.Data, "displayName"),
+		ProfileImageURL: optionalString(doc.Data, "profileImageUrl"),
+		Bio:             optionalString(doc.Data, "bio"),
+		CreatedAt:       timeField(doc.Data, "createdAt"),
+		UpdatedAt:       time.Now(),
+	}
+	if user.Email == "" {
+		return user, errors.New("no email")
+	}
+	if user.Username == "" {
+		return user, errors.New("no username")
+	}
+	return user, nil
+}
+
+func toGame(doc Document) (legacyGame, error) {
+	externalId := legacyExternalIDPrefix + doc.ID
+	game := legacyGame{
+		Game: models.Game{
+			ID:                legacyID("games", doc.ID),
+			ExternalID:        &externalId,
+			Title:             strings.TrimSpace(stringField(doc.Data, "title")),
+			Description:       stringField(doc.Data, "description"),
+			EmbedLink:         strings.TrimSpace(stringField(doc.Data, "embedLink")),
+			GameType:          strings.ToLower(stringField(doc.Data, "gameType")),
+			IsLandscape:       boolField(doc.Data, "isLandscape"),
+			ThumbnailFileName: stringField(doc.Data, "thumbnailFileName"),
+			PlayCount:         intField(doc.Data, "playCount"),
+			CreatorID:         optionalString(doc.Data, "creatorId"),
+			CreatedAt:         timeField(doc.Data, "createdAt"),
+			UpdatedAt:         time.Now(),
+		},
+		Genre: strings.TrimSpace(stringField(doc.Data, "genre")),
+	}
+	if game.Game.GameType == "" {
+		game.Game.GameType = models.GameTypeHTML5
+	}
+	game.Game.TouchSupport, game.Game.GamepadSupport = models.DefaultInputSupport(game.Game.GameType)
+
+	seen := make(map[string]bool)
+	for _, tag := range stringsField(doc.Data, "tags") {
+		tag = services.NormalizeTagName(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			game.Tags = append(game.Tags, tag)
+		}
+	}
+
+	if game.Game.Title == "" {
+		return game, errors.New("no title")
+	}
+	if len([]rune(game.Game.Title)) > maxTitleLength {
+		return game, fmt.Errorf("title is longer than %d characters", maxTitleLength)
+	}
+	if game.Game.EmbedLink == "" {
+		return game, errors.New("no embed link")
+	}
+	if err := services.ValidateEmbedLink(game.Game.EmbedLink); err != nil {
+		return game, errors.New("embed link is not an https URL")
+	}
+	switch game.Game.GameType {
+	case models.GameTypeHTML5, models.GameTypeUnity, models.GameTypeGodot:
+	default:
+		return game, fmt.Errorf("unknown game type %q", game.Game.GameType)
+	}
+	if game.Genre == "" {
+		return game, errors.New("no genre")
+	}
+	return game, nil
+}
+
+func toLike(doc Document) (models.Like, error) {
+	userId, gameId := stringField(doc.Data, "userId"), stringField(doc.Data, "gameId")
+	if userId == "" || gameId == "" {
+		return models.Like{}, errors.New("no user or game")
+	}
+	return models.Like{
+		ID:        legacyID("likes", doc.ID),
+		UserID:    userId,
+		GameID:    legacyID("games", gameId),
+		CreatedAt: timeField(doc.Data, "createdAt"),
+	}, nil
+}
+
+// toComment converts a comment. ParentID is set for replies, but the
+// comments step leaves it out: replies are linked once every comment exists.
+func toComment(doc Document) (models.Comment, error) {
+	userId, gameId := stringField(doc.Data, "userId"), stringField(doc.Data, "gameId")
+	if userId == "" || gameId == "" {
+		return models.Comment{}, errors.New("no user or game")
+	}
+	comment := models.Comment{
+		ID:        legacyID("comments", doc.ID),
+		Content:   stringField(doc.Data, "content"),
+		CreatedAt: timeField(doc.Data, "createdAt"),
+		UserID:    userId,
+		GameID:    legacyID("games", gameId),
+		IsDeleted: boolField(doc.Data, "isDeleted"),
+	}
+	if parentId := stringField(doc.Data, "parentId"); parentId != "" {
+		parent := legacyID("comments", parentId)
+		comment.ParentID = &parent
+	}
+	return comment, nil
+}
+
+func toFollow(doc Document) (models.Follow, error) {
+	followerId, followingId := stringField(doc.Data, "followerId"), stringField(doc.Data, "followingId")
+	if followerId == "" || followingId == "" {
+		return models.Follow{}, errors.New("no follower or followed user")
+	}
+	if followerId == followingId {
+		return models.Follow{}, errors.New("user follows themselves")
+	}
+	return models.Follow{
+		ID:          legacyID("follows", doc.ID),
+		CreatedAt:   timeField(doc.Data, "createdAt"),
+		FollowerID:  followerId,
+		FollowingID: followingId,
+	}, nil
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	switch value := data[key].(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func optionalString(data map[string]interface{}, key string) *string {
+	value := strings.TrimSpace(stringField(data, key))
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func intField(data map[string]interface{}, key string) int {
+	switch value := data[key].(type) {
+	case int64:
+		return int(value)
+	case int:
+		return value
+	case float64:
+		return int(value)
+	default:
+		return 0
+	}
+}
+
+func boolField(data map[string]interface{}, key string) bool {
+	value, _ := data[key].(bool)
+	return value
+}
+
+// timeField reads a timestamp. Documents without one get the current time,
+// like rows created through the API.
+func timeField(data map[string]interface{}, key string) time.Time {
+	if value, ok := data[key].(time.Time); ok && !value.IsZero() {
+		return value
+	}
+	return time.Now()
+}
+
+func stringsField(data map[string]interface{}, key string) []string {
+	values, _ := data[key].([]interface{})
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+// Package migration copies the legacy Firestore data into Postgres.
+//
+// Every step reads one collection in document ID order, writes a page per
+// transaction and records the last document of the page in its checkpoint.
+// Rows get IDs derived from their Firestore documents, so running the
+// migration again resumes where it stopped and rewrites instead of
+// duplicating. Games are the exception: once written they are left alone,
+// since they may have been claimed or edited in the meantime.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/services"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	defaultPageSize   = 200
+	defaultSampleSize = 25
+	// maxReportedSkips caps how many skipped documents a step lists.
+	maxReportedSkips = 50
+)
+
+type Options struct {
+	PageSize int
+	// Restart discards the checkpoints and migrates every collection from
+	// the start.
+	Restart bool
+	// VerifyOnly skips the migration and only compares the two databases.
+	VerifyOnly bool
+	// SampleSize is how many documents per collection verification compares
+	// field by field.
+	SampleSize int
+}
+
+type migrator struct {
+	db     *gorm.DB
+	source Source
+	opts   Options
+	names  *services.NameResolver
+}
+
+// Run migrates every collection, then verifies the result.
+func Run(ctx context.Context, db *gorm.DB, source Source, opts Options) (*Report, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.SampleSize <= 0 {
+		opts.SampleSize = defaultSampleSize
+	}
+
+	m := &migrator{
+		db:     db.WithContext(ctx),
+		source: source,
+		opts:   opts,
+		names:  services.NewNameResolver(),
+	}
+	report := &Report{}
+
+	if !opts.VerifyOnly {
+		for _, s := range steps {
+			result, err := m.runStep(ctx, s)
+			report.Steps = append(report.Steps, result)
+			if err != nil {
+				return report, fmt.Errorf("step %s: %w", s.name, err)
+			}
+		}
+		if err := recount(m.db); err != nil {
+			return report, err
+		}
+	}
+
+	checks, err := m.verify(ctx)
+	report.Checks = checks
+	return report, err
+}
+
+func (m *migrator) runStep(ctx context.Context, s step) (StepReport, error) {
+	result := StepReport{Step: s.name}
+
+	checkpoint, err := m.checkpoint(s.name)
+	if err != nil {
+		return result, err
+	}
+	result.Migrated, result.Skipped = checkpoint.Migrated, checkpoint.Skipped
+	if checkpoint.CompletedAt != nil {
+		result.AlreadyDone = true
+		return result, nil
+	}
+	result.Resumed = checkpoint.LastDocumentID != ""
+
+	after := checkpoint.LastDocumentID
+	for {
+		docs, err := m.source.Page(ctx, s.collection, after, m.opts.PageSize)
+		if err != nil {
+			return result, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		var migrated int
+		var skips []Skip
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			migrated, skips, err = s.migrate(m, tx, docs)
+			if err != nil {
+				return err
+			}
+			return tx.Model(checkpoint).UpdateColumns(map[string]interface{}{
+				"last_document_id": docs[len(docs)-1].ID,
+				"migrated":         gorm.Expr("migrated + ?", migrated),
+				"skipped":          gorm.Expr("skipped + ?", len(skips)),
+				"updated_at":       time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result.Migrated += migrated
+		result.Skipped += len(skips)
+		for _, skip := range skips {
+			if len(result.Skips) < maxReportedSkips {
+				result.Skips = append(result.Skips, skip)
+			}
+		}
+		log.Info().Str("step", s.name).Int("migrated", result.Migrated).Int("skipped", result.Skipped).Msg("Migrated page")
+
+		after = docs[len(docs)-1].ID
+		if len(docs) < m.opts.PageSize {
+			break
+		}
+	}
+
+	if err := m.db.Model(checkpoint).Update("completed_at", time.Now()).Error; err != nil {
+		return result, fmt.Errorf("failed to complete checkpoint: %w", err)
+	}
+	return result, nil
+}
+
+// checkpoint loads the step's checkpoint, creating or resetting it as needed.
+func (m *migrator) checkpoint(step string) (*models.MigrationCheckpoint, error) {
+	var checkpoint models.MigrationCheckpoint
+	err := m.db.First(&checkpoint, "step = ?", step).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		checkpoint = models.MigrationCheckpoint{Step: step}
+		if err := m.db.Create(&checkpoint).Error; err != nil {
+			return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+		}
+		return &checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	if m.opts.Restart {
+		checkpoint = models.MigrationCheckpoint{Step: step}
+		if err := m.db.Save(&checkpoint).Error; err != nil {
+			return nil, fmt.Errorf("failed to reset checkpoint: %w", err)
+		}
+	}
+	return &checkpoint, nil
+}
+
+// recount rebuilds the counters the migration can't carry over one row at a
+// time: like and comment counts of migrated games and everyone's follow
+// counts.
+func recount(db *gorm.DB) error {
+	if err := db.Exec(`
+		UPDATE games g SET
+			like_count = (SELECT COUNT(*) FROM likes l WHERE l.game_id = g.id),
+			comment_count = (SELECT COUNT(*) FROM comments c WHERE c.game_id = g.id AND c.is_deleted = false)
+		WHERE g.external_id LIKE ?
+	`, legacyExternalIDPrefix+"%").Error; err != nil {
+		return fmt.Errorf("failed to recount game interactions: %w", err)
+	}
+
+	if err := db.Exec(`
+		UPDATE users u SET
+			followers_count = (SELECT COUNT(*) FROM follows f WHERE f.following_id = u.uid),
+			following_count = (SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.uid)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to recount follows: %w", err)
+	}
+	return nil
+}
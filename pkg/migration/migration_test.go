@@ -0,0 +1,370 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"sort"
+	"testing"
+)
+
+// memorySource is a Source over documents held in memory. failPage makes the
+// given page read of a collection fail, counting from 1, to interrupt a run.
+type memorySource struct {
+	collections map[string][]Document
+	failPage    map[string]int
+	reads       map[string]int
+}
+
+var errSourceDown = errors.New("source unavailable")
+
+func newMemorySource(collections map[string][]Document) *memorySource {
+	for _, docs := range collections {
+		sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	}
+	return &memorySource{
+		collections: collections,
+		failPage:    make(map[string]int),
+		reads:       make(map[string]int),
+	}
+}
+
+func (ms *memorySource) Page(ctx context.Context, collection, after string, limit int) ([]Document, error) {
+	ms.reads[collection]++
+	if ms.reads[collection] == ms.failPage[collection] {
+		return nil, errSourceDown
+	}
+
+	docs := ms.collections[collection]
+	start := sort.Search(len(docs), func(i int) bool { return docs[i].ID > after })
+	end := start + limit
+	if end > len(docs) {
+		end = len(docs)
+	}
+	return docs[start:end], nil
+}
+
+func (ms *memorySource) Count(ctx context.Context, collection string) (int64, error) {
+	return int64(len(ms.collections[collection])), nil
+}
+
+// testDB connects to the Postgres database in TEST_DATABASE_URL, skipping the
+// test when there is none. Each test gets its own transaction, rolled back
+// when the test ends.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Genre{},
+		&models.Tag{},
+		&models.Game{},
+		&models.Like{},
+		&models.Comment{},
+		&models.Follow{},
+		&models.SearchOutbox{},
+		&models.MigrationCheckpoint{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func doc(id string, data map[string]interface{}) Document {
+	return Document{ID: id, Data: data}
+}
+
+// legacyData is a small Firestore export with one of every kind of document
+// the migration has to skip.
+func legacyData() map[string][]Document {
+	return map[string][]Document{
+		"users": {
+			doc("u1", map[string]interface{}{"email": "one@example.com", "username": "one"}),
+			doc("u2", map[string]interface{}{"email": "two@example.com", "username": "two"}),
+			doc("u3", map[string]interface{}{"username": "noemail"}),
+		},
+		"games": {
+			doc("g1", map[string]interface{}{
+				"title": "First", "embedLink": "https://example.com/1", "genre": "Puzzle",
+				"tags": []interface{}{"Pixel  Art", "pixel art"}, "creatorId": "u1",
+			}),
+			doc("g2", map[string]interface{}{
+				"title": "Second", "embedLink": "https://example.com/2", "genre": "puzzle", "creatorId": "gone",
+			}),
+			doc("g3", map[string]interface{}{"embedLink": "https://example.com/3", "genre": "Puzzle"}),
+		},
+		"likes": {
+			doc("l1", map[string]interface{}{"userId": "u1", "gameId": "g1"}),
+			doc("l2", map[string]interface{}{"userId": "u1", "gameId": "g1"}),
+			doc("l3", map[string]interface{}{"userId": "gone", "gameId": "g1"}),
+			doc("l4", map[string]interface{}{"userId": "u2", "gameId": "g3"}),
+		},
+		"comments": {
+			doc("c1", map[string]interface{}{"userId": "u2", "gameId": "g1", "content": "Great"}),
+			doc("c0", map[string]interface{}{"userId": "u1", "gameId": "g1", "content": "Thanks", "parentId": "c1"}),
+			doc("c2", map[string]interface{}{"userId": "u1", "gameId": "g1", "content": "Orphan", "parentId": "missing"}),
+		},
+		"follows": {
+			doc("f1", map[string]interface{}{"followerId": "u2", "followingId": "u1"}),
+			doc("f2", map[string]interface{}{"followerId": "u2", "followingId": "gone"}),
+		},
+	}
+}
+
+func stepReport(t *testing.T, report *Report, name string) StepReport {
+	t.Helper()
+	for _, s := range report.Steps {
+		if s.Step == name {
+			return s
+		}
+	}
+	t.Fatalf("no report for step %s", name)
+	return StepReport{}
+}
+
+func collectionCheck(t *testing.T, report *Report, collection string) CollectionCheck {
+	t.Helper()
+	for _, c := range report.Checks {
+		if c.Collection == collection {
+			return c
+		}
+	}
+	t.Fatalf("no check for collection %s", collection)
+	return CollectionCheck{}
+}
+
+func TestRunSkipsMissingReferences(t *testing.T) {
+	db := testDB(t)
+
+	report, err := Run(context.Background(), db, newMemorySource(legacyData()), Options{PageSize: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("verification failed: %+v", report.Checks)
+	}
+
+	want := map[string][2]int{
+		"users":           {2, 1},
+		"games":           {2, 1},
+		"likes":           {1, 3},
+		"comments":        {3, 0},
+		"comment-replies": {1, 1},
+		"follows":         {1, 1},
+	}
+	for name, counts := range want {
+		s := stepReport(t, report, name)
+		if s.Migrated != counts[0] || s.Skipped != counts[1] {
+			t.Errorf("%s: %d migrated and %d skipped, want %d and %d", name, s.Migrated, s.Skipped, counts[0], counts[1])
+		}
+	}
+
+	var orphaned models.Game
+	if err := db.First(&orphaned, "id = ?", legacyID("games", "g2")).Error; err != nil {
+		t.Fatalf("failed to get game: %v", err)
+	}
+	if orphaned.CreatorID != nil || orphaned.IsClaimed {
+		t.Errorf("game by a user who wasn't migrated is claimed by %v", orphaned.CreatorID)
+	}
+
+	var first models.Game
+	if err := db.Preload("Tags").First(&first, "id = ?", legacyID("games", "g1")).Error; err != nil {
+		t.Fatalf("failed to get game: %v", err)
+	}
+	if len(first.Tags) != 1 || first.Tags[0].Name != "pixel art" {
+		t.Errorf("game has tags %+v, want only pixel art", first.Tags)
+	}
+	if first.LikeCount != 1 || first.CommentCount != 3 {
+		t.Errorf("game has %d likes and %d comments, want 1 and 3", first.LikeCount, first.CommentCount)
+	}
+}
+
+func TestRunResumesAfterFailure(t *testing.T) {
+	db := testDB(t)
+	source := newMemorySource(legacyData())
+	source.failPage["games"] = 2
+
+	report, err := Run(context.Background(), db, source, Options{PageSize: 2})
+	if !errors.Is(err, errSourceDown) {
+		t.Fatalf("Run returned %v, want the source error", err)
+	}
+	if s := stepReport(t, report, "games"); s.Migrated != 2 || s.Skipped != 0 {
+		t.Fatalf("interrupted games step: %d migrated and %d skipped, want 2 and 0", s.Migrated, s.Skipped)
+	}
+
+	report, err = Run(context.Background(), db, source, Options{PageSize: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if s := stepReport(t, report, "users"); !s.AlreadyDone {
+		t.Errorf("users step ran again after it finished")
+	}
+	games := stepReport(t, report, "games")
+	if !games.Resumed || games.Migrated != 2 || games.Skipped != 1 {
+		t.Errorf("resumed games step: resumed %v, %d migrated and %d skipped, want true, 2 and 1",
+			games.Resumed, games.Migrated, games.Skipped)
+	}
+	if !report.OK() {
+		t.Errorf("verification failed: %+v", report.Checks)
+	}
+}
+
+func TestRunIsIdempotent(t *testing.T) {
+	db := testDB(t)
+	source := newMemorySource(legacyData())
+
+	if _, err := Run(context.Background(), db, source, Options{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// A game claimed and renamed after the first run keeps its changes.
+	gameId := legacyID("games", "g2")
+	if err := db.Model(&models.Game{}).Where("id = ?", gameId).Updates(map[string]interface{}{
+		"title": "Renamed", "creator_id": "u2", "is_claimed": true,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() map[string]int64 {
+		result := make(map[string]int64)
+		for name, model := range map[string]interface{}{
+			"users": &models.User{}, "games": &models.Game{}, "likes": &models.Like{},
+			"comments": &models.Comment{}, "follows": &models.Follow{}, "tags": &models.Tag{},
+		} {
+			var count int64
+			if err := db.Model(model).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			result[name] = count
+		}
+		return result
+	}
+	before := counts()
+
+	report, err := Run(context.Background(), db, source, Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, s := range report.Steps {
+		if !s.AlreadyDone {
+			t.Errorf("step %s ran again after it finished", s.Step)
+		}
+	}
+
+	report, err = Run(context.Background(), db, source, Options{Restart: true})
+	if err != nil {
+		t.Fatalf("Run with Restart: %v", err)
+	}
+	for _, c := range report.Checks {
+		if !c.CountsMatch() {
+			t.Errorf("%s: counts don't match: %+v", c.Collection, c)
+		}
+	}
+	if games := collectionCheck(t, report, "games"); len(games.Mismatches) != 1 {
+		t.Errorf("games check found %v, want only the renamed title", games.Mismatches)
+	}
+	if after := counts(); len(after) != len(before) {
+		t.Fatalf("counted %v, want %v", after, before)
+	} else {
+		for name, count := range before {
+			if after[name] != count {
+				t.Errorf("%s: %d rows after the rerun, want %d", name, after[name], count)
+			}
+		}
+	}
+
+	var game models.Game
+	if err := db.First(&game, "id = ?", gameId).Error; err != nil {
+		t.Fatal(err)
+	}
+	if game.Title != "Renamed" || game.CreatorID == nil || *game.CreatorID != "u2" || !game.IsClaimed {
+		t.Errorf("rerun overwrote the claimed game: %+v", game)
+	}
+}
+
+func TestVerifyFindsMissingRows(t *testing.T) {
+	db := testDB(t)
+	source := newMemorySource(legacyData())
+
+	if _, err := Run(context.Background(), db, source, Options{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// Rows made through the API don't count towards the collection.
+	if err := db.Create(&models.Like{UserID: "u2", GameID: legacyID("games", "g1")}).Error; err != nil {
+		t.Fatal(err)
+	}
+	report, err := Run(context.Background(), db, source, Options{VerifyOnly: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("verification failed: %+v", report.Checks)
+	}
+
+	if err := db.Delete(&models.Follow{}, "id = ?", legacyID("follows", "f1")).Error; err != nil {
+		t.Fatal(err)
+	}
+	report, err = Run(context.Background(), db, source, Options{VerifyOnly: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.OK() {
+		t.Errorf("verification passed without a migrated follow")
+	}
+	follows := collectionCheck(t, report, "follows")
+	if follows.CountsMatch() || follows.PostgresCount != 0 || follows.Skipped != 1 {
+		t.Errorf("follows check: %+v", follows)
+	}
+	if likes := collectionCheck(t, report, "likes"); !likes.CountsMatch() || likes.PostgresCount != 1 {
+		t.Errorf("likes check: %+v", likes)
+	}
+}
+
+func TestToGameRejectsWhatTheAPIWould(t *testing.T) {
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"title": "Game", "embedLink": "https://example.com/", "genre": "Puzzle", "gameType": "Unity"}
+	}
+	if _, err := toGame(doc("ok", valid())); err != nil {
+		t.Fatalf("valid game rejected: %v", err)
+	}
+
+	for name, change := range map[string]func(map[string]interface{}){
+		"javascript link": func(data map[string]interface{}) { data["embedLink"] = "javascript:alert(1)" },
+		"http link":       func(data map[string]interface{}) { data["embedLink"] = "http://example.com/" },
+		"unknown type":    func(data map[string]interface{}) { data["gameType"] = "flash" },
+		"long title": func(data map[string]interface{}) {
+			title := make([]rune, maxTitleLength+1)
+			for i := range title {
+				title[i] = 'é'
+			}
+			data["title"] = string(title)
+		},
+	} {
+		data := valid()
+		change(data)
+		if _, err := toGame(doc(name, data)); err == nil {
+			t.Errorf("%s: game was accepted", name)
+		}
+	}
+}
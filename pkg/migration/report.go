@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"fmt"
+	"io"
+)
+
+// Report describes a migration run and its verification.
+type Report struct {
+	Steps  []StepReport
+	Checks []CollectionCheck
+}
+
+type StepReport struct {
+	Step string
+	// Migrated and Skipped count documents across every run of the step.
+	Migrated int
+	Skipped  int
+	// Resumed is set when the step continued from an earlier run, and
+	// AlreadyDone when an earlier run finished it.
+	Resumed     bool
+	AlreadyDone bool
+	// Skips lists the documents skipped in this run, up to maxReportedSkips.
+	Skips []Skip
+}
+
+type Skip struct {
+	DocumentID string
+	Reason     string
+}
+
+type CollectionCheck struct {
+	Collection     string
+	FirestoreCount int64
+	Migrated       int
+	Skipped        int
+	// PostgresCount is how many of the collection's documents have their
+	// row in Postgres.
+	PostgresCount  int64
+	Sampled        int
+	SampledSkipped int
+	Mismatches     []string
+}
+
+// CountsMatch reports whether every document either made it to Postgres or
+// was skipped.
+func (c CollectionCheck) CountsMatch() bool {
+	return c.FirestoreCount == c.PostgresCount+int64(c.Skipped)
+}
+
+// OK reports whether verification found nothing wrong.
+func (r *Report) OK() bool {
+	for _, c := range r.Checks {
+		if !c.CountsMatch() || len(c.Mismatches) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) Print(w io.Writer) {
+	for _, s := range r.Steps {
+		state := ""
+		switch {
+		case s.AlreadyDone:
+			state = " (done in an earlier run)"
+		case s.Resumed:
+			state = " (resumed)"
+		}
+		fmt.Fprintf(w, "%s: %d migrated, %d skipped%s\n", s.Step, s.Migrated, s.Skipped, state)
+		for _, skip := range s.Skips {
+			fmt.Fprintf(w, "    skipped %s: %s\n", skip.DocumentID, skip.Reason)
+		}
+		if listed := len(s.Skips); listed > 0 && listed == maxReportedSkips {
+			fmt.Fprintf(w, "    only the first %d skips are listed\n", maxReportedSkips)
+		}
+	}
+
+	if len(r.Checks) > 0 {
+		fmt.Fprintln(w, "Verification:")
+	}
+	for _, c := range r.Checks {
+		status := "ok"
+		if !c.CountsMatch() || len(c.Mismatches) > 0 {
+			status = "MISMATCH"
+		}
+		fmt.Fprintf(w, "  %s: %s, %d in Firestore, %d migrated, %d skipped, %d in Postgres, %d sampled (%d skipped on purpose)\n",
+			c.Collection, status, c.FirestoreCount, c.Migrated, c.Skipped, c.PostgresCount, c.Sampled, c.SampledSkipped)
+		for _, mismatch := range c.Mismatches {
+			fmt.Fprintf(w, "    %s\n", mismatch)
+		}
+	}
+}
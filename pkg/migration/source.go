@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"context"
+	"fmt"
+)
+
+// Document is a legacy document: its ID and its fields as Firestore decodes
+// them (string, int64, float64, bool, time.Time, []interface{}, ...).
+type Document struct {
+	ID   string
+	Data map[string]interface{}
+}
+
+// Source reads the legacy collections. FirestoreSource reads the real
+// database; anything else, like an in-memory fake, works as long as pages
+// come back in document ID order.
+type Source interface {
+	// Page returns up to limit documents of a collection whose IDs sort after
+	// the given one. An empty after starts at the first document.
+	Page(ctx context.Context, collection, after string, limit int) ([]Document, error)
+	// Count returns how many documents a collection has.
+	Count(ctx context.Context, collection string) (int64, error)
+}
+
+type FirestoreSource struct {
+	client *firestore.Client
+}
+
+func NewFirestoreSource(client *firestore.Client) *FirestoreSource {
+	return &FirestoreSource{client: client}
+}
+
+func (fs *FirestoreSource) Page(ctx context.Context, collection, after string, limit int) ([]Document, error) {
+	query := fs.client.Collection(collection).OrderBy(firestore.DocumentID, firestore.Asc).Limit(limit)
+	if after != "" {
+		query = query.StartAfter(after)
+	}
+
+	snapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", collection, err)
+	}
+
+	documents := make([]Document, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		documents = append(documents, Document{ID: snapshot.Ref.ID, Data: snapshot.Data()})
+	}
+	return documents, nil
+}
+
+func (fs *FirestoreSource) Count(ctx context.Context, collection string) (int64, error) {
+	result, err := fs.client.Collection(collection).NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", collection, err)
+	}
+
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("failed to count %s: unexpected result %T", collection, result["count"])
+	}
+	return count.GetIntegerValue(), nil
+}
@@ -0,0 +1,409 @@
+package migration
+
+import (
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// step migrates one page of a legacy collection inside a transaction and
+// returns how many documents it wrote and which it skipped.
+type step struct {
+	name       string
+	collection string
+	migrate    func(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error)
+}
+
+// steps run in order: rows are only written once everything they reference
+// exists.
+var steps = []step{
+	{name: "users", collection: "users", migrate: migrateUsers},
+	{name: "games", collection: "games", migrate: migrateGames},
+	{name: "likes", collection: "likes", migrate: migrateLikes},
+	{name: "comments", collection: "comments", migrate: migrateComments},
+	{name: "comment-replies", collection: "comments", migrate: linkCommentReplies},
+	{name: "follows", collection: "follows", migrate: migrateFollows},
+}
+
+// insertOnly writes rows without their associations or the omitted fields.
+// Hooks are skipped so the BeforeCreate timestamps don't replace the legacy
+// ones.
+func insertOnly(tx *gorm.DB, omit ...string) *gorm.DB {
+	return tx.Session(&gorm.Session{SkipHooks: true}).Omit(append(omit, clause.Associations)...)
+}
+
+func migrateUsers(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var users []models.User
+	var uids, emails, usernames []string
+	for _, doc := range docs {
+		user, err := toUser(doc)
+		if err != nil {
+			skips = append(skips, Skip{DocumentID: doc.ID, Reason: err.Error()})
+			continue
+		}
+		users = append(users, user)
+		uids = append(uids, user.UID)
+		emails = append(emails, user.Email)
+		usernames = append(usernames, user.Username)
+	}
+	if len(users) == 0 {
+		return 0, skips, nil
+	}
+
+	// Users who signed up after the cutover may already hold an email or
+	// username; their accounts win.
+	var others []models.User
+	if err := tx.Select("email", "username").
+		Where("(email IN ? OR username IN ?) AND uid NOT IN ?", emails, usernames, uids).
+		Find(&others).Error; err != nil {
+		return 0, nil, err
+	}
+	taken := make(map[string]bool)
+	for _, other := range others {
+		taken["email:"+other.Email] = true
+		taken["username:"+other.Username] = true
+	}
+
+	valid := users[:0]
+	for _, user := range users {
+		emailKey, usernameKey := "email:"+user.Email, "username:"+user.Username
+		if taken[emailKey] || taken[usernameKey] {
+			skips = append(skips, Skip{DocumentID: user.UID, Reason: "email or username belongs to another user"})
+			continue
+		}
+		taken[emailKey], taken[usernameKey] = true, true
+		valid = append(valid, user)
+	}
+	if len(valid) == 0 {
+		return 0, skips, nil
+	}
+
+	err := insertOnly(tx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "username", "display_name", "profile_image_url", "bio", "updated_at"}),
+	}).Create(&valid).Error
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to write users: %w", err)
+	}
+	return len(valid), skips, nil
+}
+
+func migrateGames(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var games []legacyGame
+	var creatorIds []string
+	for _, doc := range docs {
+		game, err := toGame(doc)
+		if err != nil {
+			skips = append(skips, Skip{DocumentID: doc.ID, Reason: err.Error()})
+			continue
+		}
+		games = append(games, game)
+		if game.Game.CreatorID != nil {
+			creatorIds = append(creatorIds, *game.Game.CreatorID)
+		}
+	}
+	if len(games) == 0 {
+		return 0, skips, nil
+	}
+
+	creators, err := existing(tx, &models.User{}, "uid", creatorIds)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Games written by an earlier run may have been claimed or edited since,
+	// so they are left as they are.
+	ids := make([]string, 0, len(games))
+	for _, game := range games {
+		ids = append(ids, game.Game.ID)
+	}
+	written, err := existing(tx, &models.Game{}, "id", ids)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var rows []models.Game
+	var tagNames [][]string
+	for i := range games {
+		game := &games[i].Game
+		if written[game.ID] {
+			continue
+		}
+		if game.GenreID, _, err = m.names.Genre(tx, games[i].Genre); err != nil {
+			return 0, nil, err
+		}
+		// Games by creators who never made it to Postgres stay unclaimed
+		// so they can be claimed again.
+		if game.CreatorID != nil && !creators[*game.CreatorID] {
+			game.CreatorID = nil
+		}
+		game.IsClaimed = game.CreatorID != nil
+		rows = append(rows, *game)
+		tagNames = append(tagNames, games[i].Tags)
+	}
+	if len(rows) == 0 {
+		return len(games), skips, nil
+	}
+
+	if err := insertOnly(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to write games: %w", err)
+	}
+
+	created := make([]string, 0, len(rows))
+	for i, game := range rows {
+		tags, _, err := m.names.Tags(tx, tagNames[i])
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := tx.Model(&game).Association("Tags").Replace(tags); err != nil {
+			return 0, nil, fmt.Errorf("failed to write tags of game %s: %w", game.ID, err)
+		}
+		created = append(created, game.ID)
+	}
+	if err := gamesearch.Enqueue(tx, created...); err != nil {
+		return 0, nil, err
+	}
+	return len(games), skips, nil
+}
+
+func migrateLikes(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var likes []models.Like
+	var docIds, userIds, gameIds []string
+	for _, doc := range docs {
+		like, err := toLike(doc)
+		if err != nil {
+			skips = append(skips, Skip{DocumentID: doc.ID, Reason: err.Error()})
+			continue
+		}
+		likes = append(likes, like)
+		docIds = append(docIds, doc.ID)
+		userIds = append(userIds, like.UserID)
+		gameIds = append(gameIds, like.GameID)
+	}
+
+	users, err := existing(tx, &models.User{}, "uid", userIds)
+	if err != nil {
+		return 0, nil, err
+	}
+	games, err := existing(tx, &models.Game{}, "id", gameIds)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	valid := likes[:0]
+	var validDocIds []string
+	for i, like := range likes {
+		if reason := missingReference(users, like.UserID, games, like.GameID); reason != "" {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: reason})
+			continue
+		}
+		valid = append(valid, like)
+		validDocIds = append(validDocIds, docIds[i])
+	}
+	if len(valid) == 0 {
+		return 0, skips, nil
+	}
+
+	// A user liking a game twice in Firestore is a duplicate, not an error.
+	// Only the first like is written; the others are skipped.
+	if err := insertOnly(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(&valid).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to write likes: %w", err)
+	}
+	ids := make([]string, 0, len(valid))
+	for _, like := range valid {
+		ids = append(ids, like.ID)
+	}
+	written, err := existing(tx, &models.Like{}, "id", ids)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i, like := range valid {
+		if !written[like.ID] {
+			skips = append(skips, Skip{DocumentID: validDocIds[i], Reason: "duplicate like"})
+		}
+	}
+	return len(written), skips, nil
+}
+
+func migrateComments(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var comments []models.Comment
+	var docIds, userIds, gameIds []string
+	for _, doc := range docs {
+		comment, err := toComment(doc)
+		if err != nil {
+			skips = append(skips, Skip{DocumentID: doc.ID, Reason: err.Error()})
+			continue
+		}
+		comment.ParentID = nil
+		comments = append(comments, comment)
+		docIds = append(docIds, doc.ID)
+		userIds = append(userIds, comment.UserID)
+		gameIds = append(gameIds, comment.GameID)
+	}
+
+	users, err := existing(tx, &models.User{}, "uid", userIds)
+	if err != nil {
+		return 0, nil, err
+	}
+	games, err := existing(tx, &models.Game{}, "id", gameIds)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	valid := comments[:0]
+	for i, comment := range comments {
+		if reason := missingReference(users, comment.UserID, games, comment.GameID); reason != "" {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: reason})
+			continue
+		}
+		valid = append(valid, comment)
+	}
+	if len(valid) == 0 {
+		return 0, skips, nil
+	}
+
+	err = insertOnly(tx, "ParentID").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "is_deleted"}),
+	}).Create(&valid).Error
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to write comments: %w", err)
+	}
+	return len(valid), skips, nil
+}
+
+// linkCommentReplies points replies at their parent. It runs as its own pass
+// over the comments because a reply can sort before its parent.
+func linkCommentReplies(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var replies []models.Comment
+	var docIds, ids []string
+	for _, doc := range docs {
+		comment, err := toComment(doc)
+		if err != nil || comment.ParentID == nil {
+			continue
+		}
+		replies = append(replies, comment)
+		docIds = append(docIds, doc.ID)
+		ids = append(ids, comment.ID, *comment.ParentID)
+	}
+	if len(replies) == 0 {
+		return 0, nil, nil
+	}
+
+	comments, err := existing(tx, &models.Comment{}, "id", ids)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	linked := 0
+	for i, reply := range replies {
+		if !comments[reply.ID] {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: "reply was not migrated"})
+			continue
+		}
+		if !comments[*reply.ParentID] {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: "parent comment was not migrated"})
+			continue
+		}
+		if err := tx.Model(&models.Comment{}).Where("id = ?", reply.ID).UpdateColumn("parent_id", *reply.ParentID).Error; err != nil {
+			return 0, nil, fmt.Errorf("failed to link reply %s: %w", docIds[i], err)
+		}
+		linked++
+	}
+	return linked, skips, nil
+}
+
+func migrateFollows(m *migrator, tx *gorm.DB, docs []Document) (int, []Skip, error) {
+	var skips []Skip
+	var follows []models.Follow
+	var docIds, userIds []string
+	for _, doc := range docs {
+		follow, err := toFollow(doc)
+		if err != nil {
+			skips = append(skips, Skip{DocumentID: doc.ID, Reason: err.Error()})
+			continue
+		}
+		follows = append(follows, follow)
+		docIds = append(docIds, doc.ID)
+		userIds = append(userIds, follow.FollowerID, follow.FollowingID)
+	}
+
+	users, err := existing(tx, &models.User{}, "uid", userIds)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// follows has no unique pair index, so pairs that already exist under
+	// another ID are skipped here instead of being left to a conflict.
+	var current []models.Follow
+	if len(follows) > 0 {
+		followerIds := make([]string, 0, len(follows))
+		for _, follow := range follows {
+			followerIds = append(followerIds, follow.FollowerID)
+		}
+		if err := tx.Select("id", "follower_id", "following_id").Where("follower_id IN ?", followerIds).Find(&current).Error; err != nil {
+			return 0, nil, err
+		}
+	}
+	pairs := make(map[string]string, len(current))
+	for _, follow := range current {
+		pairs[follow.FollowerID+"/"+follow.FollowingID] = follow.ID
+	}
+
+	valid := follows[:0]
+	for i, follow := range follows {
+		if !users[follow.FollowerID] || !users[follow.FollowingID] {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: "user was not migrated"})
+			continue
+		}
+		pair := follow.FollowerID + "/" + follow.FollowingID
+		if id, ok := pairs[pair]; ok && id != follow.ID {
+			skips = append(skips, Skip{DocumentID: docIds[i], Reason: "duplicate follow"})
+			continue
+		}
+		pairs[pair] = follow.ID
+		valid = append(valid, follow)
+	}
+	if len(valid) == 0 {
+		return 0, skips, nil
+	}
+
+	if err := insertOnly(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(&valid).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to write follows: %w", err)
+	}
+	return len(valid), skips, nil
+}
+
+// existing returns which of the IDs have a row in the model's table.
+func existing(tx *gorm.DB, model interface{}, column string, ids []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	var rows []string
+	if err := tx.Model(model).Where(column+" IN ?", ids).Pluck(column, &rows).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range rows {
+		found[id] = true
+	}
+	return found, nil
+}
+
+func missingReference(users map[string]bool, userId string, games map[string]bool, gameId string) string {
+	if !users[userId] {
+		return "user was not migrated"
+	}
+	if !games[gameId] {
+		return "game was not migrated"
+	}
+	return ""
+}
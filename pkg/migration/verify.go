@@ -0,0 +1,381 @@
+package migration
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"math/big"
+	"strings"
+)
+
+// check verifies one collection. A document's row is the one in model whose
+// column holds rowID(doc); rowID returns "" for documents the migration
+// can't convert. compare lists how a document's row differs from it and
+// returns errSkipped for documents the migration can't convert. skipped
+// reports whether a document without a row was left out on purpose, such as
+// a like of a game that wasn't migrated.
+type check struct {
+	collection string
+	step       string
+	model      interface{}
+	column     string
+	rowID      func(doc Document) string
+	compare    func(db *gorm.DB, doc Document) ([]string, error)
+	skipped    func(db *gorm.DB, doc Document) (bool, error)
+}
+
+var errSkipped = errors.New("skipped")
+
+var checks = []check{
+	{
+		collection: "users",
+		step:       "users",
+		model:      &models.User{},
+		column:     "uid",
+		rowID: func(doc Document) string {
+			if _, err := toUser(doc); err != nil {
+				return ""
+			}
+			return doc.ID
+		},
+		compare: func(db *gorm.DB, doc Document) ([]string, error) {
+			want, err := toUser(doc)
+			if err != nil {
+				return nil, errSkipped
+			}
+			var got models.User
+			if err := db.First(&got, "uid = ?", want.UID).Error; err != nil {
+				return nil, err
+			}
+			var diffs []string
+			diffs = differs(diffs, "email", want.Email, got.Email)
+			diffs = differs(diffs, "username", want.Username, got.Username)
+			return diffs, nil
+		},
+		skipped: func(db *gorm.DB, doc Document) (bool, error) {
+			want, _ := toUser(doc)
+			return rowExists(db, &models.User{}, "(email = ? OR username = ?) AND uid <> ?", want.Email, want.Username, want.UID)
+		},
+	},
+	{
+		collection: "games",
+		step:       "games",
+		model:      &models.Game{},
+		column:     "id",
+		rowID: func(doc Document) string {
+			game, err := toGame(doc)
+			if err != nil {
+				return ""
+			}
+			return game.Game.ID
+		},
+		compare: func(db *gorm.DB, doc Document) ([]string, error) {
+			want, err := toGame(doc)
+			if err != nil {
+				return nil, errSkipped
+			}
+			var got models.Game
+			if err := db.Preload("Genre").Preload("Tags").First(&got, "id = ?", want.Game.ID).Error; err != nil {
+				return nil, err
+			}
+			var diffs []string
+			diffs = differs(diffs, "title", want.Game.Title, got.Title)
+			diffs = differs(diffs, "description", want.Game.Description, got.Description)
+			diffs = differs(diffs, "embedLink", want.Game.EmbedLink, got.EmbedLink)
+			diffs = differs(diffs, "gameType", want.Game.GameType, got.GameType)
+			diffs = differs(diffs, "genre", strings.ToLower(want.Genre), strings.ToLower(got.Genre.Name))
+			diffs = differs(diffs, "tags", len(want.Tags), len(got.Tags))
+			return diffs, nil
+		},
+	},
+	{
+		collection: "likes",
+		step:       "likes",
+		model:      &models.Like{},
+		column:     "id",
+		rowID: func(doc Document) string {
+			like, err := toLike(doc)
+			if err != nil {
+				return ""
+			}
+			return like.ID
+		},
+		compare: func(db *gorm.DB, doc Document) ([]string, error) {
+			want, err := toLike(doc)
+			if err != nil {
+				return nil, errSkipped
+			}
+			var got models.Like
+			if err := db.First(&got, "id = ?", want.ID).Error; err != nil {
+				return nil, err
+			}
+			var diffs []string
+			diffs = differs(diffs, "userId", want.UserID, got.UserID)
+			diffs = differs(diffs, "gameId", want.GameID, got.GameID)
+			return diffs, nil
+		},
+		skipped: func(db *gorm.DB, doc Document) (bool, error) {
+			want, _ := toLike(doc)
+			if missing, err := referencesMissing(db, []string{want.UserID}, want.GameID); missing || err != nil {
+				return missing, err
+			}
+			return rowExists(db, &models.Like{}, "user_id = ? AND game_id = ?", want.UserID, want.GameID)
+		},
+	},
+	{
+		collection: "comments",
+		step:       "comments",
+		model:      &models.Comment{},
+		column:     "id",
+		rowID: func(doc Document) string {
+			comment, err := toComment(doc)
+			if err != nil {
+				return ""
+			}
+			return comment.ID
+		},
+		compare: func(db *gorm.DB, doc Document) ([]string, error) {
+			want, err := toComment(doc)
+			if err != nil {
+				return nil, errSkipped
+			}
+			var got models.Comment
+			if err := db.First(&got, "id = ?", want.ID).Error; err != nil {
+				return nil, err
+			}
+			var diffs []string
+			diffs = differs(diffs, "content", want.Content, got.Content)
+			diffs = differs(diffs, "userId", want.UserID, got.UserID)
+			diffs = differs(diffs, "gameId", want.GameID, got.GameID)
+			diffs = differs(diffs, "isDeleted", want.IsDeleted, got.IsDeleted)
+
+			// Replies to comments that weren't migrated stay unlinked.
+			wantParent := stringOrEmpty(want.ParentID)
+			if wantParent != "" {
+				found, err := rowExists(db, &models.Comment{}, "id = ?", wantParent)
+				if err != nil {
+					return nil, err
+				}
+				if !found {
+					wantParent = ""
+				}
+			}
+			diffs = differs(diffs, "parentId", wantParent, stringOrEmpty(got.ParentID))
+			return diffs, nil
+		},
+		skipped: func(db *gorm.DB, doc Document) (bool, error) {
+			want, _ := toComment(doc)
+			return referencesMissing(db, []string{want.UserID}, want.GameID)
+		},
+	},
+	{
+		collection: "follows",
+		step:       "follows",
+		model:      &models.Follow{},
+		column:     "id",
+		rowID: func(doc Document) string {
+			follow, err := toFollow(doc)
+			if err != nil {
+				return ""
+			}
+			return follow.ID
+		},
+		compare: func(db *gorm.DB, doc Document) ([]string, error) {
+			want, err := toFollow(doc)
+			if err != nil {
+				return nil, errSkipped
+			}
+			var got models.Follow
+			if err := db.First(&got, "id = ?", want.ID).Error; err != nil {
+				return nil, err
+			}
+			var diffs []string
+			diffs = differs(diffs, "followerId", want.FollowerID, got.FollowerID)
+			diffs = differs(diffs, "followingId", want.FollowingID, got.FollowingID)
+			return diffs, nil
+		},
+		skipped: func(db *gorm.DB, doc Document) (bool, error) {
+			want, _ := toFollow(doc)
+			if missing, err := referencesMissing(db, []string{want.FollowerID, want.FollowingID}, ""); missing || err != nil {
+				return missing, err
+			}
+			return rowExists(db, &models.Follow{}, "follower_id = ? AND following_id = ?", want.FollowerID, want.FollowingID)
+		},
+	},
+}
+
+// verify checks that every document of every collection either has its row
+// in Postgres or was skipped, and compares a random sample of documents with
+// their rows.
+func (m *migrator) verify(ctx context.Context) ([]CollectionCheck, error) {
+	results := make([]CollectionCheck, 0, len(checks))
+	for _, c := range checks {
+		result := CollectionCheck{Collection: c.collection}
+
+		var err error
+		if result.FirestoreCount, err = m.source.Count(ctx, c.collection); err != nil {
+			return results, err
+		}
+		if result.PostgresCount, err = m.countMigrated(ctx, c); err != nil {
+			return results, err
+		}
+
+		var checkpoint models.MigrationCheckpoint
+		err = m.db.First(&checkpoint, "step = ?", c.step).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return results, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		result.Migrated, result.Skipped = checkpoint.Migrated, checkpoint.Skipped
+
+		sample, err := m.sample(ctx, c.collection)
+		if err != nil {
+			return results, err
+		}
+		for _, doc := range sample {
+			result.Sampled++
+			diffs, err := c.compare(m.db, doc)
+			switch {
+			case errors.Is(err, errSkipped):
+				result.SampledSkipped++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				skipped := false
+				if c.skipped != nil {
+					if skipped, err = c.skipped(m.db, doc); err != nil {
+						return results, fmt.Errorf("failed to check %s/%s: %w", c.collection, doc.ID, err)
+					}
+				}
+				if skipped {
+					result.SampledSkipped++
+				} else {
+					result.Mismatches = append(result.Mismatches, fmt.Sprintf("%s: not in Postgres", doc.ID))
+				}
+			case err != nil:
+				return results, fmt.Errorf("failed to compare %s/%s: %w", c.collection, doc.ID, err)
+			}
+			for _, diff := range diffs {
+				result.Mismatches = append(result.Mismatches, fmt.Sprintf("%s: %s", doc.ID, diff))
+			}
+		}
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// sample picks documents after a random ID. Firestore IDs are random, so
+// this lands anywhere in the collection; near the end it wraps around to the
+// first documents.
+func (m *migrator) sample(ctx context.Context, collection string) ([]Document, error) {
+	docs, err := m.source.Page(ctx, collection, randomDocumentID(), m.opts.SampleSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == m.opts.SampleSize {
+		return docs, nil
+	}
+
+	first, err := m.source.Page(ctx, collection, "", m.opts.SampleSize-len(docs))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		seen[doc.ID] = true
+	}
+	for _, doc := range first {
+		if !seen[doc.ID] {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// randomDocumentID makes an ID shaped like Firestore's auto IDs.
+func randomDocumentID() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	id := make([]byte, 20)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return ""
+		}
+		id[i] = alphabet[n.Int64()]
+	}
+	return string(id)
+}
+
+// countMigrated walks the whole collection and counts the documents whose
+// row is in Postgres. Counting the table instead would include rows created
+// through the API since the cutover.
+func (m *migrator) countMigrated(ctx context.Context, c check) (int64, error) {
+	var count int64
+	after := ""
+	for {
+		docs, err := m.source.Page(ctx, c.collection, after, m.opts.PageSize)
+		if err != nil {
+			return 0, err
+		}
+		if len(docs) == 0 {
+			return count, nil
+		}
+
+		ids := make([]string, 0, len(docs))
+		for _, doc := range docs {
+			if id := c.rowID(doc); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		found, err := existing(m.db, c.model, c.column, ids)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s in Postgres: %w", c.collection, err)
+		}
+		count += int64(len(found))
+
+		if len(docs) < m.opts.PageSize {
+			return count, nil
+		}
+		after = docs[len(docs)-1].ID
+	}
+}
+
+func rowExists(db *gorm.DB, model interface{}, query string, args ...interface{}) (bool, error) {
+	var count int64
+	err := db.Model(model).Where(query, args...).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// referencesMissing reports whether any of the users, or the game when one is
+// given, wasn't migrated.
+func referencesMissing(db *gorm.DB, userIds []string, gameId string) (bool, error) {
+	users, err := existing(db, &models.User{}, "uid", userIds)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range userIds {
+		if !users[id] {
+			return true, nil
+		}
+	}
+	if gameId == "" {
+		return false, nil
+	}
+	found, err := rowExists(db, &models.Game{}, "id = ?", gameId)
+	return !found, err
+}
+
+func differs(diffs []string, field string, want, got interface{}) []string {
+	if want != got {
+		diffs = append(diffs, fmt.Sprintf("%s is %v in Firestore but %v in Postgres", field, want, got))
+	}
+	return diffs
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Achievement is something a creator rewards players for in their game. Key
+// is the creator's own identifier, which the game uses to unlock it. Hidden
+// achievements keep their name and description secret until unlocked.
+type Achievement struct {
+	ID           string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID       string `gorm:"type:uuid;uniqueIndex:idx_achievements_game_key"`
+	Key          string `gorm:"uniqueIndex:idx_achievements_game_key"`
+	Name         string
+	Description  string
+	IconFileName string
+	Hidden       bool `gorm:"default:false"`
+	Points       int  `gorm:"default:0"`
+	UnlockCount  int  `gorm:"default:0"`
+	// RequireSignature rejects unlocks that aren't signed with the game's
+	// score secret.
+	RequireSignature bool `gorm:"default:false"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type UserAchievement struct {
+	UserID        string      `gorm:"primaryKey"`
+	AchievementID string      `gorm:"primaryKey;type:uuid"`
+	Achievement   Achievement `gorm:"foreignKey:AchievementID"`
+	GameID        string      `gorm:"type:uuid;index"`
+	UnlockedAt    time.Time   `gorm:"autoCreateTime;index"`
+}
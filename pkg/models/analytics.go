@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// GameDailyStat is the per-day rollup behind creator analytics. Rows are
+// bumped in the same transaction as the interaction they count.
+type GameDailyStat struct {
+	GameID         string    `gorm:"primaryKey;type:uuid"`
+	Day            time.Time `gorm:"primaryKey;type:date"`
+	Plays          int64     `gorm:"default:0"`
+	UniquePlayers  int64     `gorm:"default:0"`
+	Sessions       int64     `gorm:"default:0"`
+	SessionSeconds int64     `gorm:"default:0"`
+	Likes          int64     `gorm:"default:0"`
+	Bookmarks      int64     `gorm:"default:0"`
+	Comments       int64     `gorm:"default:0"`
+	UpdatedAt      time.Time
+}
+
+// GamePlayerDay records that a user played a game on a given day. It backs
+// the unique player counts and retention cohorts.
+type GamePlayerDay struct {
+	GameID string    `gorm:"primaryKey;type:uuid"`
+	UserID string    `gorm:"primaryKey"`
+	Day    time.Time `gorm:"primaryKey;type:date;index"`
+}
+
+// AnalyticsBackfill is the single row recording when the analytics backfill
+// last finished, so restarts don't repeat a full backfill that just ran.
+type AnalyticsBackfill struct {
+	ID          int `gorm:"primaryKey"`
+	CompletedAt time.Time
+}
@@ -5,10 +5,10 @@ import "time"
 type Bookmark struct {
 	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	CreatedAt time.Time `gorm:"default:current_timestamp"`
-	UserID    string
-	User      User `gorm:"foreignKey:UserID"`
-	GameID    string
-	Game      Game `gorm:"foreignKey:GameID"`
+	UserID    string    `gorm:"uniqueIndex:idx_bookmarks_user_game"`
+	User      User      `gorm:"foreignKey:UserID"`
+	GameID    string    `gorm:"uniqueIndex:idx_bookmarks_user_game"`
+	Game      Game      `gorm:"foreignKey:GameID"`
 }
 
 func (Bookmark) TableName() string {
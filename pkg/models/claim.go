@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	ClaimStatusPending  = "pending"
+	ClaimStatusApproved = "approved"
+	ClaimStatusRejected = "rejected"
+)
+
+type GameClaim struct {
+	ID                string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID            string `gorm:"type:uuid;index"`
+	Game              Game   `gorm:"foreignKey:GameID"`
+	UserID            string `gorm:"index"`
+	User              User   `gorm:"foreignKey:UserID"`
+	Status            string `gorm:"default:pending;index"`
+	Message           string
+	VerificationToken string
+	VerifiedAt        *time.Time
+	ReviewedBy        *string
+	ReviewNote        string
+	ReviewedAt        *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Events            []GameClaimEvent `gorm:"foreignKey:ClaimID"`
+}
+
+// GameClaimEvent is the audit trail of a claim, one row per state change.
+type GameClaimEvent struct {
+	ID        string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ClaimID   string `gorm:"type:uuid;index"`
+	ActorID   string
+	Action    string
+	Note      string
+	CreatedAt time.Time `gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Collection is an editorially curated, ordered list of games. It is public
+// while the current time is inside its optional StartsAt/EndsAt window.
+type Collection struct {
+	ID          string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Slug        string `gorm:"uniqueIndex"`
+	Title       string
+	Description string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	CreatedBy   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Items       []CollectionItem `gorm:"foreignKey:CollectionID;constraint:OnDelete:CASCADE"`
+}
+
+type CollectionItem struct {
+	CollectionID string `gorm:"primaryKey;type:uuid"`
+	GameID       string `gorm:"primaryKey;type:uuid"`
+	Game         Game   `gorm:"foreignKey:GameID"`
+	Position     int
+}
+
+// FeaturedGame schedules a game into the featured slots of the feed. Higher
+// priority entries take the earlier slots.
+type FeaturedGame struct {
+	ID        string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID    string `gorm:"type:uuid;index"`
+	Game      Game   `gorm:"foreignKey:GameID"`
+	Priority  int    `gorm:"default:0"`
+	StartsAt  time.Time
+	EndsAt    *time.Time
+	CreatedBy string
+	CreatedAt time.Time
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ControlOrientationPortrait  = "portrait"
+	ControlOrientationLandscape = "landscape"
+	ControlOrientationAny       = "any"
+)
+
+const (
+	ControlElementButton   = "button"
+	ControlElementDPad     = "dpad"
+	ControlElementJoystick = "joystick"
+)
+
+// ControlScheme is one version of the on-screen controls a mobile client
+// draws over a keyboard-only game. Every edit adds a new version; the highest
+// one is current.
+type ControlScheme struct {
+	ID          string        `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID      string        `gorm:"type:uuid;uniqueIndex:idx_control_schemes_game_version"`
+	Version     int           `gorm:"uniqueIndex:idx_control_schemes_game_version"`
+	Orientation string        `gorm:"default:any"`
+	Layout      ControlLayout `gorm:"type:jsonb"`
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// ControlLayout is the virtual gamepad, stored as JSON.
+type ControlLayout struct {
+	Elements []ControlElement `json:"elements"`
+}
+
+// ControlElement is one on-screen control. X and Y place its center as a
+// fraction of the screen's width and height, Size is its diameter as a
+// fraction of the shorter side. Keys are the KeyboardEvent.code values it
+// presses: one for a button, and up, right, down, left for a dpad or joystick.
+type ControlElement struct {
+	ID    string   `json:"id"`
+	Type  string   `json:"type"`
+	Label string   `json:"label,omitempty"`
+	X     float64  `json:"x"`
+	Y     float64  `json:"y"`
+	Size  float64  `json:"size"`
+	Keys  []string `json:"keys"`
+}
+
+func (l ControlLayout) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (l *ControlLayout) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case nil:
+		*l = ControlLayout{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ControlLayout", value)
+	}
+}
@@ -5,24 +5,42 @@ import (
 	"time"
 )
 
+const (
+	GameTypeHTML5 = "html5"
+	GameTypeUnity = "unity"
+	GameTypeGodot = "godot"
+)
+
 type Game struct {
-	ID                string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Title             string
-	Description       string
-	PlayCount         int       `gorm:"default:0"`
-	PlayTime          time.Time `gorm:"default:0"`
-	LikeCount         int       `gorm:"default:0"`
-	CommentCount      int       `gorm:"default:0"`
-	BookmarkCount     int       `gorm:"default:0"`
-	IsFeatured        bool      `gorm:"default:false"`
-	GenreID           string    `gorm:"type:uuid"`
-	Genre             Genre     `gorm:"foreignKey:GenreID"`
-	ButtonMapping     bool      `gorm:"default:false"`
-	EmbedLink         string
-	GameType          string
-	ThumbnailFileName string
+	ID             string  `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ExternalID     *string `gorm:"uniqueIndex"`
+	Title          string
+	Description    string
+	PlayCount      int     `gorm:"default:0"`
+	PlayTime       int     `gorm:"default:0"`
+	LikeCount      int     `gorm:"default:0"`
+	CommentCount   int     `gorm:"default:0"`
+	BookmarkCount  int     `gorm:"default:0"`
+	RatingCount    int     `gorm:"default:0"`
+	RatingSum      int     `gorm:"default:0"`
+	AverageRating  float64 `gorm:"default:0"`
+	IsFeatured     bool    `gorm:"default:false"`
+	GenreID        string  `gorm:"type:uuid"`
+	Genre          Genre   `gorm:"foreignKey:GenreID"`
+	ButtonMapping  bool    `gorm:"default:false"`
+	TouchSupport   bool    `gorm:"default:false"`
+	GamepadSupport bool    `gorm:"default:false"`
+	EmbedLink      string
+	GameType       string
+	// The thumbnail is stored as storage keys, which responses resolve to
+	// URLs, so it stays out of the game's own JSON.
+	ThumbnailFileName string        `json:"-"`
+	ThumbnailVariants ImageVariants `gorm:"type:jsonb" json:"-"`
+	ThumbnailBlurhash string        `json:"-"`
+	ThumbnailColor    string        `json:"-"`
 	IsLandscape       bool
-	IsClaimed         bool `gorm:"default:false"`
+	IsClaimed         bool   `gorm:"default:false"`
+	ScoreSecret       string `json:"-"`
 	CreatorID         *string
 	Creator           *User `gorm:"foreignKey:CreatorID"`
 	CreatedAt         time.Time
@@ -33,6 +51,19 @@ type Game struct {
 	Tags              []Tag                 `gorm:"many2many:game_tags;"`
 }
 
+// DefaultInputSupport is the input a game of the given type is assumed to
+// take until its creator says otherwise. HTML5 games are made for browsers,
+// phones included, and Unity and Godot map standard gamepads out of the box.
+func DefaultInputSupport(gameType string) (touch, gamepad bool) {
+	switch gameType {
+	case GameTypeHTML5:
+		return true, false
+	case GameTypeUnity, GameTypeGodot:
+		return false, true
+	}
+	return false, false
+}
+
 type Tag struct {
 	gorm.Model
 	Name  string `gorm:"uniqueIndex"`
@@ -61,6 +92,7 @@ type UserGameInteraction struct {
 	LikeCount       int    `gorm:"default:0"`
 	CommentCount    int    `gorm:"default:0"`
 	BookmarkCount   int    `gorm:"default:0"`
+	ShareArrivals   int    `gorm:"default:0"`
 	LastInteraction time.Time
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
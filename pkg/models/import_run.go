@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ImportRun is the checkpoint of a catalog import. An unfinished run is
+// picked up again when the same file is imported, starting at NextRow.
+type ImportRun struct {
+	ID          uint `gorm:"primaryKey"`
+	Source      string
+	Checksum    string `gorm:"index"`
+	NextRow     int    `gorm:"default:0"`
+	Created     int    `gorm:"default:0"`
+	Updated     int    `gorm:"default:0"`
+	Unchanged   int    `gorm:"default:0"`
+	Conflicts   int    `gorm:"default:0"`
+	Invalid     int    `gorm:"default:0"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	LeaderboardOrderDesc = "desc"
+	LeaderboardOrderAsc  = "asc"
+)
+
+const (
+	LeaderboardPeriodAllTime = "all_time"
+	LeaderboardPeriodDaily   = "daily"
+	LeaderboardPeriodWeekly  = "weekly"
+)
+
+// Leaderboard is a high score table a creator defines for their game. Desc
+// boards rank the highest score first, asc boards (e.g. speedruns) the lowest.
+// Daily and weekly boards start over every UTC day or ISO week.
+type Leaderboard struct {
+	ID     string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID string `gorm:"type:uuid;uniqueIndex:idx_leaderboards_game_slug"`
+	Slug   string `gorm:"uniqueIndex:idx_leaderboards_game_slug"`
+	Name   string
+	Order  string `gorm:"default:desc"`
+	Period string `gorm:"default:all_time"`
+	// RequireSignature rejects scores that aren't signed with the game's
+	// score secret.
+	RequireSignature bool `gorm:"default:false"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// LeaderboardScore is a player's best score on a board for one period. The
+// period key is "all" for all-time boards and the UTC start date of the day
+// or week otherwise.
+type LeaderboardScore struct {
+	LeaderboardID string `gorm:"primaryKey;type:uuid"`
+	PeriodKey     string `gorm:"primaryKey"`
+	UserID        string `gorm:"primaryKey"`
+	User          User   `gorm:"foreignKey:UserID"`
+	Score         int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -5,10 +5,10 @@ import "time"
 type Like struct {
 	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	CreatedAt time.Time `gorm:"default:current_timestamp"`
-	UserID    string
-	User      User `gorm:"foreignKey:UserID"`
-	GameID    string
-	Game      Game `gorm:"foreignKey:GameID"`
+	UserID    string    `gorm:"uniqueIndex:idx_likes_user_game"`
+	User      User      `gorm:"foreignKey:UserID"`
+	GameID    string    `gorm:"uniqueIndex:idx_likes_user_game"`
+	Game      Game      `gorm:"foreignKey:GameID"`
 }
 
 func (Like) TableName() string {
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MigrationCheckpoint tracks one step of the Firestore migration. Steps read
+// their collection in document ID order, so LastDocumentID is where an
+// interrupted step picks up again.
+type MigrationCheckpoint struct {
+	Step           string `gorm:"primaryKey"`
+	LastDocumentID string
+	Migrated       int `gorm:"default:0"`
+	Skipped        int `gorm:"default:0"`
+	CompletedAt    *time.Time
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PlaySession tracks one sitting with a game. The client pings it while the
+// game is open and the server credits the time between pings.
+type PlaySession struct {
+	ID              string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID          string `gorm:"index"`
+	GameID          string `gorm:"type:uuid;index"`
+	StartedAt       time.Time
+	LastHeartbeatAt time.Time
+	EndedAt         *time.Time
+	// Duration is the credited play time in seconds.
+	Duration int `gorm:"default:0"`
+	// ShareLinkID is the share link the player arrived through, if any.
+	ShareLinkID *string `gorm:"type:uuid;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	PlaylistVisibilityPublic   = "public"
+	PlaylistVisibilityUnlisted = "unlisted"
+	PlaylistVisibilityPrivate  = "private"
+)
+
+// Playlist is a user's named, ordered list of games. Public playlists are
+// listed on the owner's profile, unlisted ones are only reachable through
+// their slug and private ones only by the owner.
+type Playlist struct {
+	ID            string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID        string `gorm:"index"`
+	User          User   `gorm:"foreignKey:UserID"`
+	Slug          string `gorm:"uniqueIndex"`
+	Title         string
+	Description   string
+	Visibility    string `gorm:"default:public"`
+	CoverFileName string
+	FollowerCount int `gorm:"default:0"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type PlaylistItem struct {
+	PlaylistID string `gorm:"primaryKey;type:uuid"`
+	GameID     string `gorm:"primaryKey;type:uuid"`
+	Game       Game   `gorm:"foreignKey:GameID"`
+	Position   int
+	AddedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+type PlaylistFollow struct {
+	PlaylistID string    `gorm:"primaryKey;type:uuid"`
+	UserID     string    `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"index"`
+}
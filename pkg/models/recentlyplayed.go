@@ -6,6 +6,7 @@ type RecentlyPlayed struct {
 	ID           string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	LastPlayedAt time.Time `gorm:"default:current_timestamp"`
 	PlayCount    int       `gorm:"default:1"`
+	PlayTime     int       `gorm:"default:0"`
 	UserID       string
 	User         User `gorm:"foreignKey:UserID"`
 	GameID       string
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Review is a user's star rating of a game with optional text. Each user has
+// at most one review per game and edits it in place.
+type Review struct {
+	ID           string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID       string `gorm:"uniqueIndex:idx_reviews_user_game"`
+	User         User   `gorm:"foreignKey:UserID"`
+	GameID       string `gorm:"type:uuid;uniqueIndex:idx_reviews_user_game;index"`
+	Rating       int    `gorm:"not null"`
+	Content      string
+	HelpfulCount int `gorm:"default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type ReviewHelpfulVote struct {
+	ReviewID  string `gorm:"primaryKey;type:uuid"`
+	UserID    string `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SaveState is a player's cloud save in one slot of a game. The blob itself
+// lives in storage under BlobKey, which changes on every write. Version goes
+// up by one on every write and is what clients send back as the ETag.
+type SaveState struct {
+	UserID    string `gorm:"primaryKey"`
+	GameID    string `gorm:"primaryKey;type:uuid"`
+	Slot      string `gorm:"primaryKey"`
+	Version   int64
+	Size      int64
+	Checksum  string
+	BlobKey   string `json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SearchOutbox records that a game changed and must be pushed to the search
+// index. Rows are written in the same transaction as the change and drained
+// by the search sync worker.
+type SearchOutbox struct {
+	ID          uint   `gorm:"primaryKey"`
+	GameID      string `gorm:"type:uuid;index"`
+	Attempts    int    `gorm:"default:0"`
+	LastError   string
+	AvailableAt time.Time  `gorm:"index"`
+	ProcessedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+func (SearchOutbox) TableName() string {
+	return "search_outbox"
+}
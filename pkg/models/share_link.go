@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	ShareTargetGame     = "game"
+	ShareTargetUser     = "user"
+	ShareTargetPlaylist = "playlist"
+)
+
+// ShareLink is a short /s/{code} link a user made to a game, profile or
+// playlist. Plays are credited to it when the player arrived through it.
+type ShareLink struct {
+	ID         string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Code       string `gorm:"uniqueIndex"`
+	UserID     string `gorm:"index"`
+	TargetType string `gorm:"index:idx_share_links_target"`
+	TargetID   string `gorm:"index:idx_share_links_target"`
+	ClickCount int    `gorm:"default:0"`
+	PlayCount  int    `gorm:"default:0"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ShareClick is one visit through a share link.
+type ShareClick struct {
+	ID          string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ShareLinkID string `gorm:"type:uuid;index"`
+	// SharerID is the link's owner, kept here so clicks can be counted per
+	// sharer without a join.
+	SharerID  string `gorm:"index"`
+	Referrer  string
+	CreatedAt time.Time `gorm:"index"`
+}
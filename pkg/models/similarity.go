@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// GameSimilarity is a precomputed "more like this" edge. Each game keeps its
+// best RelatedGame matches; the rows are rebuilt by the similarity job.
+type GameSimilarity struct {
+	GameID        string  `gorm:"primaryKey;type:uuid"`
+	RelatedGameID string  `gorm:"primaryKey;type:uuid"`
+	RelatedGame   Game    `gorm:"foreignKey:RelatedGameID"`
+	Score         float64 `gorm:"default:0"`
+	TagScore      float64 `gorm:"default:0"`
+	GenreScore    float64 `gorm:"default:0"`
+	CoPlayScore   float64 `gorm:"default:0"`
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"time"
+)
+
+type AchievementService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+}
+
+func NewAchievementService(databaseHandler database.Handler, storageBackend storage.Backend) *AchievementService {
+	return &AchievementService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+	}
+}
+
+// ListAchievements returns a game's achievements with the viewer's unlocks.
+// Hidden achievements the viewer hasn't unlocked only show their points,
+// unless the viewer manages the game.
+func (as *AchievementService) ListAchievements(gameId, viewerId string) (*types.AchievementsResponse, error) {
+	db := as.databaseHandler.DB
+
+	var game models.Game
+	if err := db.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var achievements []models.Achievement
+	if err := db.Where("game_id = ?", gameId).Order("created_at ASC").Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+
+	unlockedAt := make(map[string]time.Time)
+	isManager := false
+	if viewerId != "" {
+		var unlocks []models.UserAchievement
+		if err := db.Where("user_id = ? AND game_id = ?", viewerId, gameId).Find(&unlocks).Error; err != nil {
+			return nil, err
+		}
+		for _, unlock := range unlocks {
+			unlockedAt[unlock.AchievementID] = unlock.UnlockedAt
+		}
+
+		err := authorizeGameManager(db, game, viewerId)
+		if err != nil && !errors.Is(err, types.ErrForbidden) {
+			return nil, err
+		}
+		isManager = err == nil
+	}
+
+	results := make([]types.AchievementResponse, 0, len(achievements))
+	for _, achievement := range achievements {
+		res := as.achievementResponse(achievement)
+		if at, ok := unlockedAt[achievement.ID]; ok {
+			res.UnlockedAt = &at
+		} else if achievement.Hidden && !isManager {
+			res = types.AchievementResponse{Achievement: models.Achievement{
+				ID:     achievement.ID,
+				GameID: achievement.GameID,
+				Hidden: true,
+				Points: achievement.Points,
+			}}
+		}
+		results = append(results, res)
+	}
+
+	return &types.AchievementsResponse{Achievements: results}, nil
+}
+
+// ListUserAchievements returns the achievements a user has unlocked, most
+// recent first.
+func (as *AchievementService) ListUserAchievements(userId string, query types.PageQuery) (*types.PaginatedResponse, error) {
+	db := as.databaseHandler.DB
+	filter := func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.UserAchievement{}).
+			Joins("JOIN games g ON g.id = user_achievements.game_id AND "+database.ActiveGameFilter("g")).
+			Where("user_achievements.user_id = ?", userId)
+	}
+
+	var totalItems int64
+	if err := db.Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, err
+	}
+
+	var unlocks []models.UserAchievement
+	if err := db.Scopes(filter).
+		Preload("Achievement").
+		Order("user_achievements.unlocked_at DESC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&unlocks).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]types.AchievementResponse, 0, len(unlocks))
+	for _, unlock := range unlocks {
+		unlockedAt := unlock.UnlockedAt
+		res := as.achievementResponse(unlock.Achievement)
+		res.UnlockedAt = &unlockedAt
+		results = append(results, res)
+	}
+
+	return &types.PaginatedResponse{
+		Data:       results,
+		TotalItems: totalItems,
+		TotalPages: int((totalItems + int64(query.PageSize) - 1) / int64(query.PageSize)),
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// AchievementStats reports what share of the game's players unlocked each
+// achievement. A player is anyone who has played the game.
+func (as *AchievementService) AchievementStats(gameId, userId string) (*types.AchievementStatsResponse, error) {
+	db := as.databaseHandler.DB
+
+	var game models.Game
+	if err := findManagedGame(db, gameId, userId, &game); err != nil {
+		return nil, err
+	}
+
+	var players int64
+	if err := db.Model(&models.RecentlyPlayed{}).Where("game_id = ?", gameId).Distinct("user_id").Count(&players).Error; err != nil {
+		return nil, err
+	}
+
+	var achievements []models.Achievement
+	if err := db.Where("game_id = ?", gameId).Order("unlock_count DESC, created_at ASC").Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make([]types.AchievementStat, 0, len(achievements))
+	for _, achievement := range achievements {
+		stat := types.AchievementStat{
+			Achievement: achievement,
+			IconURL:     as.IconURL(achievement),
+		}
+		if players > 0 {
+			stat.UnlockPercentage = 100 * float64(achievement.UnlockCount) / float64(players)
+		}
+		stats = append(stats, stat)
+	}
+
+	return &types.AchievementStatsResponse{Players: players, Achievements: stats}, nil
+}
+
+func (as *AchievementService) CreateAchievement(gameId, userId string, req types.CreateAchievementRequest) (*types.AchievementResponse, error) {
+	key := req.Key
+	if key == "" {
+		key = slugify(req.Name)
+	}
+	if !slugPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: key must be lowercase letters, digits and single dashes", types.ErrInvalidInput)
+	}
+
+	achievement := models.Achievement{
+		GameID:           gameId,
+		Key:              key,
+		Name:             req.Name,
+		Description:      req.Description,
+		Hidden:           req.Hidden,
+		Points:           req.Points,
+		RequireSignature: req.RequireSignature,
+	}
+
+	err := as.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Achievement{}).Where("game_id = ? AND key = ?", gameId, key).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: the game already has an achievement with key %s", types.ErrConflict, key)
+		}
+
+		if err := tx.Create(&achievement).Error; err != nil {
+			return fmt.Errorf("failed to create achievement: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := as.achievementResponse(achievement)
+	return &res, nil
+}
+
+// UpdateAchievement edits an achievement. Changing its points also changes
+// the totals of everyone who already unlocked it.
+func (as *AchievementService) UpdateAchievement(gameId, key, userId string, req types.UpdateAchievementRequest) (*types.AchievementResponse, error) {
+	var achievement models.Achievement
+	err := as.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if err := findAchievement(tx, gameId, key, &achievement); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Name != nil {
+			achievement.Name = *req.Name
+			updates["name"] = achievement.Name
+		}
+		if req.Description != nil {
+			achievement.Description = *req.Description
+			updates["description"] = achievement.Description
+		}
+		if req.Hidden != nil {
+			achievement.Hidden = *req.Hidden
+			updates["hidden"] = achievement.Hidden
+		}
+		if req.RequireSignature != nil {
+			achievement.RequireSignature = *req.RequireSignature
+			updates["require_signature"] = achievement.RequireSignature
+		}
+		if req.Points != nil && *req.Points != achievement.Points {
+			if err := applyAchievementPoints(tx, achievement.ID, *req.Points-achievement.Points, 0); err != nil {
+				return err
+			}
+			achievement.Points = *req.Points
+			updates["points"] = achievement.Points
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&achievement).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update achievement: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := as.achievementResponse(achievement)
+	return &res, nil
+}
+
+// DeleteAchievement removes an achievement and takes it off the totals of
+// everyone who unlocked it.
+func (as *AchievementService) DeleteAchievement(ctx context.Context, gameId, key, userId string) error {
+	var achievement models.Achievement
+	err := as.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if err := findAchievement(tx, gameId, key, &achievement); err != nil {
+			return err
+		}
+		if err := applyAchievementPoints(tx, achievement.ID, -achievement.Points, -1); err != nil {
+			return err
+		}
+		if err := tx.Where("achievement_id = ?", achievement.ID).Delete(&models.UserAchievement{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&achievement).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if achievement.IconFileName != "" {
+		as.storage.Delete(ctx, achievement.IconFileName)
+	}
+	return nil
+}
+
+func (as *AchievementService) UploadIcon(ctx context.Context, gameId, key, userId string, file io.Reader) (*types.AchievementResponse, error) {
+	db := as.databaseHandler.DB
+
+	var game models.Game
+	if err := findManagedGame(db, gameId, userId, &game); err != nil {
+		return nil, err
+	}
+	var achievement models.Achievement
+	if err := findAchievement(db, gameId, key, &achievement); err != nil {
+		return nil, err
+	}
+
+	data, contentType, ext, err := readImage(file)
+	if err != nil {
+		return nil, err
+	}
+
+	iconKey := fmt.Sprintf("achievements/%s/%s.%s", achievement.ID, uuid.NewString(), ext)
+	if err := as.storage.Put(ctx, iconKey, bytes.NewReader(data), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store icon: %w", err)
+	}
+
+	previous := achievement.IconFileName
+	if err := db.Model(&achievement).Update("icon_file_name", iconKey).Error; err != nil {
+		as.storage.Delete(ctx, iconKey)
+		return nil, fmt.Errorf("failed to update icon: %w", err)
+	}
+	achievement.IconFileName = iconKey
+
+	if previous != "" {
+		as.storage.Delete(ctx, previous)
+	}
+
+	res := as.achievementResponse(achievement)
+	return &res, nil
+}
+
+// UnlockAchievement unlocks an achievement for the user and adds its points
+// to their profile. Unlocking twice is a no-op. Only players who have played
+// the game can unlock its achievements, and, as with scores, a signature is
+// checked whenever one is sent and required on achievements that ask for it.
+func (as *AchievementService) UnlockAchievement(gameId, key, userId string, req types.UnlockAchievementRequest) (*types.UnlockAchievementResponse, error) {
+	var achievement models.Achievement
+	var unlock models.UserAchievement
+	var newlyUnlocked bool
+	err := as.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := findAchievement(tx, gameId, key, &achievement); err != nil {
+			return err
+		}
+		if err := tx.First(&models.User{}, "uid = ?", userId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: user profile does not exist", types.ErrNotFound)
+			}
+			return err
+		}
+
+		var played int64
+		if err := tx.Model(&models.RecentlyPlayed{}).Where("game_id = ? AND user_id = ?", gameId, userId).Count(&played).Error; err != nil {
+			return err
+		}
+		if played == 0 {
+			return fmt.Errorf("%w: play the game before unlocking its achievements", types.ErrForbidden)
+		}
+		if req.Signature != "" || achievement.RequireSignature {
+			if err := verifyUnlockSignature(game.ScoreSecret, key, userId, req.Timestamp, req.Signature, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		unlock = models.UserAchievement{UserID: userId, AchievementID: achievement.ID, GameID: gameId}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&unlock)
+		if result.Error != nil {
+			return fmt.Errorf("failed to unlock achievement: %w", result.Error)
+		}
+		newlyUnlocked = result.RowsAffected > 0
+		if !newlyUnlocked {
+			return tx.First(&unlock, "user_id = ? AND achievement_id = ?", userId, achievement.ID).Error
+		}
+
+		if err := tx.Model(&achievement).UpdateColumn("unlock_count", gorm.Expr("unlock_count + 1")).Error; err != nil {
+			return err
+		}
+		achievement.UnlockCount++
+
+		return tx.Model(&models.User{}).Where("uid = ?", userId).UpdateColumns(map[string]interface{}{
+			"achievement_points": gorm.Expr("achievement_points + ?", achievement.Points),
+			"achievement_count":  gorm.Expr("achievement_count + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := as.achievementResponse(achievement)
+	res.UnlockedAt = &unlock.UnlockedAt
+	return &types.UnlockAchievementResponse{Achievement: res, NewlyUnlocked: newlyUnlocked}, nil
+}
+
+func (as *AchievementService) IconURL(achievement models.Achievement) string {
+	if achievement.IconFileName == "" {
+		return ""
+	}
+	return as.storage.URL(achievement.IconFileName)
+}
+
+func (as *AchievementService) achievementResponse(achievement models.Achievement) types.AchievementResponse {
+	return types.AchievementResponse{Achievement: achievement, IconURL: as.IconURL(achievement)}
+}
+
+func findAchievement(tx *gorm.DB, gameId, key string, achievement *models.Achievement) error {
+	if err := tx.First(achievement, "game_id = ? AND key = ?", gameId, key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: achievement not found", types.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// applyAchievementPoints adjusts the profile totals of every user who has
+// unlocked the achievement.
+func applyAchievementPoints(tx *gorm.DB, achievementId string, pointsDelta, countDelta int) error {
+	err := tx.Model(&models.User{}).
+		Where("uid IN (SELECT user_id FROM user_achievements WHERE achievement_id = ?)", achievementId).
+		UpdateColumns(map[string]interface{}{
+			"achievement_points": gorm.Expr("GREATEST(achievement_points + ?, 0)", pointsDelta),
+			"achievement_count":  gorm.Expr("GREATEST(achievement_count + ?, 0)", countDelta),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update achievement totals: %w", err)
+	}
+	return nil
+}
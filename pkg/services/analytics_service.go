@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+)
+
+type AnalyticsService struct {
+	databaseHandler database.Handler
+}
+
+func NewAnalyticsService(databaseHandler database.Handler) *AnalyticsService {
+	return &AnalyticsService{databaseHandler: databaseHandler}
+}
+
+type retentionRow struct {
+	Day    time.Time
+	Cohort int64
+	D1     int64
+	D7     int64
+}
+
+// GetGameAnalytics returns one point per day between from and to, inclusive.
+// Only the game's creator and admins may see it.
+func (as *AnalyticsService) GetGameAnalytics(gameId, userId string, query types.GameAnalyticsQuery) (*types.GameAnalyticsResponse, error) {
+	db := as.databaseHandler.DB
+
+	var game models.Game
+	if err := db.First(&game, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+	if err := authorizeGameManager(db, game, userId); err != nil {
+		return nil, err
+	}
+
+	from, to, err := analyticsRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.GameDailyStat
+	if err := db.Where("game_id = ? AND day BETWEEN ? AND ?", gameId, from.Format(analytics.DateLayout), to.Format(analytics.DateLayout)).
+		Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get analytics: %w", err)
+	}
+
+	// A player's cohort is the first day they ever played the game, which
+	// may be before the requested range.
+	var retention []retentionRow
+	if err := db.Raw(`
+		WITH firsts AS (
+			SELECT user_id, MIN(day) AS first_day FROM game_player_days
+			WHERE game_id = ? GROUP BY user_id
+		)
+		SELECT f.first_day AS day, COUNT(*) AS cohort, COUNT(d1.user_id) AS d1, COUNT(d7.user_id) AS d7
+		FROM firsts f
+		LEFT JOIN game_player_days d1 ON d1.game_id = ? AND d1.user_id = f.user_id AND d1.day = f.first_day + 1
+		LEFT JOIN game_player_days d7 ON d7.game_id = ? AND d7.user_id = f.user_id AND d7.day = f.first_day + 7
+		WHERE f.first_day BETWEEN ? AND ?
+		GROUP BY f.first_day
+	`, gameId, gameId, gameId, from.Format(analytics.DateLayout), to.Format(analytics.DateLayout)).
+		Scan(&retention).Error; err != nil {
+		return nil, fmt.Errorf("failed to get retention: %w", err)
+	}
+
+	statsByDay := make(map[string]models.GameDailyStat, len(stats))
+	for _, stat := range stats {
+		statsByDay[stat.Day.Format(analytics.DateLayout)] = stat
+	}
+	retentionByDay := make(map[string]retentionRow, len(retention))
+	for _, row := range retention {
+		retentionByDay[row.Day.Format(analytics.DateLayout)] = row
+	}
+
+	today := analytics.Day(time.Now())
+	var days []types.GameAnalyticsDay
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format(analytics.DateLayout)
+		stat := statsByDay[key]
+		cohort := retentionByDay[key]
+
+		point := types.GameAnalyticsDay{
+			Day:           key,
+			Plays:         stat.Plays,
+			UniquePlayers: stat.UniquePlayers,
+			NewPlayers:    cohort.Cohort,
+			Sessions:      stat.Sessions,
+			Likes:         stat.Likes,
+			Bookmarks:     stat.Bookmarks,
+			Comments:      stat.Comments,
+		}
+		if stat.Sessions > 0 {
+			point.AvgSessionSeconds = float64(stat.SessionSeconds) / float64(stat.Sessions)
+		}
+		if cohort.Cohort > 0 {
+			if day.AddDate(0, 0, 1).Before(today) {
+				d1 := float64(cohort.D1) / float64(cohort.Cohort)
+				point.D1Retention = &d1
+			}
+			if day.AddDate(0, 0, 7).Before(today) {
+				d7 := float64(cohort.D7) / float64(cohort.Cohort)
+				point.D7Retention = &d7
+			}
+		}
+		days = append(days, point)
+	}
+
+	return &types.GameAnalyticsResponse{
+		GameID: gameId,
+		From:   from.Format(analytics.DateLayout),
+		To:     to.Format(analytics.DateLayout),
+		Days:   days,
+	}, nil
+}
+
+// analyticsRange resolves the requested range, defaulting to the last 30
+// days up to today.
+func analyticsRange(query types.GameAnalyticsQuery) (from, to time.Time, err error) {
+	to = analytics.Day(time.Now())
+	if query.To != "" {
+		if to, err = time.Parse(analytics.DateLayout, query.To); err != nil {
+			return from, to, fmt.Errorf("%w: invalid to date", types.ErrInvalidInput)
+		}
+	}
+
+	from = to.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if query.From != "" {
+		if from, err = time.Parse(analytics.DateLayout, query.From); err != nil {
+			return from, to, fmt.Errorf("%w: invalid from date", types.ErrInvalidInput)
+		}
+	}
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("%w: from must not be after to", types.ErrInvalidInput)
+	}
+	if to.Sub(from) >= maxAnalyticsDays*24*time.Hour {
+		return from, to, fmt.Errorf("%w: range is limited to %d days", types.ErrInvalidInput, maxAnalyticsDays)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/fetcher"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// claimVerificationPath is where a developer places the verification token,
+// relative to the origin of the game's EmbedLink.
+const claimVerificationPath = "/.well-known/hitbox-claim.txt"
+
+type ClaimService struct {
+	databaseHandler database.Handler
+	fetcher         fetcher.Fetcher
+}
+
+func NewClaimService(databaseHandler database.Handler, documentFetcher fetcher.Fetcher) *ClaimService {
+	return &ClaimService{
+		databaseHandler: databaseHandler,
+		fetcher:         documentFetcher,
+	}
+}
+
+func (cs *ClaimService) CreateClaim(gameId, userId string, req types.CreateClaimRequest) (claim models.GameClaim, err error) {
+	token, err := newVerificationToken()
+	if err != nil {
+		return claim, err
+	}
+
+	err = cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.User{}, "uid = ?", userId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: user profile does not exist", types.ErrNotFound)
+			}
+			return err
+		}
+
+		var game models.Game
+		if err := tx.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if game.IsClaimed || game.CreatorID != nil {
+			return fmt.Errorf("%w: game has already been claimed", types.ErrConflict)
+		}
+
+		var pending int64
+		if err := tx.Model(&models.GameClaim{}).
+			Where("game_id = ? AND user_id = ? AND status = ?", gameId, userId, models.ClaimStatusPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%w: you already have a pending claim for this game", types.ErrConflict)
+		}
+
+		claim = models.GameClaim{
+			GameID:            gameId,
+			UserID:            userId,
+			Status:            models.ClaimStatusPending,
+			Message:           req.Message,
+			VerificationToken: token,
+		}
+		if err := tx.Create(&claim).Error; err != nil {
+			return fmt.Errorf("failed to create claim: %w", err)
+		}
+		claim.Game = game
+
+		return recordClaimEvent(tx, claim.ID, userId, "submitted", req.Message)
+	})
+
+	return claim, err
+}
+
+// VerifyClaim fetches the verification document from the game's origin and
+// marks the claim as verified if it contains the claim's token. It does not
+// change the status; an admin still has to approve.
+func (cs *ClaimService) VerifyClaim(ctx context.Context, claimId, userId string) (claim models.GameClaim, err error) {
+	if err = cs.databaseHandler.DB.Preload("Game").First(&claim, "id = ?", claimId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return claim, fmt.Errorf("%w: claim not found", types.ErrNotFound)
+		}
+		return claim, err
+	}
+	if claim.UserID != userId {
+		return claim, fmt.Errorf("%w: only the claimant can verify this claim", types.ErrForbidden)
+	}
+	if claim.Status != models.ClaimStatusPending {
+		return claim, fmt.Errorf("%w: claim is already %s", types.ErrConflict, claim.Status)
+	}
+
+	verificationURL, err := ClaimVerificationURL(claim.Game)
+	if err != nil {
+		return claim, err
+	}
+
+	body, err := cs.fetcher.Fetch(fetcher.WithExpected(ctx, claim.VerificationToken), verificationURL)
+	if err != nil {
+		if errors.Is(err, fetcher.ErrNotFound) {
+			return claim, fmt.Errorf("%w: verification file not found at %s", types.ErrInvalidInput, verificationURL)
+		}
+		if errors.Is(err, fetcher.ErrForbiddenAddress) || errors.Is(err, fetcher.ErrForeignRedirect) {
+			return claim, fmt.Errorf("%w: %s can't be fetched: %v", types.ErrInvalidInput, verificationURL, err)
+		}
+		return claim, fmt.Errorf("failed to fetch verification file: %w", err)
+	}
+	if !strings.Contains(body, claim.VerificationToken) {
+		return claim, fmt.Errorf("%w: verification token not found at %s", types.ErrInvalidInput, verificationURL)
+	}
+
+	now := time.Now()
+	err = cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&claim).Update("verified_at", now).Error; err != nil {
+			return err
+		}
+		return recordClaimEvent(tx, claim.ID, userId, "verified", verificationURL)
+	})
+	claim.VerifiedAt = &now
+
+	return claim, err
+}
+
+func (cs *ClaimService) ApproveClaim(claimId, adminId string, req types.ReviewClaimRequest) (claim models.GameClaim, err error) {
+	err = cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingClaim(tx, claimId, &claim); err != nil {
+			return err
+		}
+		if claim.VerifiedAt == nil && !req.Override {
+			return fmt.Errorf("%w: claim has not been verified", types.ErrConflict)
+		}
+
+		var game models.Game
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(database.ActiveGames).
+			First(&game, "id = ?", claim.GameID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if game.IsClaimed || game.CreatorID != nil {
+			return fmt.Errorf("%w: game has already been claimed", types.ErrConflict)
+		}
+
+		if err := tx.Model(&game).Updates(map[string]interface{}{
+			"creator_id": claim.UserID,
+			"is_claimed": true,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to assign game: %w", err)
+		}
+
+		if err := reviewClaim(tx, &claim, adminId, models.ClaimStatusApproved, req.Note); err != nil {
+			return err
+		}
+
+		// Everyone else who was waiting on this game loses out.
+		var competing []models.GameClaim
+		if err := tx.Where("game_id = ? AND status = ? AND id <> ?", claim.GameID, models.ClaimStatusPending, claim.ID).
+			Find(&competing).Error; err != nil {
+			return err
+		}
+		for i := range competing {
+			if err := reviewClaim(tx, &competing[i], adminId, models.ClaimStatusRejected, "Another claim for this game was approved"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return claim, err
+}
+
+func (cs *ClaimService) RejectClaim(claimId, adminId string, req types.ReviewClaimRequest) (claim models.GameClaim, err error) {
+	err = cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingClaim(tx, claimId, &claim); err != nil {
+			return err
+		}
+		return reviewClaim(tx, &claim, adminId, models.ClaimStatusRejected, req.Note)
+	})
+
+	return claim, err
+}
+
+// GetClaimById returns a claim with its audit trail. Only the claimant and
+// admins may see it.
+func (cs *ClaimService) GetClaimById(claimId, userId string) (claim models.GameClaim, err error) {
+	err = cs.databaseHandler.DB.
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Game").
+		First(&claim, "id = ?", claimId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return claim, fmt.Errorf("%w: claim not found", types.ErrNotFound)
+		}
+		return claim, err
+	}
+
+	if claim.UserID != userId {
+		admin, err := isAdmin(cs.databaseHandler.DB, userId)
+		if err != nil {
+			return claim, err
+		}
+		if !admin {
+			return claim, fmt.Errorf("%w: not allowed to view this claim", types.ErrForbidden)
+		}
+	}
+
+	return claim, nil
+}
+
+// ListClaims returns claims filtered by status and game. Admins see every
+// claim, everyone else only sees their own.
+func (cs *ClaimService) ListClaims(userId string, query types.ListClaimsQuery) (*types.PaginatedResponse, error) {
+	admin, err := isAdmin(cs.databaseHandler.DB, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := func(db *gorm.DB) *gorm.DB {
+		if !admin {
+			db = db.Where("user_id = ?", userId)
+		}
+		if query.Status != "" {
+			db = db.Where("status = ?", query.Status)
+		}
+		if query.GameID != "" {
+			db = db.Where("game_id = ?", query.GameID)
+		}
+		return db
+	}
+
+	var totalItems int64
+	if err := cs.databaseHandler.DB.Model(&models.GameClaim{}).Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count claims: %w", err)
+	}
+
+	var claims []models.GameClaim
+	offset := (query.Page - 1) * query.PageSize
+	if err := cs.databaseHandler.DB.Scopes(filter).
+		Preload("Game").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&claims).Error; err != nil {
+		return nil, fmt.Errorf("failed to get claims: %w", err)
+	}
+
+	totalPages := (int(totalItems) + query.PageSize - 1) / query.PageSize
+
+	return &types.PaginatedResponse{
+		Data:       claims,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// ClaimVerificationURL is where the claimant has to publish their token.
+func ClaimVerificationURL(game models.Game) (string, error) {
+	u, err := url.Parse(game.EmbedLink)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: game has no valid embed link to verify against", types.ErrInvalidInput)
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: claimVerificationPath}).String(), nil
+}
+
+func lockPendingClaim(tx *gorm.DB, claimId string, claim *models.GameClaim) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(claim, "id = ?", claimId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: claim not found", types.ErrNotFound)
+		}
+		return err
+	}
+	if claim.Status != models.ClaimStatusPending {
+		return fmt.Errorf("%w: claim is already %s", types.ErrConflict, claim.Status)
+	}
+	return nil
+}
+
+func reviewClaim(tx *gorm.DB, claim *models.GameClaim, adminId, status, note string) error {
+	now := time.Now()
+	if err := tx.Model(claim).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewed_by": adminId,
+		"review_note": note,
+		"reviewed_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update claim: %w", err)
+	}
+	claim.Status = status
+	claim.ReviewedBy = &adminId
+	claim.ReviewNote = note
+	claim.ReviewedAt = &now
+
+	return recordClaimEvent(tx, claim.ID, adminId, status, note)
+}
+
+func recordClaimEvent(tx *gorm.DB, claimId, actorId, action, note string) error {
+	event := models.GameClaimEvent{
+		ClaimID: claimId,
+		ActorID: actorId,
+		Action:  action,
+		Note:    note,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record claim event: %w", err)
+	}
+	return nil
+}
+
+func newVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return "hitbox-claim-" + hex.EncodeToString(b), nil
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
 	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
 	"gorm.io/gorm"
 )
@@ -32,6 +35,14 @@ func (cs *CommentService) ValidateParentComment(parentID, gameID string) (bool,
 func (cs *CommentService) CreateCommentByGameId(gameId, userId string, req types.CreateCommentRequest) (comment models.Comment, err error) {
 	tx := cs.databaseHandler.DB.Begin()
 
+	if err = tx.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return
+	}
+
 	comment = models.Comment{
 		Content:  req.Content,
 		UserID:   userId,
@@ -49,6 +60,16 @@ func (cs *CommentService) CreateCommentByGameId(gameId, userId string, req types
 		return
 	}
 
+	if err = analytics.Record(tx, gameId, comment.CreatedAt, analytics.Delta{Comments: 1}); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = gamesearch.Enqueue(tx, gameId); err != nil {
+		tx.Rollback()
+		return
+	}
+
 	if err = tx.Commit().Error; err != nil {
 		return
 	}
@@ -63,6 +84,13 @@ func (cs *CommentService) GetCommentsByGameId(gameId string, page, pageSize int)
 
 	offset := (page - 1) * pageSize
 
+	if err := cs.databaseHandler.DB.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, 0, err
+	}
+
 	if err := cs.databaseHandler.DB.Model(&models.Comment{}).Where("game_id = ? AND parent_id IS NULL AND is_deleted = false", gameId).Count(&totalItems).Error; err != nil {
 		return nil, 0, err
 	}
@@ -119,10 +147,11 @@ func (cs *CommentService) DeleteCommentByCommentId(commentId, userId string) err
 	}
 
 	return cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
-		var replyCount int64
-		if err := tx.Model(&models.Comment{}).Where("parent_id = ? AND is_deleted = false", commentId).Count(&replyCount).Error; err != nil {
+		var replies []models.Comment
+		if err := tx.Select("id", "created_at").Where("parent_id = ? AND is_deleted = false", commentId).Find(&replies).Error; err != nil {
 			return err
 		}
+		replyCount := int64(len(replies))
 
 		if err := tx.Model(&comment).Update("is_deleted", true).Error; err != nil {
 			return err
@@ -137,6 +166,12 @@ func (cs *CommentService) DeleteCommentByCommentId(commentId, userId string) err
 			return err
 		}
 
-		return nil
+		for _, removed := range append(replies, comment) {
+			if err := analytics.Record(tx, comment.GameID, removed.CreatedAt, analytics.Delta{Comments: -1}); err != nil {
+				return err
+			}
+		}
+
+		return gamesearch.Enqueue(tx, comment.GameID)
 	})
 }
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"regexp"
+)
+
+const (
+	maxControlElements     = 32
+	maxControlLabelLength  = 16
+	maxControlElementSize  = 0.5
+	controlDirectionalKeys = 4
+)
+
+var (
+	controlElementIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	// keyCodePattern matches the KeyboardEvent.code values games commonly
+	// listen for.
+	keyCodePattern = regexp.MustCompile(`^(Key[A-Z]|Digit[0-9]|Numpad[0-9]|Arrow(Up|Down|Left|Right)|F([1-9]|1[0-2])|` +
+		`Space|Enter|Escape|Tab|Backspace|(Shift|Control|Alt|Meta)(Left|Right)|Comma|Period|Slash|Semicolon|Quote|` +
+		`Minus|Equal|BracketLeft|BracketRight|Backslash|Backquote)$`)
+)
+
+type ControlSchemeService struct {
+	databaseHandler database.Handler
+}
+
+func NewControlSchemeService(databaseHandler database.Handler) *ControlSchemeService {
+	return &ControlSchemeService{
+		databaseHandler: databaseHandler,
+	}
+}
+
+// GetControlScheme returns one version of a game's control scheme, or the
+// current one when version is 0.
+func (cs *ControlSchemeService) GetControlScheme(gameId string, version int) (*models.ControlScheme, error) {
+	db := cs.databaseHandler.DB
+	if err := db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var scheme *models.ControlScheme
+	var err error
+	if version == 0 {
+		scheme, err = currentControlScheme(db, gameId)
+	} else {
+		scheme = &models.ControlScheme{}
+		err = db.First(scheme, "game_id = ? AND version = ?", gameId, version).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			scheme, err = nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if scheme == nil {
+		return nil, fmt.Errorf("%w: control scheme not found", types.ErrNotFound)
+	}
+	return scheme, nil
+}
+
+// ListControlSchemeVersions returns every version of a game's control
+// scheme, newest first.
+func (cs *ControlSchemeService) ListControlSchemeVersions(gameId, userId string) (*types.ControlSchemeVersionsResponse, error) {
+	db := cs.databaseHandler.DB
+
+	var game models.Game
+	if err := findManagedGame(db, gameId, userId, &game); err != nil {
+		return nil, err
+	}
+
+	versions := []models.ControlScheme{}
+	if err := db.Where("game_id = ?", gameId).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return &types.ControlSchemeVersionsResponse{Versions: versions}, nil
+}
+
+// PutControlScheme saves a new version of the game's control scheme.
+func (cs *ControlSchemeService) PutControlScheme(gameId, userId string, req types.PutControlSchemeRequest) (*models.ControlScheme, error) {
+	if err := validateControlLayout(req.Layout); err != nil {
+		return nil, err
+	}
+
+	scheme := models.ControlScheme{
+		GameID:      gameId,
+		Orientation: req.Orientation,
+		Layout:      req.Layout,
+		CreatedBy:   userId,
+	}
+	err := cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		// Locking the game serializes edits so versions stay gapless.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+			return err
+		}
+
+		current, err := currentControlScheme(tx, gameId)
+		if err != nil {
+			return err
+		}
+		currentVersion := 0
+		if current != nil {
+			currentVersion = current.Version
+		}
+		if req.BaseVersion != nil && *req.BaseVersion != currentVersion {
+			return fmt.Errorf("%w: the control scheme is at version %d, not %d", types.ErrConflict, currentVersion, *req.BaseVersion)
+		}
+
+		scheme.Version = currentVersion + 1
+		if err := tx.Create(&scheme).Error; err != nil {
+			return fmt.Errorf("failed to save control scheme: %w", err)
+		}
+
+		// ButtonMapping is kept for clients that only check whether a
+		// mapping exists, and lets touch-only devices find the game.
+		if err := tx.Model(&game).UpdateColumn("button_mapping", true).Error; err != nil {
+			return err
+		}
+		return gamesearch.Enqueue(tx, gameId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheme, nil
+}
+
+// DeleteControlScheme removes every version of the game's control scheme.
+func (cs *ControlSchemeService) DeleteControlScheme(gameId, userId string) error {
+	return cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		result := tx.Where("game_id = ?", gameId).Delete(&models.ControlScheme{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: control scheme not found", types.ErrNotFound)
+		}
+
+		if err := tx.Model(&game).UpdateColumn("button_mapping", false).Error; err != nil {
+			return err
+		}
+		return gamesearch.Enqueue(tx, gameId)
+	})
+}
+
+// currentControlScheme returns the game's latest control scheme, or nil if it
+// has none.
+func currentControlScheme(tx *gorm.DB, gameId string) (*models.ControlScheme, error) {
+	var scheme models.ControlScheme
+	err := tx.Where("game_id = ?", gameId).Order("version DESC").First(&scheme).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get control scheme: %w", err)
+	}
+	return &scheme, nil
+}
+
+// validateControlLayout checks a layout against the control scheme rules
+// documented on models.ControlElement.
+func validateControlLayout(layout models.ControlLayout) error {
+	if len(layout.Elements) == 0 {
+		return fmt.Errorf("%w: layout needs at least one element", types.ErrInvalidInput)
+	}
+	if len(layout.Elements) > maxControlElements {
+		return fmt.Errorf("%w: layout can have at most %d elements", types.ErrInvalidInput, maxControlElements)
+	}
+
+	ids := make(map[string]bool, len(layout.Elements))
+	for i, element := range layout.Elements {
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w: element %d: %s", types.ErrInvalidInput, i, fmt.Sprintf(format, args...))
+		}
+
+		if !controlElementIDPattern.MatchString(element.ID) {
+			return invalid("id must be 1 to 32 letters, digits, dashes or underscores")
+		}
+		if ids[element.ID] {
+			return invalid("duplicate id %s", element.ID)
+		}
+		ids[element.ID] = true
+
+		if len([]rune(element.Label)) > maxControlLabelLength {
+			return invalid("label can be at most %d characters", maxControlLabelLength)
+		}
+		if element.X < 0 || element.X > 1 || element.Y < 0 || element.Y > 1 {
+			return invalid("x and y must be between 0 and 1")
+		}
+		if element.Size <= 0 || element.Size > maxControlElementSize {
+			return invalid("size must be above 0 and at most %g", maxControlElementSize)
+		}
+
+		switch element.Type {
+		case models.ControlElementButton:
+			if len(element.Keys) != 1 {
+				return invalid("a button binds exactly one key")
+			}
+		case models.ControlElementDPad, models.ControlElementJoystick:
+			if len(element.Keys) != controlDirectionalKeys {
+				return invalid("a %s binds exactly %d keys: up, right, down, left", element.Type, controlDirectionalKeys)
+			}
+		default:
+			return invalid("type must be button, dpad or joystick")
+		}
+		for _, key := range element.Keys {
+			if !keyCodePattern.MatchString(key) {
+				return invalid("unsupported key %q", key)
+			}
+		}
+	}
+	return nil
+}
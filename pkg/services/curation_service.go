@@ -0,0 +1,497 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	slugPattern     = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	slugUnsafeChars = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// liveFilter limits a query to rows whose starts_at/ends_at window contains
+// the current time. A missing bound is open.
+func liveFilter(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now)
+	}
+}
+
+type CurationService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+	featuredSlots   []int
+}
+
+// NewCurationService takes the 1-based feed positions featured games are
+// placed at, in ascending order.
+func NewCurationService(databaseHandler database.Handler, storageBackend storage.Backend, featuredSlots []int) *CurationService {
+	return &CurationService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+		featuredSlots:   featuredSlots,
+	}
+}
+
+// ListCollections returns collections newest first. Unless includeAll is set
+// only collections that are currently live are returned.
+func (cs *CurationService) ListCollections(query types.PageQuery, includeAll bool) (*types.PaginatedResponse, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if includeAll {
+			return db
+		}
+		return db.Scopes(liveFilter(time.Now()))
+	}
+
+	var totalItems int64
+	if err := cs.databaseHandler.DB.Model(&models.Collection{}).Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count collections: %w", err)
+	}
+
+	var collections []models.Collection
+	offset := (query.Page - 1) * query.PageSize
+	if err := cs.databaseHandler.DB.Scopes(filter).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+
+	ids := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		ids = append(ids, collection.ID)
+	}
+	var counts []struct {
+		CollectionID string
+		GameCount    int64
+	}
+	if len(ids) > 0 {
+		if err := cs.databaseHandler.DB.Table("collection_items ci").
+			Select("ci.collection_id, COUNT(*) AS game_count").
+			Joins("JOIN games g ON g.id = ci.game_id AND "+database.ActiveGameFilter("g")).
+			Where("ci.collection_id IN ?", ids).
+			Group("ci.collection_id").
+			Scan(&counts).Error; err != nil {
+			return nil, fmt.Errorf("failed to count collection games: %w", err)
+		}
+	}
+	countById := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		countById[count.CollectionID] = count.GameCount
+	}
+
+	summaries := make([]types.CollectionSummary, 0, len(collections))
+	for _, collection := range collections {
+		summaries = append(summaries, types.CollectionSummary{
+			Collection: collection,
+			GameCount:  countById[collection.ID],
+		})
+	}
+
+	return &types.PaginatedResponse{
+		Data:       summaries,
+		TotalItems: totalItems,
+		TotalPages: (int(totalItems) + query.PageSize - 1) / query.PageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// GetCollection returns a collection and its games in order. Collections
+// outside their schedule are only visible to admins.
+func (cs *CurationService) GetCollection(slug, userId string) (*types.CollectionResponse, error) {
+	var collection models.Collection
+	if err := cs.databaseHandler.DB.First(&collection, "slug = ?", slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: collection not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if !isLive(collection.StartsAt, collection.EndsAt, time.Now()) {
+		admin, err := isAdmin(cs.databaseHandler.DB, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, fmt.Errorf("%w: collection not found", types.ErrNotFound)
+		}
+	}
+
+	games, err := cs.collectionGames(cs.databaseHandler.DB, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CollectionResponse{Collection: collection, Games: GameSummaries(cs.storage, games)}, nil
+}
+
+func (cs *CurationService) CreateCollection(userId string, req types.CreateCollectionRequest) (*types.CollectionResponse, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Title)
+	}
+	if !slugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%w: slug must be lowercase letters, digits and single dashes", types.ErrInvalidInput)
+	}
+	if err := validateSchedule(req.StartsAt, req.EndsAt); err != nil {
+		return nil, err
+	}
+
+	collection := models.Collection{
+		Slug:        slug,
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		CreatedBy:   userId,
+	}
+
+	var games []models.Game
+	err := cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.Collection{}).Where("slug = ?", slug).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: a collection with slug %s already exists", types.ErrConflict, slug)
+		}
+
+		if err := tx.Create(&collection).Error; err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+
+		var err error
+		games, err = cs.replaceCollectionGames(tx, collection.ID, req.GameIDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CollectionResponse{Collection: collection, Games: GameSummaries(cs.storage, games)}, nil
+}
+
+func (cs *CurationService) UpdateCollection(slug string, req types.UpdateCollectionRequest) (*types.CollectionResponse, error) {
+	var collection models.Collection
+	var games []models.Game
+	err := cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := findCollection(tx, slug, &collection); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Title != nil {
+			collection.Title = strings.TrimSpace(*req.Title)
+			updates["title"] = collection.Title
+		}
+		if req.Description != nil {
+			collection.Description = *req.Description
+			updates["description"] = collection.Description
+		}
+		if req.StartsAt != nil {
+			collection.StartsAt = req.StartsAt
+			updates["starts_at"] = collection.StartsAt
+		}
+		if req.EndsAt != nil {
+			collection.EndsAt = req.EndsAt
+			updates["ends_at"] = collection.EndsAt
+		}
+		if err := validateSchedule(collection.StartsAt, collection.EndsAt); err != nil {
+			return err
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&collection).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update collection: %w", err)
+			}
+		}
+
+		var err error
+		games, err = cs.collectionGames(tx, collection.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CollectionResponse{Collection: collection, Games: GameSummaries(cs.storage, games)}, nil
+}
+
+// SetCollectionGames replaces the games of a collection, in the given order.
+func (cs *CurationService) SetCollectionGames(slug string, req types.SetCollectionGamesRequest) (*types.CollectionResponse, error) {
+	var collection models.Collection
+	var games []models.Game
+	err := cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := findCollection(tx, slug, &collection); err != nil {
+			return err
+		}
+
+		var err error
+		games, err = cs.replaceCollectionGames(tx, collection.ID, req.GameIDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CollectionResponse{Collection: collection, Games: GameSummaries(cs.storage, games)}, nil
+}
+
+func (cs *CurationService) DeleteCollection(slug string) error {
+	return cs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var collection models.Collection
+		if err := findCollection(tx, slug, &collection); err != nil {
+			return err
+		}
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	})
+}
+
+func (cs *CurationService) CreateFeaturedGame(userId string, req types.CreateFeaturedGameRequest) (featured models.FeaturedGame, err error) {
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if err := validateSchedule(&startsAt, req.EndsAt); err != nil {
+		return featured, err
+	}
+
+	var game models.Game
+	if err := cs.databaseHandler.DB.Scopes(database.ActiveGames).First(&game, "id = ?", req.GameID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return featured, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return featured, err
+	}
+
+	featured = models.FeaturedGame{
+		GameID:    req.GameID,
+		Priority:  req.Priority,
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: userId,
+	}
+	if err := cs.databaseHandler.DB.Create(&featured).Error; err != nil {
+		return featured, fmt.Errorf("failed to feature game: %w", err)
+	}
+	featured.Game = game
+
+	return featured, nil
+}
+
+func (cs *CurationService) ListFeaturedGames(query types.ListFeaturedGamesQuery) (*types.PaginatedResponse, error) {
+	now := time.Now()
+	filter := func(db *gorm.DB) *gorm.DB {
+		switch query.Status {
+		case "active":
+			return db.Scopes(liveFilter(now))
+		case "scheduled":
+			return db.Where("starts_at > ?", now)
+		case "expired":
+			return db.Where("ends_at <= ?", now)
+		}
+		return db
+	}
+
+	var totalItems int64
+	if err := cs.databaseHandler.DB.Model(&models.FeaturedGame{}).Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count featured games: %w", err)
+	}
+
+	var featured []models.FeaturedGame
+	offset := (query.Page - 1) * query.PageSize
+	if err := cs.databaseHandler.DB.Scopes(filter).
+		Preload("Game").
+		Order("starts_at DESC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&featured).Error; err != nil {
+		return nil, fmt.Errorf("failed to get featured games: %w", err)
+	}
+
+	return &types.PaginatedResponse{
+		Data:       featured,
+		TotalItems: totalItems,
+		TotalPages: (int(totalItems) + query.PageSize - 1) / query.PageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+func (cs *CurationService) DeleteFeaturedGame(featuredId string) error {
+	result := cs.databaseHandler.DB.Delete(&models.FeaturedGame{}, "id = ?", featuredId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: featured game not found", types.ErrNotFound)
+	}
+	return nil
+}
+
+// InjectFeatured places the currently featured games into the configured
+// slots of the first feed page, highest priority first, and leaves them out
+// of every other page. A featured game that is already in the feed is moved
+// to its slot rather than shown twice. The first page keeps its size, so the
+// games pushed past limit are dropped, and slots past the end of the feed are
+// left empty.
+func (cs *CurationService) InjectFeatured(feed []models.Game, device capability.Device, page, limit int) ([]models.Game, error) {
+	if len(cs.featuredSlots) == 0 {
+		return feed, nil
+	}
+
+	picks, err := cs.liveFeatured(device)
+	if err != nil {
+		return nil, err
+	}
+	if len(picks) == 0 {
+		return feed, nil
+	}
+
+	picked := make(map[string]bool, len(picks))
+	for _, game := range picks {
+		picked[game.ID] = true
+	}
+	result := make([]models.Game, 0, len(feed)+len(picks))
+	for _, game := range feed {
+		if !picked[game.ID] {
+			result = append(result, game)
+		}
+	}
+	if page != 1 {
+		return result, nil
+	}
+
+	for i, game := range picks {
+		slot := cs.featuredSlots[i] - 1
+		if slot > len(result) || slot >= limit {
+			break
+		}
+		result = append(result[:slot], append([]models.Game{game}, result[slot:]...)...)
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// liveFeatured returns the games that fill the featured slots right now.
+func (cs *CurationService) liveFeatured(device capability.Device) ([]models.Game, error) {
+	var featured []models.FeaturedGame
+	if err := cs.databaseHandler.DB.Model(&models.FeaturedGame{}).
+		Scopes(liveFilter(time.Now())).
+		Joins("Game").
+		Where(database.ActiveGameFilter(`"Game"`)).
+		Scopes(func(db *gorm.DB) *gorm.DB {
+			if playable := device.PlayableFilter(`"Game"`); playable != "" {
+				return db.Where(playable)
+			}
+			return db
+		}).
+		Order("priority DESC, starts_at DESC").
+		Find(&featured).Error; err != nil {
+		return nil, fmt.Errorf("failed to get featured games: %w", err)
+	}
+
+	var picks []models.Game
+	picked := make(map[string]bool)
+	for _, entry := range featured {
+		if len(picks) == len(cs.featuredSlots) {
+			break
+		}
+		if picked[entry.GameID] {
+			continue
+		}
+		picked[entry.GameID] = true
+		game := entry.Game
+		game.IsFeatured = true
+		picks = append(picks, game)
+	}
+	return picks, nil
+}
+
+func (cs *CurationService) collectionGames(tx *gorm.DB, collectionId string) ([]models.Game, error) {
+	var games []models.Game
+	if err := tx.Scopes(database.ActiveGames).
+		Joins("JOIN collection_items ON collection_items.game_id = games.id").
+		Where("collection_items.collection_id = ?", collectionId).
+		Order("collection_items.position ASC").
+		Preload("Genre").
+		Find(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collection games: %w", err)
+	}
+	return games, nil
+}
+
+func (cs *CurationService) replaceCollectionGames(tx *gorm.DB, collectionId string, gameIds []string) ([]models.Game, error) {
+	seen := make(map[string]bool, len(gameIds))
+	items := make([]models.CollectionItem, 0, len(gameIds))
+	for _, id := range gameIds {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: game %s is listed more than once", types.ErrInvalidInput, id)
+		}
+		seen[id] = true
+		items = append(items, models.CollectionItem{CollectionID: collectionId, GameID: id, Position: len(items)})
+	}
+
+	if len(gameIds) > 0 {
+		var found int64
+		if err := tx.Model(&models.Game{}).Scopes(database.ActiveGames).Where("id IN ?", gameIds).Count(&found).Error; err != nil {
+			return nil, err
+		}
+		if int(found) != len(gameIds) {
+			return nil, fmt.Errorf("%w: some games do not exist", types.ErrInvalidInput)
+		}
+	}
+
+	if err := tx.Where("collection_id = ?", collectionId).Delete(&models.CollectionItem{}).Error; err != nil {
+		return nil, err
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			return nil, fmt.Errorf("failed to add games to collection: %w", err)
+		}
+	}
+
+	return cs.collectionGames(tx, collectionId)
+}
+
+func findCollection(tx *gorm.DB, slug string, collection *models.Collection) error {
+	if err := tx.First(collection, "slug = ?", slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: collection not found", types.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+func validateSchedule(startsAt, endsAt *time.Time) error {
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", types.ErrInvalidInput)
+	}
+	return nil
+}
+
+func isLive(startsAt, endsAt *time.Time, now time.Time) bool {
+	return (startsAt == nil || !startsAt.After(now)) && (endsAt == nil || endsAt.After(now))
+}
+
+func slugify(title string) string {
+	return strings.Trim(slugUnsafeChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
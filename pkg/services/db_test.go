@@ -0,0 +1,77 @@
+package services
+
+import (
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"testing"
+)
+
+// testDB connects to the Postgres database in TEST_DATABASE_URL, skipping the
+// test when there is none. Each test gets its own transaction, rolled back
+// when the test ends, so tests can share a database.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Genre{},
+		&models.Tag{},
+		&models.Game{},
+		&models.Like{},
+		&models.Comment{},
+		&models.Bookmark{},
+		&models.RecentlyPlayed{},
+		&models.UserGameInteraction{},
+		&models.PlaySession{},
+		&models.GameSimilarity{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, uid string) models.User {
+	t.Helper()
+	user := models.User{UID: uid, Email: uid + "@example.com", Username: uid}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func createTestGame(t *testing.T, db *gorm.DB, genreId, title string) models.Game {
+	t.Helper()
+	game := models.Game{Title: title, GenreID: genreId, GameType: models.GameTypeHTML5}
+	if err := db.Create(&game).Error; err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	return game
+}
+
+func createTestGenre(t *testing.T, db *gorm.DB, name string) models.Genre {
+	t.Helper()
+	genre := models.Genre{Name: name}
+	if err := db.Create(&genre).Error; err != nil {
+		t.Fatalf("failed to create genre: %v", err)
+	}
+	return genre
+}
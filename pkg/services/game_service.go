@@ -1,36 +1,132 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/imaging"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
 	"github.com/PixelzOrg/PHOLE.git/pkg/utils/supabase"
-	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+const MaxThumbnailSize = 5 << 20
+
+// thumbnailWidths are the variant sizes of uploaded thumbnails, covering
+// feed cards up to the full-width game page.
+var thumbnailWidths = []int{320, 640, 1280}
+
+var thumbnailExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+	"image/gif":  "gif",
+}
+
 type GameService struct {
 	databaseHandler database.Handler
 	supabaseAuth    *supabase.SupabaseAuth
-	algoliaClient   *search.Client
+	searchBackend   gamesearch.Backend
+	storage         storage.Backend
 }
 
-func NewGameService(databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth, algoliaClient *search.Client) *GameService {
+func NewGameService(databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth, searchBackend gamesearch.Backend, storageBackend storage.Backend) *GameService {
 	return &GameService{
 		databaseHandler: databaseHandler,
 		supabaseAuth:    supabaseAuth,
-		algoliaClient:   algoliaClient,
+		searchBackend:   searchBackend,
+		storage:         storageBackend,
 	}
 }
 
 func (gs *GameService) GameDetailsByGameId(gameId string) (game models.Game, err error) {
-	err = gs.databaseHandler.DB.Where("id = ?", gameId).First(&game).Error
+	err = gs.databaseHandler.DB.Scopes(database.ActiveGames).Where("id = ?", gameId).First(&game).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return game, fmt.Errorf("%w: game not found", types.ErrNotFound)
+	}
 	return game, err
 }
 
-func (gs *GameService) CreateInteractionByGameId(gameId string, userId string, interactionType string) (err error) {
+// ControlSchemeByGameId returns the game's current control scheme, or nil if
+// it has none.
+func (gs *GameService) ControlSchemeByGameId(gameId string) (*models.ControlScheme, error) {
+	return currentControlScheme(gs.databaseHandler.DB, gameId)
+}
+
+func (gs *GameService) SearchGames(ctx context.Context, query types.SearchGamesQuery) (*types.PaginatedResponse, error) {
+	result, err := gs.searchBackend.Search(ctx, gamesearch.Query{
+		Text:        strings.TrimSpace(query.Query),
+		GenreID:     query.GenreID,
+		Tag:         NormalizeTagName(query.Tag),
+		GameType:    query.GameType,
+		IsLandscape: query.IsLandscape,
+		Device:      query.Device,
+		Page:        query.Page,
+		PageSize:    query.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.GameID)
+	}
+
+	var games []models.Game
+	if len(ids) > 0 {
+		if err := gs.databaseHandler.DB.Scopes(database.ActiveGames).
+			Preload("Genre").
+			Preload("Tags").
+			Where("id IN ?", ids).
+			Find(&games).Error; err != nil {
+			return nil, fmt.Errorf("failed to load search results: %w", err)
+		}
+	}
+
+	byId := make(map[string]models.Game, len(games))
+	for _, game := range games {
+		byId[game.ID] = game
+	}
+
+	results := make([]types.SearchResult, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		game, ok := byId[hit.GameID]
+		if !ok {
+			continue
+		}
+		results = append(results, types.SearchResult{Game: game, Thumbnail: gs.Thumbnail(game), Highlights: hit.Highlights})
+	}
+
+	totalPages := (int(result.Total) + query.PageSize - 1) / query.PageSize
+
+	return &types.PaginatedResponse{
+		Data:       results,
+		TotalItems: result.Total,
+		TotalPages: totalPages,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// CreateInteractionByGameId records an interaction and bumps the matching
+// counter. Likes and bookmarks are idempotent: repeating one is a no-op and
+// reports created as false. A play starts a play session, and is a no-op
+// while the user has a live one for the game.
+func (gs *GameService) CreateInteractionByGameId(gameId string, userId string, interactionType string) (created bool, err error) {
 	tx := gs.databaseHandler.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -39,9 +135,12 @@ func (gs *GameService) CreateInteractionByGameId(gameId string, userId string, i
 	}()
 
 	var game models.Game
-	if err := tx.First(&game, "id = ?", gameId).Error; err != nil {
+	if err := tx.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
 		tx.Rollback()
-		return err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return false, err
 	}
 
 	switch interactionType {
@@ -50,14 +149,23 @@ func (gs *GameService) CreateInteractionByGameId(gameId string, userId string, i
 			GameID: gameId,
 			UserID: userId,
 		}
-		if err := tx.Create(&like).Error; err != nil {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		if result.Error != nil {
 			tx.Rollback()
-			return err
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return false, nil
 		}
 
 		if err := tx.Model(&game).Update("like_count", gorm.Expr("like_count + ?", 1)).Error; err != nil {
 			tx.Rollback()
-			return err
+			return false, err
+		}
+		if err := analytics.Record(tx, gameId, like.CreatedAt, analytics.Delta{Likes: 1}); err != nil {
+			tx.Rollback()
+			return false, err
 		}
 
 	case "bookmark":
@@ -65,57 +173,520 @@ func (gs *GameService) CreateInteractionByGameId(gameId string, userId string, i
 			GameID: gameId,
 			UserID: userId,
 		}
-		if err := tx.Create(&bookmark).Error; err != nil {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark)
+		if result.Error != nil {
 			tx.Rollback()
-			return err
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return false, nil
 		}
 		if err := tx.Model(&game).Update("bookmark_count", gorm.Expr("bookmark_count + ?", 1)).Error; err != nil {
 			tx.Rollback()
-			return err
+			return false, err
+		}
+		if err := analytics.Record(tx, gameId, bookmark.CreatedAt, analytics.Delta{Bookmarks: 1}); err != nil {
+			tx.Rollback()
+			return false, err
 		}
 
 	case "play":
-		var recentlyPlayed models.RecentlyPlayed
-		result := tx.Where("game_id = ? AND user_id = ?", gameId, userId).First(&recentlyPlayed)
+		// Plays go through a session, so a client that reports a play and
+		// also starts a session is only counted once.
+		_, started, err := startSession(tx, &game, userId, "")
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if !started {
+			tx.Rollback()
+			return false, nil
+		}
 
-		if result.Error != nil {
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				recentlyPlayed = models.RecentlyPlayed{
-					GameID:    gameId,
-					UserID:    userId,
-					PlayCount: 1,
-				}
-				if err := tx.Create(&recentlyPlayed).Error; err != nil {
-					tx.Rollback()
-					return err
-				}
-			} else {
-				tx.Rollback()
-				return result.Error
+	default:
+		tx.Rollback()
+		return false, fmt.Errorf("%w: invalid interaction type: %s", types.ErrInvalidInput, interactionType)
+	}
+
+	if err := gamesearch.Enqueue(tx, gameId); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// recordPlay counts one play of the game by the user in RecentlyPlayed, on
+// the game itself and in the daily rollup.
+func recordPlay(tx *gorm.DB, game *models.Game, userId string) error {
+	var recentlyPlayed models.RecentlyPlayed
+	result := tx.Where("game_id = ? AND user_id = ?", game.ID, userId).First(&recentlyPlayed)
+
+	if result.Error != nil {
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return result.Error
+		}
+		recentlyPlayed = models.RecentlyPlayed{
+			GameID:    game.ID,
+			UserID:    userId,
+			PlayCount: 1,
+		}
+		if err := tx.Create(&recentlyPlayed).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := tx.Model(&recentlyPlayed).Updates(map[string]interface{}{
+			"last_played_at": time.Now(),
+			"play_count":     gorm.Expr("play_count + ?", 1),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(game).Update("play_count", gorm.Expr("play_count + ?", 1)).Error; err != nil {
+		return err
+	}
+
+	return analytics.RecordPlay(tx, game.ID, userId, time.Now())
+}
+
+// DeleteInteractionByGameId undoes a like or bookmark and decrements the
+// matching counter. Undoing something that was never recorded is a no-op and
+// reports removed as false. Plays can't be undone.
+func (gs *GameService) DeleteInteractionByGameId(gameId string, userId string, interactionType string) (removed bool, err error) {
+	var model interface{}
+	var counter string
+	var delta analytics.Delta
+	switch interactionType {
+	case "like":
+		model, counter, delta = &models.Like{}, "like_count", analytics.Delta{Likes: -1}
+	case "bookmark":
+		model, counter, delta = &models.Bookmark{}, "bookmark_count", analytics.Delta{Bookmarks: -1}
+	default:
+		return false, fmt.Errorf("%w: cannot undo interaction type: %s", types.ErrInvalidInput, interactionType)
+	}
+
+	err = gs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
 			}
-		} else {
-			if err := tx.Model(&recentlyPlayed).Updates(map[string]interface{}{
-				"last_played_at": time.Now(),
-				"play_count":     gorm.Expr("play_count + ?", 1),
-			}).Error; err != nil {
-				tx.Rollback()
+			return err
+		}
+
+		var createdAt []time.Time
+		if err := tx.Model(model).Where("game_id = ? AND user_id = ?", gameId, userId).
+			Pluck("created_at", &createdAt).Error; err != nil {
+			return err
+		}
+		if len(createdAt) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("game_id = ? AND user_id = ?", gameId, userId).Delete(model).Error; err != nil {
+			return err
+		}
+		removed = true
+
+		if err := tx.Model(&game).Update(counter, gorm.Expr("GREATEST("+counter+" - 1, 0)")).Error; err != nil {
+			return err
+		}
+		// Take it off the day it was counted on, not today.
+		if err := analytics.Record(tx, gameId, createdAt[0], delta); err != nil {
+			return err
+		}
+
+		return gamesearch.Enqueue(tx, gameId)
+	})
+
+	return removed, err
+}
+
+func (gs *GameService) CreateGame(userId string, req types.CreateGameRequest) (game models.Game, err error) {
+	if err = ValidateEmbedLink(req.EmbedLink); err != nil {
+		return game, err
+	}
+
+	err = gs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.User{}, "uid = ?", userId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: creator profile does not exist", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := ensureGenreExists(tx, req.GenreID); err != nil {
+			return err
+		}
+
+		tags, err := findOrCreateTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+
+		game = models.Game{
+			Title:       strings.TrimSpace(req.Title),
+			Description: req.Description,
+			EmbedLink:   req.EmbedLink,
+			GameType:    req.GameType,
+			GenreID:     req.GenreID,
+			IsLandscape: *req.IsLandscape,
+			IsClaimed:   true,
+			CreatorID:   &userId,
+			Tags:        tags,
+		}
+		game.TouchSupport, game.GamepadSupport = models.DefaultInputSupport(req.GameType)
+		if req.TouchSupport != nil {
+			game.TouchSupport = *req.TouchSupport
+		}
+		if req.GamepadSupport != nil {
+			game.GamepadSupport = *req.GamepadSupport
+		}
+		if err := tx.Create(&game).Error; err != nil {
+			return fmt.Errorf("failed to create game: %w", err)
+		}
+		if err := gamesearch.Enqueue(tx, game.ID); err != nil {
+			return err
+		}
+
+		return tx.Preload("Genre").Preload("Tags").First(&game, "id = ?", game.ID).Error
+	})
+
+	return game, err
+}
+
+func (gs *GameService) UpdateGameByGameId(gameId, userId string, req types.UpdateGameRequest) (game models.Game, err error) {
+	if req.EmbedLink != nil {
+		if err = ValidateEmbedLink(*req.EmbedLink); err != nil {
+			return game, err
+		}
+	}
+
+	err = gs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Title != nil {
+			updates["title"] = strings.TrimSpace(*req.Title)
+		}
+		if req.Description != nil {
+			updates["description"] = *req.Description
+		}
+		if req.EmbedLink != nil {
+			updates["embed_link"] = *req.EmbedLink
+		}
+		if req.GameType != nil {
+			updates["game_type"] = *req.GameType
+		}
+		if req.GenreID != nil {
+			if err := ensureGenreExists(tx, *req.GenreID); err != nil {
 				return err
 			}
+			updates["genre_id"] = *req.GenreID
+		}
+		if req.IsLandscape != nil {
+			updates["is_landscape"] = *req.IsLandscape
+		}
+		if req.TouchSupport != nil {
+			updates["touch_support"] = *req.TouchSupport
+		}
+		if req.GamepadSupport != nil {
+			updates["gamepad_support"] = *req.GamepadSupport
 		}
 
-		if err := tx.Model(&game).Update("play_count", gorm.Expr("play_count + ?", 1)).Error; err != nil {
-			tx.Rollback()
+		if len(updates) > 0 {
+			if err := tx.Model(&game).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update game: %w", err)
+			}
+		}
+
+		if req.Tags != nil {
+			tags, err := findOrCreateTags(tx, *req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&game).Association("Tags").Replace(tags); err != nil {
+				return fmt.Errorf("failed to update game tags: %w", err)
+			}
+		}
+
+		if err := gamesearch.Enqueue(tx, gameId); err != nil {
 			return err
 		}
 
-	default:
-		tx.Rollback()
-		return fmt.Errorf("invalid interaction type: %s", interactionType)
+		return tx.Preload("Genre").Preload("Tags").First(&game, "id = ?", gameId).Error
+	})
+
+	return game, err
+}
+
+// UploadThumbnailByGameId validates the image, stores a WebP and a JPEG
+// variant per thumbnailWidths entry and swaps the game over to them.
+// ThumbnailFileName keeps pointing at the largest JPEG for clients that read
+// it directly.
+func (gs *GameService) UploadThumbnailByGameId(ctx context.Context, gameId, userId string, file io.Reader) (game models.Game, err error) {
+	if err = findManagedGame(gs.databaseHandler.DB, gameId, userId, &game); err != nil {
+		return game, err
 	}
 
-	if err := tx.Commit().Error; err != nil {
+	data, _, _, err := readImage(file)
+	if err != nil {
+		return game, err
+	}
+	processed, err := imaging.Process(data, thumbnailWidths)
+	if errors.Is(err, imaging.ErrInvalidImage) {
+		return game, fmt.Errorf("%w: %v", types.ErrInvalidInput, err)
+	}
+	if err != nil {
+		return game, err
+	}
+
+	prefix := fmt.Sprintf("thumbnails/%s/%s", gameId, uuid.NewString())
+	variants := make(models.ImageVariants, 0, len(processed.Variants))
+	var fallback string
+	for _, variant := range processed.Variants {
+		key := fmt.Sprintf("%s/%d.%s", prefix, variant.Width, thumbnailExtensions[variant.ContentType])
+		if err = gs.storage.Put(ctx, key, bytes.NewReader(variant.Data), variant.ContentType); err != nil {
+			deleteThumbnailVariants(ctx, gs.storage, variants)
+			return game, fmt.Errorf("failed to store thumbnail: %w", err)
+		}
+		variants = append(variants, models.ImageVariant{
+			Key:         key,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			ContentType: variant.ContentType,
+		})
+		if variant.ContentType == "image/jpeg" {
+			fallback = key
+		}
+	}
+
+	previousFile, previousVariants := game.ThumbnailFileName, game.ThumbnailVariants
+	updates := map[string]interface{}{
+		"thumbnail_file_name": fallback,
+		"thumbnail_variants":  variants,
+		"thumbnail_blurhash":  processed.Blurhash,
+		"thumbnail_color":     processed.DominantColor,
+	}
+	if err = gs.databaseHandler.DB.Model(&game).Updates(updates).Error; err != nil {
+		deleteThumbnailVariants(ctx, gs.storage, variants)
+		return game, fmt.Errorf("failed to update thumbnail: %w", err)
+	}
+	game.ThumbnailFileName = fallback
+	game.ThumbnailVariants = variants
+	game.ThumbnailBlurhash = processed.Blurhash
+	game.ThumbnailColor = processed.DominantColor
+
+	deleteThumbnailVariants(ctx, gs.storage, previousVariants)
+	if len(previousVariants) == 0 && strings.HasPrefix(previousFile, "thumbnails/") {
+		gs.storage.Delete(ctx, previousFile)
+	}
+
+	return game, nil
+}
+
+func deleteThumbnailVariants(ctx context.Context, backend storage.Backend, variants models.ImageVariants) {
+	for _, variant := range variants {
+		backend.Delete(ctx, variant.Key)
+	}
+}
+
+// DeleteGameByGameId soft deletes a game. Likes, bookmarks, comments and
+// their counters are left untouched so a restore brings the game back exactly
+// as it was; while deleted the game is hidden from every read path and new
+// interactions are rejected.
+func (gs *GameService) DeleteGameByGameId(gameId, userId string) error {
+	return gs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if err := tx.Model(&game).Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		return gamesearch.Enqueue(tx, gameId)
+	})
+}
+
+func (gs *GameService) RestoreGameByGameId(gameId, userId string) error {
+	return gs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Where("is_deleted = true").First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: deleted game not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := authorizeGameManager(tx, game, userId); err != nil {
+			return err
+		}
+		if err := tx.Model(&game).Update("is_deleted", false).Error; err != nil {
+			return err
+		}
+		return gamesearch.Enqueue(tx, gameId)
+	})
+}
+
+// readImage reads an uploaded image of at most MaxThumbnailSize bytes and
+// sniffs its type.
+func readImage(file io.Reader) (data []byte, contentType, ext string, err error) {
+	data, err = io.ReadAll(io.LimitReader(file, MaxThumbnailSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > MaxThumbnailSize {
+		return nil, "", "", fmt.Errorf("%w: image exceeds %d bytes", types.ErrInvalidInput, MaxThumbnailSize)
+	}
+
+	contentType = http.DetectContentType(data)
+	ext, ok := thumbnailExtensions[contentType]
+	if !ok {
+		return nil, "", "", fmt.Errorf("%w: unsupported image type %s", types.ErrInvalidInput, contentType)
+	}
+	return data, contentType, ext, nil
+}
+
+func (gs *GameService) Thumbnail(game models.Game) *types.Thumbnail {
+	return ResolveThumbnail(gs.storage, game)
+}
+
+func (gs *GameService) Summaries(games []models.Game) []types.GameSummary {
+	return GameSummaries(gs.storage, games)
+}
+
+// GameSummaries resolves the thumbnails of games for a list response.
+func GameSummaries(backend storage.Backend, games []models.Game) []types.GameSummary {
+	summaries := make([]types.GameSummary, 0, len(games))
+	for _, game := range games {
+		summaries = append(summaries, types.GameSummary{Game: game, Thumbnail: ResolveThumbnail(backend, game)})
+	}
+	return summaries
+}
+
+// ResolveThumbnail resolves the game's thumbnail variants to URLs, or
+// returns nil if it has none.
+func ResolveThumbnail(backend storage.Backend, game models.Game) *types.Thumbnail {
+	if game.ThumbnailFileName == "" {
+		return nil
+	}
+
+	thumbnail := &types.Thumbnail{
+		URL:           backend.URL(game.ThumbnailFileName),
+		Blurhash:      game.ThumbnailBlurhash,
+		DominantColor: game.ThumbnailColor,
+	}
+	for _, variant := range game.ThumbnailVariants {
+		thumbnail.Variants = append(thumbnail.Variants, types.ThumbnailVariant{
+			URL:         backend.URL(variant.Key),
+			Width:       variant.Width,
+			Height:      variant.Height,
+			ContentType: variant.ContentType,
+		})
+	}
+	return thumbnail
+}
+
+// findManagedGame loads an active game that the user is allowed to manage,
+// which is either its creator or an admin.
+func findManagedGame(tx *gorm.DB, gameId, userId string, game *models.Game) error {
+	if err := tx.Scopes(database.ActiveGames).First(game, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
 		return err
 	}
+	return authorizeGameManager(tx, *game, userId)
+}
 
+func authorizeGameManager(tx *gorm.DB, game models.Game, userId string) error {
+	if game.CreatorID != nil && *game.CreatorID == userId {
+		return nil
+	}
+	admin, err := isAdmin(tx, userId)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("%w: only the creator or an admin can modify this game", types.ErrForbidden)
+	}
+	return nil
+}
+
+func isAdmin(tx *gorm.DB, userId string) (bool, error) {
+	var user models.User
+	if err := tx.Select("is_admin").First(&user, "uid = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+func ensureGenreExists(tx *gorm.DB, genreId string) error {
+	if err := tx.First(&models.Genre{}, "id = ?", genreId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: genre %s does not exist", types.ErrInvalidInput, genreId)
+		}
+		return err
+	}
+	return nil
+}
+
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag, _, err := findOrCreateTag(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// findOrCreateTag returns the tag with a normalized name. created reports
+// whether it had to be created.
+func findOrCreateTag(tx *gorm.DB, name string) (tag models.Tag, created bool, err error) {
+	err = tx.Where("LOWER(name) = ?", name).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tag, created = models.Tag{Name: name}, true
+		err = tx.Create(&tag).Error
+	}
+	if err != nil {
+		return tag, false, fmt.Errorf("failed to resolve tag %s: %w", name, err)
+	}
+	return tag, created, nil
+}
+
+// NormalizeTagName lowercases a tag and collapses runs of whitespace so that
+// "Pixel  Art" and "pixel art" end up as the same tag.
+func NormalizeTagName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// ValidateEmbedLink checks that a game is embedded from an https URL.
+func ValidateEmbedLink(link string) error {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: embed link is not a valid URL", types.ErrInvalidInput)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: embed link must use https", types.ErrInvalidInput)
+	}
 	return nil
 }
@@ -0,0 +1,578 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	leaderboardCacheKey = "leaderboard:%s:%s"
+	allTimePeriodKey    = "all"
+	// scoreSignatureMaxAge is how long a signed score can be submitted after
+	// it was signed, which limits replaying a captured submission.
+	scoreSignatureMaxAge = 5 * time.Minute
+)
+
+// Finished periods stay cached for a while so late readers of yesterday's
+// board don't all rebuild it.
+var leaderboardCacheTTLs = map[string]time.Duration{
+	models.LeaderboardPeriodDaily:  2 * 24 * time.Hour,
+	models.LeaderboardPeriodWeekly: 8 * 24 * time.Hour,
+}
+
+// cacheScoreScript adds a score to a period's sorted set only if the set is
+// there. A set that was dropped after a failure must come back whole from
+// loadLeaderboard; recreating it with one member would leave a partial board
+// that, with no TTL, never gets rebuilt. ARGV is GT or LT, the score, the
+// member and the TTL in seconds, 0 for none.
+var cacheScoreScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[1], ARGV[2], ARGV[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call("EXPIRE", KEYS[1], ARGV[4])
+end
+return 1
+`)
+
+// LeaderboardService keeps every player's best score per board and period in
+// Postgres and mirrors each period into a Redis sorted set for ranking. The
+// sorted sets are rebuilt from Postgres whenever they are missing.
+type LeaderboardService struct {
+	databaseHandler database.Handler
+	redisClient     *redis.Client
+}
+
+func NewLeaderboardService(databaseHandler database.Handler, redisClient *redis.Client) *LeaderboardService {
+	return &LeaderboardService{
+		databaseHandler: databaseHandler,
+		redisClient:     redisClient,
+	}
+}
+
+func (ls *LeaderboardService) ListLeaderboards(gameId string) (*types.LeaderboardsResponse, error) {
+	db := ls.databaseHandler.DB
+	if err := db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	leaderboards := []models.Leaderboard{}
+	if err := db.Where("game_id = ?", gameId).Order("created_at ASC").Find(&leaderboards).Error; err != nil {
+		return nil, err
+	}
+	return &types.LeaderboardsResponse{Leaderboards: leaderboards}, nil
+}
+
+func (ls *LeaderboardService) CreateLeaderboard(gameId, userId string, req types.CreateLeaderboardRequest) (*models.Leaderboard, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !slugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%w: slug must be lowercase letters, digits and single dashes", types.ErrInvalidInput)
+	}
+
+	leaderboard := models.Leaderboard{
+		GameID:           gameId,
+		Slug:             slug,
+		Name:             req.Name,
+		Order:            req.Order,
+		Period:           req.Period,
+		RequireSignature: req.RequireSignature,
+	}
+	if leaderboard.Order == "" {
+		leaderboard.Order = models.LeaderboardOrderDesc
+	}
+	if leaderboard.Period == "" {
+		leaderboard.Period = models.LeaderboardPeriodAllTime
+	}
+
+	err := ls.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Leaderboard{}).Where("game_id = ? AND slug = ?", gameId, slug).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: the game already has a leaderboard with slug %s", types.ErrConflict, slug)
+		}
+
+		if err := tx.Create(&leaderboard).Error; err != nil {
+			return fmt.Errorf("failed to create leaderboard: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &leaderboard, nil
+}
+
+// UpdateLeaderboard renames a board or changes whether it needs signed
+// scores. Order and period can't change, since the stored scores depend on
+// them.
+func (ls *LeaderboardService) UpdateLeaderboard(gameId, slug, userId string, req types.UpdateLeaderboardRequest) (*models.Leaderboard, error) {
+	var leaderboard models.Leaderboard
+	err := ls.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if err := findLeaderboard(tx, gameId, slug, &leaderboard); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Name != nil {
+			leaderboard.Name = *req.Name
+			updates["name"] = leaderboard.Name
+		}
+		if req.RequireSignature != nil {
+			leaderboard.RequireSignature = *req.RequireSignature
+			updates["require_signature"] = leaderboard.RequireSignature
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&leaderboard).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update leaderboard: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &leaderboard, nil
+}
+
+func (ls *LeaderboardService) DeleteLeaderboard(ctx context.Context, gameId, slug, userId string) error {
+	var leaderboard models.Leaderboard
+	err := ls.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if err := findLeaderboard(tx, gameId, slug, &leaderboard); err != nil {
+			return err
+		}
+		if err := tx.Where("leaderboard_id = ?", leaderboard.ID).Delete(&models.LeaderboardScore{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&leaderboard).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	iter := ls.redisClient.Scan(ctx, 0, fmt.Sprintf(leaderboardCacheKey, leaderboard.ID, "*"), 100).Iterator()
+	for iter.Next(ctx) {
+		ls.redisClient.Del(ctx, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Warn().Err(err).Str("leaderboard", leaderboard.ID).Msg("Failed to clear cached leaderboard")
+	}
+	return nil
+}
+
+// ScoreSecret returns the game's score secret, creating one the first time.
+func (ls *LeaderboardService) ScoreSecret(gameId, userId string) (*types.ScoreSecretResponse, error) {
+	var secret string
+	err := ls.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+		if game.ScoreSecret != "" {
+			secret = game.ScoreSecret
+			return nil
+		}
+
+		var err error
+		secret, err = setScoreSecret(tx, gameId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ScoreSecretResponse{Secret: secret}, nil
+}
+
+// RotateScoreSecret replaces the game's score secret. Scores signed with the
+// old one are rejected from then on.
+func (ls *LeaderboardService) RotateScoreSecret(gameId, userId string) (*types.ScoreSecretResponse, error) {
+	var secret string
+	err := ls.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		var err error
+		secret, err = setScoreSecret(tx, gameId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ScoreSecretResponse{Secret: secret}, nil
+}
+
+// SubmitScore records a score for the current period and keeps it if it beats
+// the player's best. A signature is checked whenever one is sent, and required
+// on boards that ask for it.
+func (ls *LeaderboardService) SubmitScore(ctx context.Context, gameId, slug, userId string, req types.SubmitScoreRequest) (*types.SubmitScoreResponse, error) {
+	var leaderboard models.Leaderboard
+	var best models.LeaderboardScore
+	var isNewRecord bool
+	now := time.Now()
+	var periodKey string
+
+	err := ls.databaseHandler.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := findLeaderboard(tx, gameId, slug, &leaderboard); err != nil {
+			return err
+		}
+
+		if req.Signature != "" || leaderboard.RequireSignature {
+			if err := verifyScoreSignature(game.ScoreSecret, slug, userId, *req.Score, req.Timestamp, req.Signature, now); err != nil {
+				return err
+			}
+		}
+
+		periodKey = leaderboardPeriodKey(leaderboard, now)
+
+		// Only overwrite the stored score when the new one is better.
+		better := "leaderboard_scores.score < EXCLUDED.score"
+		if leaderboard.Order == models.LeaderboardOrderAsc {
+			better = "leaderboard_scores.score > EXCLUDED.score"
+		}
+		result := tx.Exec(`
+			INSERT INTO leaderboard_scores (leaderboard_id, period_key, user_id, score, created_at, updated_at)
+			VALUES (?, ?, ?, ?, NOW(), NOW())
+			ON CONFLICT (leaderboard_id, period_key, user_id) DO UPDATE SET
+				score = EXCLUDED.score,
+				updated_at = NOW()
+			WHERE `+better,
+			leaderboard.ID, periodKey, userId, *req.Score,
+		)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record score: %w", result.Error)
+		}
+		isNewRecord = result.RowsAffected > 0
+
+		return tx.Preload("User").First(&best, "leaderboard_id = ? AND period_key = ? AND user_id = ?",
+			leaderboard.ID, periodKey, userId).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ls.loadLeaderboard(ctx, leaderboard, periodKey)
+	if err == nil && isNewRecord {
+		var cached bool
+		cached, err = ls.cacheScore(ctx, leaderboard, key, best.UserID, best.Score)
+		if err == nil && !cached {
+			// The set was dropped since it was loaded; the rebuild reads
+			// the new best from Postgres.
+			_, err = ls.loadLeaderboard(ctx, leaderboard, periodKey)
+		}
+	}
+	var rank int64
+	if err == nil {
+		rank, err = ls.rank(ctx, leaderboard, key, userId)
+	}
+	if err != nil {
+		// The score is safe in Postgres; drop the sorted set so the next
+		// read rebuilds it.
+		log.Error().Err(err).Str("leaderboard", leaderboard.ID).Msg("Failed to cache leaderboard score")
+		ls.redisClient.Del(ctx, key)
+	}
+
+	return &types.SubmitScoreResponse{
+		Best:        leaderboardEntry(rank, best.UserID, best.User, best.Score),
+		IsNewRecord: isNewRecord,
+	}, nil
+}
+
+// TopScores returns the best scores of the current period.
+func (ls *LeaderboardService) TopScores(ctx context.Context, gameId, slug string, limit int) (*types.LeaderboardScoresResponse, error) {
+	leaderboard, periodKey, key, err := ls.currentLeaderboard(ctx, gameId, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ls.entries(ctx, leaderboard, key, 0, int64(limit-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LeaderboardScoresResponse{Leaderboard: leaderboard, PeriodKey: periodKey, Entries: entries}, nil
+}
+
+// ScoresAroundUser returns the user's entry in the current period with up to
+// radius entries on either side.
+func (ls *LeaderboardService) ScoresAroundUser(ctx context.Context, gameId, slug, userId string, radius int) (*types.LeaderboardScoresResponse, error) {
+	leaderboard, periodKey, key, err := ls.currentLeaderboard(ctx, gameId, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	rank, err := ls.rank(ctx, leaderboard, key, userId)
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: you have no score on this leaderboard yet", types.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank score: %w", err)
+	}
+
+	start := rank - 1 - int64(radius)
+	if start < 0 {
+		start = 0
+	}
+	entries, err := ls.entries(ctx, leaderboard, key, start, rank-1+int64(radius))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LeaderboardScoresResponse{Leaderboard: leaderboard, PeriodKey: periodKey, Entries: entries}, nil
+}
+
+func (ls *LeaderboardService) currentLeaderboard(ctx context.Context, gameId, slug string) (leaderboard models.Leaderboard, periodKey, key string, err error) {
+	db := ls.databaseHandler.DB.WithContext(ctx)
+	if err = db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return
+	}
+	if err = findLeaderboard(db, gameId, slug, &leaderboard); err != nil {
+		return
+	}
+
+	periodKey = leaderboardPeriodKey(leaderboard, time.Now())
+	key, err = ls.loadLeaderboard(ctx, leaderboard, periodKey)
+	if err != nil {
+		err = fmt.Errorf("failed to load leaderboard: %w", err)
+	}
+	return
+}
+
+// loadLeaderboard makes sure the period's sorted set is in Redis and returns
+// its key. Only players' bests are ever added, so rebuilding while scores
+// come in can't lower anyone's cached score.
+func (ls *LeaderboardService) loadLeaderboard(ctx context.Context, leaderboard models.Leaderboard, periodKey string) (string, error) {
+	key := fmt.Sprintf(leaderboardCacheKey, leaderboard.ID, periodKey)
+
+	exists, err := ls.redisClient.Exists(ctx, key).Result()
+	if err != nil || exists > 0 {
+		return key, err
+	}
+
+	var scores []models.LeaderboardScore
+	if err := ls.databaseHandler.DB.WithContext(ctx).Select("user_id", "score").
+		Where("leaderboard_id = ? AND period_key = ?", leaderboard.ID, periodKey).
+		Find(&scores).Error; err != nil {
+		return key, err
+	}
+	if len(scores) == 0 {
+		return key, nil
+	}
+
+	members := make([]redis.Z, 0, len(scores))
+	for _, score := range scores {
+		members = append(members, redis.Z{Score: float64(score.Score), Member: score.UserID})
+	}
+
+	pipe := ls.redisClient.TxPipeline()
+	if leaderboard.Order == models.LeaderboardOrderAsc {
+		pipe.ZAddLT(ctx, key, members...)
+	} else {
+		pipe.ZAddGT(ctx, key, members...)
+	}
+	if ttl, ok := leaderboardCacheTTLs[leaderboard.Period]; ok {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return key, err
+}
+
+// cacheScore adds a new best to the period's sorted set and reports whether
+// the set was there to add it to.
+func (ls *LeaderboardService) cacheScore(ctx context.Context, leaderboard models.Leaderboard, key, userId string, score int64) (bool, error) {
+	mode := "GT"
+	if leaderboard.Order == models.LeaderboardOrderAsc {
+		mode = "LT"
+	}
+	ttl := int64(leaderboardCacheTTLs[leaderboard.Period] / time.Second)
+
+	added, err := cacheScoreScript.Run(ctx, ls.redisClient, []string{key}, mode, score, userId, ttl).Int()
+	return added == 1, err
+}
+
+// rank is the user's 1-based position on the board.
+func (ls *LeaderboardService) rank(ctx context.Context, leaderboard models.Leaderboard, key, userId string) (int64, error) {
+	var rank int64
+	var err error
+	if leaderboard.Order == models.LeaderboardOrderAsc {
+		rank, err = ls.redisClient.ZRank(ctx, key, userId).Result()
+	} else {
+		rank, err = ls.redisClient.ZRevRank(ctx, key, userId).Result()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rank + 1, nil
+}
+
+// entries returns the ranked entries between two 0-based positions, inclusive.
+func (ls *LeaderboardService) entries(ctx context.Context, leaderboard models.Leaderboard, key string, start, stop int64) ([]types.LeaderboardEntry, error) {
+	var members []redis.Z
+	var err error
+	if leaderboard.Order == models.LeaderboardOrderAsc {
+		members, err = ls.redisClient.ZRangeWithScores(ctx, key, start, stop).Result()
+	} else {
+		members, err = ls.redisClient.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %w", err)
+	}
+
+	userIds := make([]string, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.Member.(string))
+	}
+	usersById := make(map[string]models.User, len(userIds))
+	if len(userIds) > 0 {
+		var users []models.User
+		if err := ls.databaseHandler.DB.WithContext(ctx).Where("uid IN ?", userIds).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			usersById[user.UID] = user
+		}
+	}
+
+	entries := make([]types.LeaderboardEntry, 0, len(members))
+	for i, member := range members {
+		userId := member.Member.(string)
+		entries = append(entries, leaderboardEntry(start+int64(i)+1, userId, usersById[userId], int64(member.Score)))
+	}
+	return entries, nil
+}
+
+func leaderboardEntry(rank int64, userId string, user models.User, score int64) types.LeaderboardEntry {
+	return types.LeaderboardEntry{
+		Rank:        rank,
+		UserID:      userId,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Score:       score,
+	}
+}
+
+func findLeaderboard(tx *gorm.DB, gameId, slug string, leaderboard *models.Leaderboard) error {
+	if err := tx.First(leaderboard, "game_id = ? AND slug = ?", gameId, slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: leaderboard not found", types.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// leaderboardPeriodKey names the period t falls into. Weeks start on Monday.
+func leaderboardPeriodKey(leaderboard models.Leaderboard, t time.Time) string {
+	day := t.UTC().Truncate(24 * time.Hour)
+	switch leaderboard.Period {
+	case models.LeaderboardPeriodDaily:
+		return day.Format(analytics.DateLayout)
+	case models.LeaderboardPeriodWeekly:
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -sinceMonday).Format(analytics.DateLayout)
+	default:
+		return allTimePeriodKey
+	}
+}
+
+func verifyScoreSignature(secret, slug, userId string, score, timestamp int64, signature string, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("%w: the game has no score secret", types.ErrInvalidInput)
+	}
+	if signature == "" {
+		return fmt.Errorf("%w: this leaderboard only accepts signed scores", types.ErrForbidden)
+	}
+	return verifySignature(secret, fmt.Sprintf("%s:%s:%d:%d", slug, userId, score, timestamp), timestamp, signature, now)
+}
+
+func verifyUnlockSignature(secret, key, userId string, timestamp int64, signature string, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("%w: the game has no score secret", types.ErrInvalidInput)
+	}
+	if signature == "" {
+		return fmt.Errorf("%w: this achievement only accepts signed unlocks", types.ErrForbidden)
+	}
+	return verifySignature(secret, fmt.Sprintf("achievement:%s:%s:%d", key, userId, timestamp), timestamp, signature, now)
+}
+
+// verifySignature checks a hex HMAC-SHA256 of message keyed with the game's
+// score secret, and that it was signed recently.
+func verifySignature(secret, message string, timestamp int64, signature string, now time.Time) error {
+	signedAt := time.Unix(timestamp, 0)
+	if signedAt.Before(now.Add(-scoreSignatureMaxAge)) || signedAt.After(now.Add(scoreSignatureMaxAge)) {
+		return fmt.Errorf("%w: signature has expired", types.ErrForbidden)
+	}
+
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", types.ErrInvalidInput)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	if !hmac.Equal(mac.Sum(nil), given) {
+		return fmt.Errorf("%w: invalid signature", types.ErrForbidden)
+	}
+	return nil
+}
+
+func setScoreSecret(tx *gorm.DB, gameId string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate score secret: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+
+	if err := tx.Model(&models.Game{}).Where("id = ?", gameId).UpdateColumn("score_secret", secret).Error; err != nil {
+		return "", fmt.Errorf("failed to store score secret: %w", err)
+	}
+	return secret, nil
+}
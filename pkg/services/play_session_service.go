@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/analytics"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	// HeartbeatInterval is how often clients are asked to ping a session.
+	HeartbeatInterval = 30 * time.Second
+	// maxHeartbeatGap caps the time credited for a single gap between pings,
+	// so a tab left in the background doesn't count as play.
+	maxHeartbeatGap = 2 * HeartbeatInterval
+	// sessionTimeout is how long a session can go without a ping before it is
+	// considered abandoned.
+	sessionTimeout = 10 * time.Minute
+)
+
+type PlaySessionService struct {
+	databaseHandler database.Handler
+}
+
+func NewPlaySessionService(databaseHandler database.Handler) *PlaySessionService {
+	return &PlaySessionService{databaseHandler: databaseHandler}
+}
+
+// StartSession opens a play session and counts it as a play of the game.
+// shareCode is the share link the player arrived through, if any. If the
+// user already has a live session for the game, that one is returned and
+// started is false.
+func (ps *PlaySessionService) StartSession(gameId, userId, shareCode string) (session models.PlaySession, started bool, err error) {
+	err = ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Scopes(database.ActiveGames).First(&game, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+
+		var err error
+		if session, started, err = startSession(tx, &game, userId, shareCode); err != nil || !started {
+			return err
+		}
+		return gamesearch.Enqueue(tx, gameId)
+	})
+
+	return session, started, err
+}
+
+// startSession opens a session and counts the play, keeping each user to one
+// open session per game: a live session is returned as it is, with started
+// false, and ones that went quiet are closed at their last ping first.
+func startSession(tx *gorm.DB, game *models.Game, userId, shareCode string) (session models.PlaySession, started bool, err error) {
+	// Two starts at once, such as two tabs, would both find no open session.
+	if err := database.AdvisoryXactLock(tx, "play_session:"+userId+":"+game.ID); err != nil {
+		return session, false, fmt.Errorf("failed to lock sessions: %w", err)
+	}
+
+	var open []models.PlaySession
+	if err := tx.Where("user_id = ? AND game_id = ? AND ended_at IS NULL", userId, game.ID).
+		Order("last_heartbeat_at DESC").
+		Find(&open).Error; err != nil {
+		return session, false, err
+	}
+	now := time.Now()
+	for i, candidate := range open {
+		if i == 0 && now.Sub(candidate.LastHeartbeatAt) <= sessionTimeout {
+			session = candidate
+			continue
+		}
+		if err := tx.Model(&candidate).Update("ended_at", candidate.LastHeartbeatAt).Error; err != nil {
+			return session, false, fmt.Errorf("failed to close abandoned session: %w", err)
+		}
+	}
+	if session.ID != "" {
+		return session, false, nil
+	}
+
+	session = models.PlaySession{
+		UserID:          userId,
+		GameID:          game.ID,
+		StartedAt:       now,
+		LastHeartbeatAt: now,
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return session, false, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	if err := recordPlay(tx, game, userId); err != nil {
+		return session, false, err
+	}
+	if err := addPlaySignal(tx, userId, game.ID, 1, 0); err != nil {
+		return session, false, err
+	}
+	if shareCode != "" {
+		if err := attributeShare(tx, &session, shareCode); err != nil {
+			return session, false, err
+		}
+	}
+	if err := analytics.Record(tx, game.ID, now, analytics.Delta{Sessions: 1}); err != nil {
+		return session, false, err
+	}
+	return session, true, nil
+}
+
+// Heartbeat credits the time since the previous ping to the session.
+func (ps *PlaySessionService) Heartbeat(gameId, sessionId, userId string) (models.PlaySession, error) {
+	return ps.advance(gameId, sessionId, userId, false)
+}
+
+// EndSession credits the time since the last ping and closes the session.
+func (ps *PlaySessionService) EndSession(gameId, sessionId, userId string) (models.PlaySession, error) {
+	return ps.advance(gameId, sessionId, userId, true)
+}
+
+func (ps *PlaySessionService) advance(gameId, sessionId, userId string, end bool) (session models.PlaySession, err error) {
+	var expired bool
+	err = ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&session, "id = ? AND game_id = ?", sessionId, gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: session not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if session.UserID != userId {
+			return fmt.Errorf("%w: session belongs to another user", types.ErrForbidden)
+		}
+		if session.EndedAt != nil {
+			return fmt.Errorf("%w: session has already ended", types.ErrConflict)
+		}
+
+		now := time.Now()
+		gap := now.Sub(session.LastHeartbeatAt)
+		updates := map[string]interface{}{}
+
+		// An abandoned session is closed at its last ping and credits nothing
+		// further, whatever the client asked for.
+		if gap > sessionTimeout {
+			expired = true
+			updates["ended_at"] = session.LastHeartbeatAt
+			session.EndedAt = &session.LastHeartbeatAt
+			return tx.Model(&session).Updates(updates).Error
+		}
+
+		if gap > maxHeartbeatGap {
+			gap = maxHeartbeatGap
+		}
+		seconds := int(gap / time.Second)
+
+		updates["last_heartbeat_at"] = now
+		updates["duration"] = gorm.Expr("duration + ?", seconds)
+		if end {
+			updates["ended_at"] = now
+			session.EndedAt = &now
+		}
+		if err := tx.Model(&session).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		session.LastHeartbeatAt = now
+		session.Duration += seconds
+
+		return creditPlayTime(tx, userId, gameId, seconds)
+	})
+	if err == nil && expired {
+		err = fmt.Errorf("%w: session expired, start a new one", types.ErrConflict)
+	}
+
+	return session, err
+}
+
+// creditPlayTime adds play time to the user's history, the recommendation
+// signal, the game's aggregate and the daily rollup.
+func creditPlayTime(tx *gorm.DB, userId, gameId string, seconds int) error {
+	if seconds <= 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.RecentlyPlayed{}).
+		Where("game_id = ? AND user_id = ?", gameId, userId).
+		Updates(map[string]interface{}{
+			"last_played_at": time.Now(),
+			"play_time":      gorm.Expr("play_time + ?", seconds),
+		}).Error; err != nil {
+		return err
+	}
+
+	if err := addPlaySignal(tx, userId, gameId, 0, seconds); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Game{}).Where("id = ?", gameId).
+		Update("play_time", gorm.Expr("play_time + ?", seconds)).Error; err != nil {
+		return err
+	}
+
+	return analytics.Record(tx, gameId, time.Now(), analytics.Delta{SessionSeconds: int64(seconds)})
+}
+
+// addPlaySignal adds plays and seconds played to the user's
+// UserGameInteraction row for the game, creating it if needed.
+func addPlaySignal(tx *gorm.DB, userId, gameId string, plays, seconds int) error {
+	now := time.Now()
+	result := tx.Model(&models.UserGameInteraction{}).
+		Where("user_id = ? AND game_id = ?", userId, gameId).
+		Updates(map[string]interface{}{
+			"play_count":       gorm.Expr("play_count + ?", plays),
+			"play_time":        gorm.Expr("play_time + ?", seconds),
+			"last_interaction": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	return tx.Create(&models.UserGameInteraction{
+		UserID:          userId,
+		GameID:          gameId,
+		PlayCount:       plays,
+		PlayTime:        seconds,
+		LastInteraction: now,
+	}).Error
+}
@@ -0,0 +1,567 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"strings"
+)
+
+const maxPlaylistGames = 200
+
+type PlaylistService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+}
+
+func NewPlaylistService(databaseHandler database.Handler, storageBackend storage.Backend) *PlaylistService {
+	return &PlaylistService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+	}
+}
+
+// ListPlaylistsByUserId returns a user's playlists, newest first. The owner
+// sees all of them, everyone else only the public ones.
+func (ps *PlaylistService) ListPlaylistsByUserId(ownerId, viewerId string, query types.PageQuery) (*types.PaginatedResponse, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", ownerId)
+		if ownerId != viewerId {
+			db = db.Where("visibility = ?", models.PlaylistVisibilityPublic)
+		}
+		return db
+	}
+
+	return ps.paginatePlaylists(filter, "created_at DESC", query)
+}
+
+// ListFollowedPlaylists returns the playlists a user follows, most recently
+// followed first. Only the user can see their own follows.
+func (ps *PlaylistService) ListFollowedPlaylists(userId, viewerId string, query types.PageQuery) (*types.PaginatedResponse, error) {
+	if userId != viewerId {
+		return nil, fmt.Errorf("%w: you can only see the playlists you follow", types.ErrForbidden)
+	}
+
+	filter := func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN playlist_follows pf ON pf.playlist_id = playlists.id AND pf.user_id = ?", userId).
+			Where("playlists.visibility <> ? OR playlists.user_id = ?", models.PlaylistVisibilityPrivate, userId)
+	}
+
+	return ps.paginatePlaylists(filter, "pf.created_at DESC", query)
+}
+
+// GetPlaylist returns one of the owner's playlists with its games.
+func (ps *PlaylistService) GetPlaylist(ownerId, playlistId, viewerId string) (*types.PlaylistResponse, error) {
+	var playlist models.Playlist
+	if err := ps.databaseHandler.DB.First(&playlist, "id = ? AND user_id = ?", playlistId, ownerId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	return ps.playlistResponse(ps.databaseHandler.DB, playlist, viewerId)
+}
+
+// GetPlaylistBySlug resolves a shared playlist link.
+func (ps *PlaylistService) GetPlaylistBySlug(slug, viewerId string) (*types.PlaylistResponse, error) {
+	var playlist models.Playlist
+	if err := ps.databaseHandler.DB.First(&playlist, "slug = ?", slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	return ps.playlistResponse(ps.databaseHandler.DB, playlist, viewerId)
+}
+
+func (ps *PlaylistService) CreatePlaylist(ownerId, viewerId string, req types.CreatePlaylistRequest) (*types.PlaylistResponse, error) {
+	if ownerId != viewerId {
+		return nil, fmt.Errorf("%w: you can only create playlists for yourself", types.ErrForbidden)
+	}
+
+	slug, err := newPlaylistSlug(req.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := models.Playlist{
+		UserID:      ownerId,
+		Slug:        slug,
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		Visibility:  req.Visibility,
+	}
+	if playlist.Visibility == "" {
+		playlist.Visibility = models.PlaylistVisibilityPublic
+	}
+
+	var res *types.PlaylistResponse
+	err = ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.User{}, "uid = ?", ownerId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: user profile does not exist", types.ErrNotFound)
+			}
+			return err
+		}
+
+		if err := tx.Create(&playlist).Error; err != nil {
+			return fmt.Errorf("failed to create playlist: %w", err)
+		}
+		if err := replacePlaylistGames(tx, playlist.ID, req.GameIDs); err != nil {
+			return err
+		}
+
+		var err error
+		res, err = ps.playlistResponse(tx, playlist, viewerId)
+		return err
+	})
+
+	return res, err
+}
+
+func (ps *PlaylistService) UpdatePlaylist(ownerId, playlistId, viewerId string, req types.UpdatePlaylistRequest) (*types.PlaylistResponse, error) {
+	var res *types.PlaylistResponse
+	err := ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := findOwnedPlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Title != nil {
+			playlist.Title = strings.TrimSpace(*req.Title)
+			updates["title"] = playlist.Title
+		}
+		if req.Description != nil {
+			playlist.Description = *req.Description
+			updates["description"] = playlist.Description
+		}
+		if req.Visibility != nil {
+			playlist.Visibility = *req.Visibility
+			updates["visibility"] = playlist.Visibility
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&playlist).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update playlist: %w", err)
+			}
+		}
+
+		var err error
+		res, err = ps.playlistResponse(tx, playlist, viewerId)
+		return err
+	})
+
+	return res, err
+}
+
+func (ps *PlaylistService) DeletePlaylist(ctx context.Context, ownerId, playlistId, viewerId string) error {
+	var playlist models.Playlist
+	err := ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := findOwnedPlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+		if err := tx.Where("playlist_id = ?", playlist.ID).Delete(&models.PlaylistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("playlist_id = ?", playlist.ID).Delete(&models.PlaylistFollow{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&playlist).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if playlist.CoverFileName != "" {
+		ps.storage.Delete(ctx, playlist.CoverFileName)
+	}
+	return nil
+}
+
+// SetPlaylistGames replaces the games of a playlist, in the given order.
+func (ps *PlaylistService) SetPlaylistGames(ownerId, playlistId, viewerId string, req types.SetPlaylistGamesRequest) (*types.PlaylistResponse, error) {
+	var res *types.PlaylistResponse
+	err := ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := findOwnedPlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+		if err := replacePlaylistGames(tx, playlist.ID, req.GameIDs); err != nil {
+			return err
+		}
+		if err := tx.Model(&playlist).Update("updated_at", gorm.Expr("NOW()")).Error; err != nil {
+			return err
+		}
+
+		var err error
+		res, err = ps.playlistResponse(tx, playlist, viewerId)
+		return err
+	})
+
+	return res, err
+}
+
+// AddPlaylistGame inserts a game at the requested position, shifting the
+// games after it, or appends it.
+func (ps *PlaylistService) AddPlaylistGame(ownerId, playlistId, viewerId string, req types.AddPlaylistGameRequest) (*types.PlaylistResponse, error) {
+	var res *types.PlaylistResponse
+	err := ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := findOwnedPlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+
+		if err := tx.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", req.GameID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+
+		var items []models.PlaylistItem
+		if err := tx.Where("playlist_id = ?", playlist.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if item.GameID == req.GameID {
+				return fmt.Errorf("%w: game is already in the playlist", types.ErrConflict)
+			}
+		}
+		if len(items) >= maxPlaylistGames {
+			return fmt.Errorf("%w: a playlist can hold at most %d games", types.ErrInvalidInput, maxPlaylistGames)
+		}
+
+		position := len(items)
+		if req.Position != nil && *req.Position < position {
+			position = *req.Position
+			if err := tx.Model(&models.PlaylistItem{}).
+				Where("playlist_id = ? AND position >= ?", playlist.ID, position).
+				Update("position", gorm.Expr("position + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		item := models.PlaylistItem{PlaylistID: playlist.ID, GameID: req.GameID, Position: position}
+		if err := tx.Create(&item).Error; err != nil {
+			return fmt.Errorf("failed to add game to playlist: %w", err)
+		}
+		if err := tx.Model(&playlist).Update("updated_at", gorm.Expr("NOW()")).Error; err != nil {
+			return err
+		}
+
+		var err error
+		res, err = ps.playlistResponse(tx, playlist, viewerId)
+		return err
+	})
+
+	return res, err
+}
+
+func (ps *PlaylistService) RemovePlaylistGame(ownerId, playlistId, gameId, viewerId string) (*types.PlaylistResponse, error) {
+	var res *types.PlaylistResponse
+	err := ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := findOwnedPlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+
+		var item models.PlaylistItem
+		if err := tx.First(&item, "playlist_id = ? AND game_id = ?", playlist.ID, gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game is not in the playlist", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PlaylistItem{}).
+			Where("playlist_id = ? AND position > ?", playlist.ID, item.Position).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&playlist).Update("updated_at", gorm.Expr("NOW()")).Error; err != nil {
+			return err
+		}
+
+		var err error
+		res, err = ps.playlistResponse(tx, playlist, viewerId)
+		return err
+	})
+
+	return res, err
+}
+
+func (ps *PlaylistService) UploadCover(ctx context.Context, ownerId, playlistId, viewerId string, file io.Reader) (*types.PlaylistResponse, error) {
+	var playlist models.Playlist
+	if err := findOwnedPlaylist(ps.databaseHandler.DB, ownerId, playlistId, viewerId, &playlist); err != nil {
+		return nil, err
+	}
+
+	data, contentType, ext, err := readImage(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("playlists/%s/%s.%s", playlist.ID, uuid.NewString(), ext)
+	if err := ps.storage.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store cover: %w", err)
+	}
+
+	previous := playlist.CoverFileName
+	if err := ps.databaseHandler.DB.Model(&playlist).Update("cover_file_name", key).Error; err != nil {
+		ps.storage.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to update cover: %w", err)
+	}
+	playlist.CoverFileName = key
+
+	if previous != "" {
+		ps.storage.Delete(ctx, previous)
+	}
+
+	return ps.playlistResponse(ps.databaseHandler.DB, playlist, viewerId)
+}
+
+// FollowPlaylist follows a playlist the viewer can see. Following twice is
+// a no-op.
+func (ps *PlaylistService) FollowPlaylist(ownerId, playlistId, viewerId string) error {
+	return ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := findVisiblePlaylist(tx, ownerId, playlistId, viewerId, &playlist); err != nil {
+			return err
+		}
+		if playlist.UserID == viewerId {
+			return fmt.Errorf("%w: you can't follow your own playlist", types.ErrInvalidInput)
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.PlaylistFollow{PlaylistID: playlist.ID, UserID: viewerId})
+		if result.Error != nil {
+			return fmt.Errorf("failed to follow playlist: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&playlist).UpdateColumn("follower_count", gorm.Expr("follower_count + 1")).Error
+	})
+}
+
+// UnfollowPlaylist stops following a playlist. It works even if the playlist
+// has since been made private.
+func (ps *PlaylistService) UnfollowPlaylist(ownerId, playlistId, viewerId string) error {
+	return ps.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var playlist models.Playlist
+		if err := tx.First(&playlist, "id = ? AND user_id = ?", playlistId, ownerId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+			}
+			return err
+		}
+
+		result := tx.Where("playlist_id = ? AND user_id = ?", playlist.ID, viewerId).Delete(&models.PlaylistFollow{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&playlist).UpdateColumn("follower_count", gorm.Expr("GREATEST(follower_count - 1, 0)")).Error
+	})
+}
+
+func (ps *PlaylistService) CoverURL(playlist models.Playlist) string {
+	if playlist.CoverFileName == "" {
+		return ""
+	}
+	return ps.storage.URL(playlist.CoverFileName)
+}
+
+func (ps *PlaylistService) playlistResponse(tx *gorm.DB, playlist models.Playlist, viewerId string) (*types.PlaylistResponse, error) {
+	if !canViewPlaylist(playlist, viewerId) {
+		return nil, fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+	}
+
+	var games []models.Game
+	if err := tx.Scopes(database.ActiveGames).
+		Joins("JOIN playlist_items ON playlist_items.game_id = games.id").
+		Where("playlist_items.playlist_id = ?", playlist.ID).
+		Order("playlist_items.position ASC").
+		Preload("Genre").
+		Find(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to get playlist games: %w", err)
+	}
+
+	res := &types.PlaylistResponse{
+		Playlist: playlist,
+		CoverURL: ps.CoverURL(playlist),
+		Games:    GameSummaries(ps.storage, games),
+	}
+
+	if viewerId != "" && viewerId != playlist.UserID {
+		var following int64
+		if err := tx.Model(&models.PlaylistFollow{}).
+			Where("playlist_id = ? AND user_id = ?", playlist.ID, viewerId).
+			Count(&following).Error; err != nil {
+			return nil, err
+		}
+		res.IsFollowing = following > 0
+	}
+
+	return res, nil
+}
+
+func (ps *PlaylistService) paginatePlaylists(filter func(db *gorm.DB) *gorm.DB, order string, query types.PageQuery) (*types.PaginatedResponse, error) {
+	var totalItems int64
+	if err := ps.databaseHandler.DB.Model(&models.Playlist{}).Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count playlists: %w", err)
+	}
+
+	var playlists []models.Playlist
+	offset := (query.Page - 1) * query.PageSize
+	if err := ps.databaseHandler.DB.Scopes(filter).
+		Order(order).
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&playlists).Error; err != nil {
+		return nil, fmt.Errorf("failed to get playlists: %w", err)
+	}
+
+	ids := make([]string, 0, len(playlists))
+	for _, playlist := range playlists {
+		ids = append(ids, playlist.ID)
+	}
+	var counts []struct {
+		PlaylistID string
+		GameCount  int64
+	}
+	if len(ids) > 0 {
+		if err := ps.databaseHandler.DB.Table("playlist_items pi").
+			Select("pi.playlist_id, COUNT(*) AS game_count").
+			Joins("JOIN games g ON g.id = pi.game_id AND "+database.ActiveGameFilter("g")).
+			Where("pi.playlist_id IN ?", ids).
+			Group("pi.playlist_id").
+			Scan(&counts).Error; err != nil {
+			return nil, fmt.Errorf("failed to count playlist games: %w", err)
+		}
+	}
+	countById := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		countById[count.PlaylistID] = count.GameCount
+	}
+
+	summaries := make([]types.PlaylistSummary, 0, len(playlists))
+	for _, playlist := range playlists {
+		summaries = append(summaries, types.PlaylistSummary{
+			Playlist:  playlist,
+			CoverURL:  ps.CoverURL(playlist),
+			GameCount: countById[playlist.ID],
+		})
+	}
+
+	return &types.PaginatedResponse{
+		Data:       summaries,
+		TotalItems: totalItems,
+		TotalPages: (int(totalItems) + query.PageSize - 1) / query.PageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+func replacePlaylistGames(tx *gorm.DB, playlistId string, gameIds []string) error {
+	if len(gameIds) > maxPlaylistGames {
+		return fmt.Errorf("%w: a playlist can hold at most %d games", types.ErrInvalidInput, maxPlaylistGames)
+	}
+
+	seen := make(map[string]bool, len(gameIds))
+	items := make([]models.PlaylistItem, 0, len(gameIds))
+	for _, id := range gameIds {
+		if seen[id] {
+			return fmt.Errorf("%w: game %s is listed more than once", types.ErrInvalidInput, id)
+		}
+		seen[id] = true
+		items = append(items, models.PlaylistItem{PlaylistID: playlistId, GameID: id, Position: len(items)})
+	}
+
+	if len(gameIds) > 0 {
+		var found int64
+		if err := tx.Model(&models.Game{}).Scopes(database.ActiveGames).Where("id IN ?", gameIds).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(gameIds) {
+			return fmt.Errorf("%w: some games do not exist", types.ErrInvalidInput)
+		}
+	}
+
+	if err := tx.Where("playlist_id = ?", playlistId).Delete(&models.PlaylistItem{}).Error; err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			return fmt.Errorf("failed to add games to playlist: %w", err)
+		}
+	}
+	return nil
+}
+
+// findOwnedPlaylist loads a playlist the viewer owns. Playlists the viewer
+// can see but doesn't own are forbidden, the rest don't exist for them.
+func findOwnedPlaylist(tx *gorm.DB, ownerId, playlistId, viewerId string, playlist *models.Playlist) error {
+	if err := findVisiblePlaylist(tx, ownerId, playlistId, viewerId, playlist); err != nil {
+		return err
+	}
+	if playlist.UserID != viewerId {
+		return fmt.Errorf("%w: only the owner can change this playlist", types.ErrForbidden)
+	}
+	return nil
+}
+
+func findVisiblePlaylist(tx *gorm.DB, ownerId, playlistId, viewerId string, playlist *models.Playlist) error {
+	if err := tx.First(playlist, "id = ? AND user_id = ?", playlistId, ownerId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+		}
+		return err
+	}
+	if !canViewPlaylist(*playlist, viewerId) {
+		return fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+	}
+	return nil
+}
+
+func canViewPlaylist(playlist models.Playlist, viewerId string) bool {
+	return playlist.Visibility != models.PlaylistVisibilityPrivate || playlist.UserID == viewerId
+}
+
+// newPlaylistSlug derives a share slug from the title with a random suffix,
+// so playlists with the same title don't collide and slugs can't be guessed
+// from the title alone.
+func newPlaylistSlug(title string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate playlist slug: %w", err)
+	}
+
+	base := slugify(title)
+	if len(base) > 48 {
+		base = strings.Trim(base[:48], "-")
+	}
+	if base == "" {
+		return hex.EncodeToString(b), nil
+	}
+	return base + "-" + hex.EncodeToString(b), nil
+}
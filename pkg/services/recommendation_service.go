@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
 	"github.com/redis/go-redis/v9"
@@ -15,7 +16,7 @@ import (
 const (
 	seenGameThreshold               = 3 * 24 * time.Hour
 	cacheExpirationTime             = 1 * time.Hour
-	recommendationCacheKey          = "user:%s:recommendations"
+	recommendationCacheKey          = "user:%s:recommendations:%s"
 	fallbackRecommendationsCacheKey = "fallback:recommendations"
 	maxRecommendations              = 25
 )
@@ -32,17 +33,24 @@ func NewRecommendationService(databaseHandler database.Handler, redisClient *red
 	}
 }
 
-func (rs *RecommendationService) GetRecommendations(userId string, page, limit int) ([]models.Game, int64, error) {
-	cacheKey := fmt.Sprintf(recommendationCacheKey, userId)
-	return rs.getRecommendationsFromCacheOrGenerate(cacheKey, func() ([]models.Game, error) {
-		return rs.generatePersonalizedRecommendations(userId)
+// GetRecommendations returns the user's feed for the device. Feeds are cached
+// per device key, since devices get different games.
+func (rs *RecommendationService) GetRecommendations(userId string, device capability.Device, page, limit int) ([]models.Game, int64, error) {
+	cacheKey := fmt.Sprintf(recommendationCacheKey, userId, device.Key())
+	games, _, err := rs.getRecommendationsFromCacheOrGenerate(cacheKey, func() ([]models.Game, error) {
+		return rs.generatePersonalizedRecommendations(userId, device)
 	}, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The whole feed is cached, so pages are cut from it here.
+	return rs.paginateAndReturnGames(games, page, limit)
 }
 
-func (rs *RecommendationService) GetFallbackRecommendations(page, limit int) ([]models.Game, int64, error) {
-	cacheKey := fmt.Sprintf("%s:%d:%d", fallbackRecommendationsCacheKey, page, limit)
+func (rs *RecommendationService) GetFallbackRecommendations(device capability.Device, page, limit int) ([]models.Game, int64, error) {
+	cacheKey := fmt.Sprintf("%s:%s:%d:%d", fallbackRecommendationsCacheKey, device.Key(), page, limit)
 	return rs.getRecommendationsFromCacheOrGenerate(cacheKey, func() ([]models.Game, error) {
-		return rs.generateFallbackRecommendations(page, limit)
+		return rs.generateFallbackRecommendations(device, page, limit)
 	}, page, limit)
 }
 
@@ -51,9 +59,9 @@ func (rs *RecommendationService) getRecommendationsFromCacheOrGenerate(cacheKey
 	if err == nil && len(cachedRecommendations) > 0 {
 		var games []models.Game
 		for _, gameJSON := range cachedRecommendations {
-			var game models.Game
-			if err := json.Unmarshal([]byte(gameJSON), &game); err == nil {
-				games = append(games, game)
+			var cached cachedGame
+			if err := json.Unmarshal([]byte(gameJSON), &cached); err == nil {
+				games = append(games, cached.game())
 			}
 		}
 		return games, int64(len(games)), nil
@@ -68,7 +76,7 @@ func (rs *RecommendationService) getRecommendationsFromCacheOrGenerate(cacheKey
 	return recommendations, int64(len(recommendations)), nil
 }
 
-func (rs *RecommendationService) generatePersonalizedRecommendations(userId string) ([]models.Game, error) {
+func (rs *RecommendationService) generatePersonalizedRecommendations(userId string, device capability.Device) ([]models.Game, error) {
 	var userInteractions []models.UserGameInteraction
 	if err := rs.db.Where("user_id = ?", userId).Find(&userInteractions).Error; err != nil {
 		return nil, err
@@ -80,7 +88,9 @@ func (rs *RecommendationService) generatePersonalizedRecommendations(userId stri
 		if err := rs.db.Preload("Genre").Where("id = ?", interaction.GameID).First(&game).Error; err != nil {
 			continue
 		}
-		score := float64(interaction.PlayCount*3 + interaction.PlayTime/60 + interaction.LikeCount*2 + interaction.BookmarkCount*1)
+		// Arriving through a friend's share link says more than finding the
+		// game alone, so those plays count extra.
+		score := float64(interaction.PlayCount*3 + interaction.PlayTime/60 + interaction.LikeCount*2 + interaction.BookmarkCount*1 + interaction.ShareArrivals*2)
 		genreScores[game.GenreID] += score
 	}
 
@@ -103,7 +113,7 @@ func (rs *RecommendationService) generatePersonalizedRecommendations(userId stri
 
 	for _, gs := range sortedGenres {
 		var genreGames []models.Game
-		if err := rs.db.Where("genre_id = ?", gs.genreID).
+		if err := rs.db.Scopes(database.ActiveGames, playableOn(device)).Where("genre_id = ?", gs.genreID).
 			Where("id NOT IN (SELECT game_id FROM user_seen_games WHERE user_id = ? AND seen_at > ?)", userId, seenThreshold).
 			Order("play_count DESC, like_count DESC").
 			Limit(10).
@@ -117,17 +127,17 @@ func (rs *RecommendationService) generatePersonalizedRecommendations(userId stri
 	}
 
 	if len(recommendations) < maxRecommendations {
-		mixedPopularGames, err := rs.getMixedPopularGames(userId, maxRecommendations-len(recommendations))
+		mixedPopularGames, err := rs.getMixedPopularGames(userId, device, maxRecommendations-len(recommendations))
 		if err != nil {
 			return nil, err
 		}
 		recommendations = append(recommendations, mixedPopularGames...)
 	}
 
-	return recommendations, nil
+	return device.Arrange(recommendations), nil
 }
 
-func (rs *RecommendationService) getMixedPopularGames(userId string, numRecommendations int) ([]models.Game, error) {
+func (rs *RecommendationService) getMixedPopularGames(userId string, device capability.Device, numRecommendations int) ([]models.Game, error) {
 	var games []models.Game
 	seenThreshold := time.Now().Add(-seenGameThreshold)
 
@@ -139,7 +149,8 @@ func (rs *RecommendationService) getMixedPopularGames(userId string, numRecommen
 			FROM user_game_interactions
 			GROUP BY game_id
 		) ugi ON g.id = ugi.game_id
-		WHERE g.id NOT IN (
+		WHERE `+feedFilter(device, "g")+`
+		AND g.id NOT IN (
 			SELECT game_id 
 			FROM user_seen_games 
 			WHERE user_id = ? AND seen_at > ?
@@ -159,10 +170,15 @@ func (rs *RecommendationService) getMixedPopularGames(userId string, numRecommen
 	return games, nil
 }
 
-func (rs *RecommendationService) generateFallbackRecommendations(page, limit int) ([]models.Game, error) {
+func (rs *RecommendationService) generateFallbackRecommendations(device capability.Device, page, limit int) ([]models.Game, error) {
 	var games []models.Game
 	offset := (page - 1) * limit
 
+	demotion := ""
+	if order := device.DemotionOrder("g"); order != "" {
+		demotion = order + ","
+	}
+
 	err := rs.db.Raw(`
 		SELECT g.* 
 		FROM games g
@@ -171,7 +187,8 @@ func (rs *RecommendationService) generateFallbackRecommendations(page, limit int
 			FROM user_game_interactions
 			GROUP BY game_id
 		) ugi ON g.id = ugi.game_id
-		ORDER BY 
+		WHERE `+feedFilter(device, "g")+`
+		ORDER BY `+demotion+`
 			(g.play_count + COALESCE(ugi.total_play_count, 0)) * 0.4 + 
 			(g.like_count + COALESCE(ugi.total_like_count, 0)) * 0.3 + 
 			COALESCE(ugi.total_play_time, 0) * 0.2 - 
@@ -187,16 +204,85 @@ func (rs *RecommendationService) generateFallbackRecommendations(page, limit int
 	return games, nil
 }
 
+// cachedGame carries the thumbnail storage keys that models.Game leaves out
+// of its JSON, so cached feeds can still resolve thumbnails.
+type cachedGame struct {
+	models.Game
+	ThumbnailFileName string
+	ThumbnailVariants models.ImageVariants
+	ThumbnailBlurhash string
+	ThumbnailColor    string
+}
+
+func newCachedGame(game models.Game) cachedGame {
+	return cachedGame{
+		Game:              game,
+		ThumbnailFileName: game.ThumbnailFileName,
+		ThumbnailVariants: game.ThumbnailVariants,
+		ThumbnailBlurhash: game.ThumbnailBlurhash,
+		ThumbnailColor:    game.ThumbnailColor,
+	}
+}
+
+func (c cachedGame) game() models.Game {
+	game := c.Game
+	game.ThumbnailFileName = c.ThumbnailFileName
+	game.ThumbnailVariants = c.ThumbnailVariants
+	game.ThumbnailBlurhash = c.ThumbnailBlurhash
+	game.ThumbnailColor = c.ThumbnailColor
+	return game
+}
+
 func (rs *RecommendationService) cacheRecommendations(cacheKey string, recommendations []models.Game) {
 	var gameJSONs []interface{}
 	for _, game := range recommendations {
-		gameJSON, _ := json.Marshal(game)
+		gameJSON, _ := json.Marshal(newCachedGame(game))
 		gameJSONs = append(gameJSONs, gameJSON)
 	}
 	rs.redisClient.RPush(context.Background(), cacheKey, gameJSONs...)
 	rs.redisClient.Expire(context.Background(), cacheKey, cacheExpirationTime)
 }
 
+// InvalidateCaches drops every cached feed, used when a game disappears from
+// or returns to the catalog.
+func (rs *RecommendationService) InvalidateCaches(ctx context.Context) error {
+	for _, pattern := range []string{fmt.Sprintf(recommendationCacheKey, "*", "*"), fallbackRecommendationsCacheKey + ":*"} {
+		if err := rs.deleteKeys(ctx, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *RecommendationService) deleteKeys(ctx context.Context, pattern string) error {
+	iter := rs.redisClient.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := rs.redisClient.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// feedFilter is the WHERE condition for feed queries that alias the games
+// table.
+func feedFilter(device capability.Device, alias string) string {
+	filter := database.ActiveGameFilter(alias)
+	if playable := device.PlayableFilter(alias); playable != "" {
+		filter += " AND " + playable
+	}
+	return filter
+}
+
+func playableOn(device capability.Device) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if playable := device.PlayableFilter("games"); playable != "" {
+			return db.Where(playable)
+		}
+		return db
+	}
+}
+
 func (rs *RecommendationService) paginateAndReturnGames(games interface{}, page, limit int) ([]models.Game, int64, error) {
 	var allGames []models.Game
 	switch v := games.(type) {
@@ -204,9 +290,9 @@ func (rs *RecommendationService) paginateAndReturnGames(games interface{}, page,
 		allGames = v
 	case []string:
 		for _, gameJSON := range v {
-			var game models.Game
-			if err := json.Unmarshal([]byte(gameJSON), &game); err == nil {
-				allGames = append(allGames, game)
+			var cached cachedGame
+			if err := json.Unmarshal([]byte(gameJSON), &cached); err == nil {
+				allGames = append(allGames, cached.game())
 			}
 		}
 	}
@@ -238,50 +324,42 @@ func (rs *RecommendationService) RecordSeenGame(userId, gameId string) error {
 	`, userId, gameId, seenGame.SeenAt).Error
 }
 
-type PlayInteraction struct {
-	GameID   string
-	PlayTime *int
+type BookmarkInteraction struct {
+	GameID string
 }
 
+// RecordInteraction adds to the user's recommendation signal for a game. The
+// game's own counters are maintained by GameService.
 func (rs *RecommendationService) RecordInteraction(userId string, interaction interface{}) error {
 	var userInteraction models.UserGameInteraction
 	var gameId string
-	var interactionType string
 
 	switch v := interaction.(type) {
-	case PlayInteraction:
+	case BookmarkInteraction:
 		gameId = v.GameID
-		interactionType = "play"
-		result := rs.db.Where("user_id = ? AND game_id = ?", userId, gameId).First(&userInteraction)
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				userInteraction = models.UserGameInteraction{
-					UserID: userId,
-					GameID: gameId,
-				}
-			} else {
-				return result.Error
-			}
-		}
-		userInteraction.PlayCount++
-		userInteraction.PlayTime += *v.PlayTime
 	case string:
 		gameId = v
-		interactionType = "like"
-		result := rs.db.Where("user_id = ? AND game_id = ?", userId, gameId).First(&userInteraction)
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				userInteraction = models.UserGameInteraction{
-					UserID: userId,
-					GameID: gameId,
-				}
-			} else {
-				return result.Error
+	default:
+		return fmt.Errorf("invalid interaction type")
+	}
+
+	result := rs.db.Where("user_id = ? AND game_id = ?", userId, gameId).First(&userInteraction)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			userInteraction = models.UserGameInteraction{
+				UserID: userId,
+				GameID: gameId,
 			}
+		} else {
+			return result.Error
 		}
+	}
+
+	switch interaction.(type) {
+	case BookmarkInteraction:
+		userInteraction.BookmarkCount++
+	case string:
 		userInteraction.LikeCount++
-	default:
-		return fmt.Errorf("invalid interaction type")
 	}
 
 	userInteraction.LastInteraction = time.Now()
@@ -296,33 +374,46 @@ func (rs *RecommendationService) RecordInteraction(userId string, interaction in
 		}
 	}
 
-	var game models.Game
-	if err := rs.db.First(&game, "id = ?", gameId).Error; err != nil {
-		return err
-	}
+	return rs.invalidateUserCacheIfActive(userId)
+}
 
+// RemoveInteraction reverses the signal left by an undone like or bookmark.
+func (rs *RecommendationService) RemoveInteraction(userId, gameId, interactionType string) error {
+	var column string
 	switch interactionType {
-	case "play":
-		if err := rs.db.Model(&game).Updates(map[string]interface{}{
-			"play_count": gorm.Expr("play_count + ?", 1),
-			"play_time":  gorm.Expr("play_time + ?", userInteraction.PlayTime),
-		}).Error; err != nil {
-			return err
-		}
 	case "like":
-		if err := rs.db.Model(&game).Update("like_count", gorm.Expr("like_count + ?", 1)).Error; err != nil {
-			return err
-		}
+		column = "like_count"
+	case "bookmark":
+		column = "bookmark_count"
+	default:
+		return fmt.Errorf("invalid interaction type")
 	}
 
+	if err := rs.db.Model(&models.UserGameInteraction{}).
+		Where("user_id = ? AND game_id = ?", userId, gameId).
+		Updates(map[string]interface{}{
+			column:             gorm.Expr("GREATEST(" + column + " - 1, 0)"),
+			"last_interaction": time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+
+	return rs.invalidateUserCacheIfActive(userId)
+}
+
+func (rs *RecommendationService) invalidateUserCacheIfActive(userId string) error {
 	shouldInvalidate, err := rs.shouldInvalidateCache(userId)
 	if err != nil {
 		return err
 	}
 
 	if shouldInvalidate {
-		cacheKey := fmt.Sprintf(recommendationCacheKey, userId)
-		rs.redisClient.Del(context.Background(), cacheKey)
+		deviceKeys := capability.Keys()
+		keys := make([]string, 0, len(deviceKeys))
+		for _, deviceKey := range deviceKeys {
+			keys = append(keys, fmt.Sprintf(recommendationCacheKey, userId, deviceKey))
+		}
+		return rs.redisClient.Del(context.Background(), keys...).Err()
 	}
 
 	return nil
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+type ReviewService struct {
+	databaseHandler database.Handler
+}
+
+func NewReviewService(databaseHandler database.Handler) *ReviewService {
+	return &ReviewService{
+		databaseHandler: databaseHandler,
+	}
+}
+
+// ListReviewsByGameId returns a page of a game's reviews, most helpful or
+// most recent first.
+func (rs *ReviewService) ListReviewsByGameId(gameId, viewerId string, query types.ListReviewsQuery) (*types.PaginatedResponse, error) {
+	db := rs.databaseHandler.DB
+	if err := db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var totalItems int64
+	if err := db.Model(&models.Review{}).Where("game_id = ?", gameId).Count(&totalItems).Error; err != nil {
+		return nil, err
+	}
+
+	order := "created_at DESC, id"
+	if query.Sort == "helpful" {
+		order = "helpful_count DESC, created_at DESC, id"
+	}
+
+	var reviews []models.Review
+	if err := db.Where("game_id = ?", gameId).
+		Order(order).
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Preload("User").
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	results, err := reviewResponses(db, reviews, viewerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PaginatedResponse{
+		Data:       results,
+		TotalItems: totalItems,
+		TotalPages: int((totalItems + int64(query.PageSize) - 1) / int64(query.PageSize)),
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// CreateReview adds the user's review of a game. Only users who have played
+// the game can review it, and only once.
+func (rs *ReviewService) CreateReview(gameId, userId string, req types.CreateReviewRequest) (*types.ReviewResponse, error) {
+	review := models.Review{
+		UserID:  userId,
+		GameID:  gameId,
+		Rating:  req.Rating,
+		Content: strings.TrimSpace(req.Content),
+	}
+
+	err := rs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return err
+		}
+
+		var played int64
+		if err := tx.Model(&models.RecentlyPlayed{}).Where("game_id = ? AND user_id = ?", gameId, userId).Count(&played).Error; err != nil {
+			return err
+		}
+		if played == 0 {
+			return fmt.Errorf("%w: play the game before reviewing it", types.ErrForbidden)
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create review: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: you have already reviewed this game", types.ErrConflict)
+		}
+
+		return applyRating(tx, gameId, 1, review.Rating)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ReviewResponse{Review: review}, nil
+}
+
+// UpdateReview edits the user's own review.
+func (rs *ReviewService) UpdateReview(gameId, reviewId, userId string, req types.UpdateReviewRequest) (*types.ReviewResponse, error) {
+	var review models.Review
+	var markedHelpful bool
+	err := rs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := findOwnedReview(tx, gameId, reviewId, userId, &review); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Rating != nil && *req.Rating != review.Rating {
+			if err := applyRating(tx, gameId, 0, *req.Rating-review.Rating); err != nil {
+				return err
+			}
+			review.Rating = *req.Rating
+			updates["rating"] = review.Rating
+		}
+		if req.Content != nil {
+			review.Content = strings.TrimSpace(*req.Content)
+			updates["content"] = review.Content
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&review).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update review: %w", err)
+			}
+		}
+
+		var err error
+		markedHelpful, err = hasMarkedHelpful(tx, review.ID, userId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ReviewResponse{Review: review, MarkedHelpful: markedHelpful}, nil
+}
+
+// DeleteReview removes the user's own review and its helpful votes.
+func (rs *ReviewService) DeleteReview(gameId, reviewId, userId string) error {
+	return rs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := findOwnedReview(tx, gameId, reviewId, userId, &review); err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewHelpfulVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+
+		return applyRating(tx, gameId, -1, -review.Rating)
+	})
+}
+
+// MarkReviewHelpful records the user's helpful vote. Voting twice is a no-op.
+func (rs *ReviewService) MarkReviewHelpful(gameId, reviewId, userId string) error {
+	return rs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := findReview(tx, gameId, reviewId, &review); err != nil {
+			return err
+		}
+		if review.UserID == userId {
+			return fmt.Errorf("%w: you can't mark your own review helpful", types.ErrInvalidInput)
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ReviewHelpfulVote{ReviewID: review.ID, UserID: userId})
+		if result.Error != nil {
+			return fmt.Errorf("failed to mark review helpful: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+}
+
+// UnmarkReviewHelpful takes back the user's helpful vote, if any.
+func (rs *ReviewService) UnmarkReviewHelpful(gameId, reviewId, userId string) error {
+	return rs.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := findReview(tx, gameId, reviewId, &review); err != nil {
+			return err
+		}
+
+		result := tx.Where("review_id = ? AND user_id = ?", review.ID, userId).Delete(&models.ReviewHelpfulVote{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&review).UpdateColumn("helpful_count", gorm.Expr("GREATEST(helpful_count - 1, 0)")).Error
+	})
+}
+
+func findReview(tx *gorm.DB, gameId, reviewId string, review *models.Review) error {
+	if err := tx.First(review, "id = ? AND game_id = ?", reviewId, gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: review not found", types.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+func findOwnedReview(tx *gorm.DB, gameId, reviewId, userId string, review *models.Review) error {
+	if err := findReview(tx, gameId, reviewId, review); err != nil {
+		return err
+	}
+	if review.UserID != userId {
+		return fmt.Errorf("%w: you can only change your own review", types.ErrForbidden)
+	}
+	return nil
+}
+
+// applyRating moves the game's rating aggregates by the given number of
+// ratings and stars. Postgres evaluates every SET expression against the old
+// row, so the average is computed from the new count and sum.
+func applyRating(tx *gorm.DB, gameId string, countDelta, sumDelta int) error {
+	err := tx.Model(&models.Game{}).Where("id = ?", gameId).UpdateColumns(map[string]interface{}{
+		"rating_count": gorm.Expr("rating_count + ?", countDelta),
+		"rating_sum":   gorm.Expr("rating_sum + ?", sumDelta),
+		"average_rating": gorm.Expr(
+			"CASE WHEN rating_count + ? > 0 THEN (rating_sum + ?)::float / (rating_count + ?) ELSE 0 END",
+			countDelta, sumDelta, countDelta,
+		),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update game rating: %w", err)
+	}
+
+	return gamesearch.Enqueue(tx, gameId)
+}
+
+func hasMarkedHelpful(tx *gorm.DB, reviewId, userId string) (bool, error) {
+	if userId == "" {
+		return false, nil
+	}
+	var count int64
+	err := tx.Model(&models.ReviewHelpfulVote{}).Where("review_id = ? AND user_id = ?", reviewId, userId).Count(&count).Error
+	return count > 0, err
+}
+
+func reviewResponses(tx *gorm.DB, reviews []models.Review, viewerId string) ([]types.ReviewResponse, error) {
+	marked := make(map[string]bool)
+	if viewerId != "" && len(reviews) > 0 {
+		ids := make([]string, 0, len(reviews))
+		for _, review := range reviews {
+			ids = append(ids, review.ID)
+		}
+
+		var votes []models.ReviewHelpfulVote
+		if err := tx.Where("user_id = ? AND review_id IN ?", viewerId, ids).Find(&votes).Error; err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			marked[vote.ReviewID] = true
+		}
+	}
+
+	results := make([]types.ReviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		results = append(results, types.ReviewResponse{Review: review, MarkedHelpful: marked[review.ID]})
+	}
+	return results, nil
+}
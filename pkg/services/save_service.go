@@ -0,0 +1,313 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxSaveSize = 1 << 20
+	// saveQuota is how much a user can store across all slots of one game.
+	saveQuota    = 8 << 20
+	maxSaveSlots = 32
+)
+
+var saveSlotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SaveService stores opaque save blobs per user, game and slot. Writes are
+// optimistic: the blob is uploaded under a new key first, then the row is
+// swapped to it only if the version the client last saw is still current.
+type SaveService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+}
+
+func NewSaveService(databaseHandler database.Handler, storageBackend storage.Backend) *SaveService {
+	return &SaveService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+	}
+}
+
+// ListSaves returns the user's saves for a game and how much of the quota
+// they use.
+func (ss *SaveService) ListSaves(gameId, userId string) (*types.SavesResponse, error) {
+	db := ss.databaseHandler.DB
+	if err := db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	saves := []models.SaveState{}
+	if err := db.Where("user_id = ? AND game_id = ?", userId, gameId).Order("slot ASC").Find(&saves).Error; err != nil {
+		return nil, err
+	}
+
+	var used int64
+	for _, save := range saves {
+		used += save.Size
+	}
+
+	return &types.SavesResponse{Saves: saves, UsedBytes: used, QuotaBytes: saveQuota, MaxSlots: maxSaveSlots}, nil
+}
+
+// GetSave returns a save and its blob. The caller closes the blob.
+func (ss *SaveService) GetSave(ctx context.Context, gameId, userId, slot string) (*models.SaveState, io.ReadCloser, error) {
+	save, err := ss.findSave(ss.databaseHandler.DB.WithContext(ctx), gameId, userId, slot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blob, err := ss.storage.Get(ctx, save.BlobKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, fmt.Errorf("%w: save data is missing", types.ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read save: %w", err)
+	}
+	return save, blob, nil
+}
+
+// PutSave writes a slot. Overwriting an existing save needs an If-Match with
+// its current version. Without one, the write only creates a new save.
+func (ss *SaveService) PutSave(ctx context.Context, gameId, userId, slot string, condition types.SaveCondition, body io.Reader) (*models.SaveState, error) {
+	if !saveSlotPattern.MatchString(slot) {
+		return nil, fmt.Errorf("%w: slot must be 1 to 64 letters, digits, dashes or underscores", types.ErrInvalidInput)
+	}
+	expected, err := parseSaveCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxSaveSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read save: %w", err)
+	}
+	if len(data) > MaxSaveSize {
+		return nil, fmt.Errorf("%w: saves are limited to %d bytes", types.ErrTooLarge, MaxSaveSize)
+	}
+
+	db := ss.databaseHandler.DB.WithContext(ctx)
+	if err := db.Scopes(database.ActiveGames).First(&models.Game{}, "id = ?", gameId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	// Blob keys are random so a save can't be fetched from a public storage
+	// URL without going through this service.
+	sum := sha256.Sum256(data)
+	blobKey := fmt.Sprintf("saves/%s/%s/%s", gameId, userId, uuid.NewString())
+	if err := ss.storage.Put(ctx, blobKey, bytes.NewReader(data), "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("failed to store save: %w", err)
+	}
+
+	save := models.SaveState{
+		UserID:   userId,
+		GameID:   gameId,
+		Slot:     slot,
+		Size:     int64(len(data)),
+		Checksum: hex.EncodeToString(sum[:]),
+		BlobKey:  blobKey,
+	}
+	var previousKey string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var current models.SaveState
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "user_id = ? AND game_id = ? AND slot = ?", userId, gameId, slot).Error
+		exists := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := expected.check(exists, current.Version); err != nil {
+			return err
+		}
+		if err := checkSaveQuota(tx, gameId, userId, slot, save.Size); err != nil {
+			return err
+		}
+
+		if !exists {
+			save.Version = 1
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&save)
+			if result.Error != nil {
+				return fmt.Errorf("failed to create save: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: the save was created by another device", types.ErrPreconditionFailed)
+			}
+			return nil
+		}
+
+		previousKey = current.BlobKey
+		save.Version = current.Version + 1
+		save.CreatedAt = current.CreatedAt
+		return tx.Model(&current).Updates(map[string]interface{}{
+			"version":  save.Version,
+			"size":     save.Size,
+			"checksum": save.Checksum,
+			"blob_key": save.BlobKey,
+		}).Error
+	})
+	if err != nil {
+		ss.storage.Delete(ctx, blobKey)
+		return nil, err
+	}
+
+	if previousKey != "" {
+		ss.storage.Delete(ctx, previousKey)
+	}
+	return ss.findSave(db, gameId, userId, slot)
+}
+
+// DeleteSave removes a slot. An If-Match, if sent, must match the current
+// version.
+func (ss *SaveService) DeleteSave(ctx context.Context, gameId, userId, slot string, condition types.SaveCondition) error {
+	expected, err := parseSaveCondition(condition)
+	if err != nil {
+		return err
+	}
+
+	var save models.SaveState
+	err = ss.databaseHandler.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&save, "user_id = ? AND game_id = ? AND slot = ?", userId, gameId, slot).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: save not found", types.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if expected.version != nil && *expected.version != save.Version {
+			return fmt.Errorf("%w: the save has changed since version %d", types.ErrPreconditionFailed, *expected.version)
+		}
+		return tx.Delete(&save).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	ss.storage.Delete(ctx, save.BlobKey)
+	return nil
+}
+
+func (ss *SaveService) findSave(db *gorm.DB, gameId, userId, slot string) (*models.SaveState, error) {
+	var save models.SaveState
+	err := db.Joins("JOIN games g ON g.id = save_states.game_id AND "+database.ActiveGameFilter("g")).
+		First(&save, "save_states.user_id = ? AND save_states.game_id = ? AND save_states.slot = ?", userId, gameId, slot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: save not found", types.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// SaveETag is the ETag header value of a save version.
+func SaveETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// saveExpectation is what a write expects the slot to look like: a specific
+// version, any existing save, or no save at all. The zero value expects no
+// save, so a write without headers can't clobber one.
+type saveExpectation struct {
+	version   *int64
+	anyExists bool
+	explicit  bool
+}
+
+func (e saveExpectation) check(exists bool, version int64) error {
+	switch {
+	case e.version != nil:
+		if !exists {
+			return fmt.Errorf("%w: the save no longer exists", types.ErrPreconditionFailed)
+		}
+		if *e.version != version {
+			return fmt.Errorf("%w: the save is at version %d, not %d", types.ErrPreconditionFailed, version, *e.version)
+		}
+	case e.anyExists:
+		if !exists {
+			return fmt.Errorf("%w: the save no longer exists", types.ErrPreconditionFailed)
+		}
+	case exists && e.explicit:
+		return fmt.Errorf("%w: the save already exists", types.ErrPreconditionFailed)
+	case exists:
+		return fmt.Errorf("%w: send If-Match with the current version to overwrite a save", types.ErrPreconditionRequired)
+	}
+	return nil
+}
+
+func parseSaveCondition(condition types.SaveCondition) (saveExpectation, error) {
+	var expected saveExpectation
+	if condition.IfNoneMatch != "" {
+		if strings.TrimSpace(condition.IfNoneMatch) != "*" {
+			return expected, fmt.Errorf("%w: If-None-Match only supports *", types.ErrInvalidInput)
+		}
+		expected.explicit = true
+	}
+
+	ifMatch := strings.TrimSpace(condition.IfMatch)
+	switch {
+	case ifMatch == "":
+	case expected.explicit:
+		return expected, fmt.Errorf("%w: send either If-Match or If-None-Match, not both", types.ErrInvalidInput)
+	case ifMatch == "*":
+		expected.anyExists = true
+	default:
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+		if err != nil {
+			return expected, fmt.Errorf("%w: malformed If-Match", types.ErrInvalidInput)
+		}
+		expected.version = &version
+	}
+	return expected, nil
+}
+
+// checkSaveQuota makes sure the new save fits next to the user's other slots
+// of the game. Writes to different slots don't lock each other's rows, so
+// the check holds a lock on the user's saves of the game until the
+// transaction ends; otherwise two devices could both fit in the last slot.
+func checkSaveQuota(tx *gorm.DB, gameId, userId, slot string, size int64) error {
+	if err := database.AdvisoryXactLock(tx, "save_quota:"+userId+":"+gameId); err != nil {
+		return fmt.Errorf("failed to lock saves: %w", err)
+	}
+
+	var usage struct {
+		Slots int
+		Bytes int64
+	}
+	if err := tx.Model(&models.SaveState{}).
+		Select("COUNT(*) AS slots, COALESCE(SUM(size), 0) AS bytes").
+		Where("user_id = ? AND game_id = ? AND slot <> ?", userId, gameId, slot).
+		Scan(&usage).Error; err != nil {
+		return err
+	}
+
+	if usage.Slots >= maxSaveSlots {
+		return fmt.Errorf("%w: a game can have at most %d save slots", types.ErrInvalidInput, maxSaveSlots)
+	}
+	if usage.Bytes+size > saveQuota {
+		return fmt.Errorf("%w: saves for this game are limited to %d bytes in total", types.ErrTooLarge, saveQuota)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	shareCodeAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// shareCodeLength gives 62^7, about 3.5 trillion, codes: enough that
+	// guessing a live one is hopeless and collisions are rare. The unique
+	// index catches the rare ones and a new code is drawn.
+	shareCodeLength   = 7
+	shareCodeAttempts = 5
+	maxReferrerLength = 512
+)
+
+type ShareLinkService struct {
+	databaseHandler database.Handler
+	publicURL       string
+	webAppURL       string
+}
+
+func NewShareLinkService(databaseHandler database.Handler, publicURL, webAppURL string) *ShareLinkService {
+	return &ShareLinkService{
+		databaseHandler: databaseHandler,
+		publicURL:       strings.TrimRight(publicURL, "/"),
+		webAppURL:       strings.TrimRight(webAppURL, "/"),
+	}
+}
+
+// CreateShareLink returns the user's link to the target, making one if they
+// don't have a live one yet. created reports whether a link was made.
+func (ss *ShareLinkService) CreateShareLink(userId string, req types.CreateShareLinkRequest) (res *types.ShareLinkResponse, created bool, err error) {
+	err = ss.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := ss.redirectURL(tx, req.TargetType, req.TargetID, userId); err != nil {
+			return err
+		}
+
+		var link models.ShareLink
+		err := tx.Where("user_id = ? AND target_type = ? AND target_id = ? AND revoked_at IS NULL", userId, req.TargetType, req.TargetID).
+			First(&link).Error
+		if err == nil {
+			res = ss.response(link)
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		for attempt := 0; attempt < shareCodeAttempts; attempt++ {
+			code, err := newShareCode()
+			if err != nil {
+				return err
+			}
+			link = models.ShareLink{
+				Code:       code,
+				UserID:     userId,
+				TargetType: req.TargetType,
+				TargetID:   req.TargetID,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link)
+			if result.Error != nil {
+				return fmt.Errorf("failed to create share link: %w", result.Error)
+			}
+			if result.RowsAffected == 1 {
+				res, created = ss.response(link), true
+				return nil
+			}
+		}
+		return fmt.Errorf("failed to create share link: no free code after %d attempts", shareCodeAttempts)
+	})
+	return res, created, err
+}
+
+// ListShareLinks returns the user's links, newest first, with how many clicks
+// and plays each one drove.
+func (ss *ShareLinkService) ListShareLinks(userId string, query types.ListShareLinksQuery) (*types.PaginatedResponse, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userId)
+		if !query.IncludeRevoked {
+			db = db.Where("revoked_at IS NULL")
+		}
+		return db
+	}
+
+	var totalItems int64
+	if err := ss.databaseHandler.DB.Model(&models.ShareLink{}).Scopes(filter).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count share links: %w", err)
+	}
+
+	var links []models.ShareLink
+	offset := (query.Page - 1) * query.PageSize
+	if err := ss.databaseHandler.DB.Scopes(filter).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+
+	data := make([]types.ShareLinkResponse, 0, len(links))
+	for _, link := range links {
+		data = append(data, *ss.response(link))
+	}
+
+	return &types.PaginatedResponse{
+		Data:       data,
+		TotalItems: totalItems,
+		TotalPages: (int(totalItems) + query.PageSize - 1) / query.PageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// RevokeShareLink stops a link from redirecting. Only its owner or an admin
+// can revoke it. Clicks and plays it already drove are kept.
+func (ss *ShareLinkService) RevokeShareLink(code, userId string) (*types.ShareLinkResponse, error) {
+	var link models.ShareLink
+	if err := ss.databaseHandler.DB.First(&link, "code = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: share link not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if link.UserID != userId {
+		admin, err := isAdmin(ss.databaseHandler.DB, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, fmt.Errorf("%w: you can only revoke your own share links", types.ErrForbidden)
+		}
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := ss.databaseHandler.DB.Model(&link).Update("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke share link: %w", err)
+		}
+		link.RevokedAt = &now
+	}
+	return ss.response(link), nil
+}
+
+// FollowShareLink returns where to send the visitor of a live link. The code
+// is passed along so the client can credit plays to the link when it starts
+// a session. The click is recorded in the background and only logged if it
+// fails, so counting it never holds up or breaks the redirect.
+func (ss *ShareLinkService) FollowShareLink(code, referrer string) (string, error) {
+	var link models.ShareLink
+	if err := ss.databaseHandler.DB.First(&link, "code = ? AND revoked_at IS NULL", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: share link not found", types.ErrNotFound)
+		}
+		return "", err
+	}
+
+	target, err := ss.redirectURL(ss.databaseHandler.DB, link.TargetType, link.TargetID, "")
+	if err != nil {
+		return "", err
+	}
+
+	go ss.recordClick(link, truncateReferrer(referrer))
+
+	separator := "?"
+	if strings.Contains(target, "?") {
+		separator = "&"
+	}
+	return target + separator + "via=" + url.QueryEscape(code), nil
+}
+
+func (ss *ShareLinkService) recordClick(link models.ShareLink, referrer string) {
+	err := ss.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.ShareClick{
+			ShareLinkID: link.ID,
+			SharerID:    link.UserID,
+			Referrer:    referrer,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&link).UpdateColumn("click_count", gorm.Expr("click_count + ?", 1)).Error
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("shareLink", link.ID).Msg("Failed to record share link click")
+	}
+}
+
+// truncateReferrer drops what Postgres won't store in a text column, invalid
+// UTF-8 and NUL bytes, and cuts the referrer to maxReferrerLength bytes
+// without splitting a character.
+func truncateReferrer(referrer string) string {
+	referrer = strings.ReplaceAll(strings.ToValidUTF8(referrer, ""), "\x00", "")
+	if len(referrer) <= maxReferrerLength {
+		return referrer
+	}
+	cut := maxReferrerLength
+	for cut > 0 && !utf8.RuneStart(referrer[cut]) {
+		cut--
+	}
+	return referrer[:cut]
+}
+
+// redirectURL checks that the target can be shared and returns where its
+// link leads: the web app when one is configured, the share pages otherwise.
+// sharerId is the user making a link, and is empty when a link is followed.
+func (ss *ShareLinkService) redirectURL(tx *gorm.DB, targetType, targetId, sharerId string) (string, error) {
+	switch targetType {
+	case models.ShareTargetGame:
+		var game models.Game
+		if err := tx.Scopes(database.ActiveGames).Select("id").First(&game, "id = ?", targetId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("%w: game not found", types.ErrNotFound)
+			}
+			return "", err
+		}
+		if ss.webAppURL != "" {
+			return ss.webAppURL + "/games/" + game.ID, nil
+		}
+		return ss.publicURL + "/share/games/" + game.ID, nil
+
+	case models.ShareTargetUser:
+		var user models.User
+		if err := tx.Select("uid", "username").First(&user, "uid = ?", targetId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("%w: user not found", types.ErrNotFound)
+			}
+			return "", err
+		}
+		if ss.webAppURL != "" {
+			return ss.webAppURL + "/users/" + url.PathEscape(user.Username), nil
+		}
+		return ss.publicURL + "/share/users/" + url.PathEscape(user.Username), nil
+
+	case models.ShareTargetPlaylist:
+		var playlist models.Playlist
+		if err := tx.First(&playlist, "id = ?", targetId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+			}
+			return "", err
+		}
+		if playlist.Visibility == models.PlaylistVisibilityPrivate {
+			if sharerId == "" || sharerId != playlist.UserID {
+				return "", fmt.Errorf("%w: playlist not found", types.ErrNotFound)
+			}
+			return "", fmt.Errorf("%w: private playlists can't be shared", types.ErrInvalidInput)
+		}
+		if ss.webAppURL != "" {
+			return ss.webAppURL + "/playlists/" + url.PathEscape(playlist.Slug), nil
+		}
+		return ss.publicURL + "/api/v1/playlists/" + url.PathEscape(playlist.Slug), nil
+	}
+	return "", fmt.Errorf("%w: unknown share target %q", types.ErrInvalidInput, targetType)
+}
+
+func (ss *ShareLinkService) response(link models.ShareLink) *types.ShareLinkResponse {
+	return &types.ShareLinkResponse{Link: link, URL: ss.publicURL + "/s/" + link.Code}
+}
+
+// attributeShare credits a new play session to the share link the player
+// arrived through. Only plays of what the link leads to count: the game
+// itself, the shared user's games or the shared playlist's games. Unknown
+// and revoked codes, and sharers playing from their own links, are ignored
+// rather than failing the session. Every attributed session is tagged with
+// the link, but the link's plays and the player's share arrivals only count
+// the first, so replaying a link can't inflate them.
+func attributeShare(tx *gorm.DB, session *models.PlaySession, code string) error {
+	var link models.ShareLink
+	if err := tx.First(&link, "code = ? AND revoked_at IS NULL", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if link.UserID == session.UserID {
+		return nil
+	}
+
+	var related int64
+	var err error
+	switch link.TargetType {
+	case models.ShareTargetGame:
+		if link.TargetID == session.GameID {
+			related = 1
+		}
+	case models.ShareTargetUser:
+		err = tx.Model(&models.Game{}).Where("id = ? AND creator_id = ?", session.GameID, link.TargetID).Count(&related).Error
+	case models.ShareTargetPlaylist:
+		err = tx.Model(&models.PlaylistItem{}).Where("playlist_id = ? AND game_id = ?", link.TargetID, session.GameID).Count(&related).Error
+	}
+	if err != nil || related == 0 {
+		return err
+	}
+
+	// Sessions for different games of the same link aren't serialized by
+	// the session lock, so the first-arrival check needs its own.
+	if err := database.AdvisoryXactLock(tx, "share_arrival:"+link.ID+":"+session.UserID); err != nil {
+		return fmt.Errorf("failed to lock share arrival: %w", err)
+	}
+	var arrivals int64
+	if err := tx.Model(&models.PlaySession{}).
+		Where("share_link_id = ? AND user_id = ?", link.ID, session.UserID).
+		Count(&arrivals).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(session).UpdateColumn("share_link_id", link.ID).Error; err != nil {
+		return fmt.Errorf("failed to attribute session: %w", err)
+	}
+	session.ShareLinkID = &link.ID
+	if arrivals > 0 {
+		return nil
+	}
+	if err := tx.Model(&link).UpdateColumn("play_count", gorm.Expr("play_count + ?", 1)).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.UserGameInteraction{}).
+		Where("user_id = ? AND game_id = ?", session.UserID, session.GameID).
+		UpdateColumn("share_arrivals", gorm.Expr("share_arrivals + ?", 1)).Error
+}
+
+func newShareCode() (string, error) {
+	code := make([]byte, shareCodeLength)
+	max := big.NewInt(int64(len(shareCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate share code: %w", err)
+		}
+		code[i] = shareCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"gorm.io/gorm"
+	"html"
+	"net/url"
+	"strings"
+)
+
+const (
+	ShareSiteName = "Hitbox"
+	// ShareCacheAge is how long, in seconds, share pages and oEmbed
+	// responses may be cached.
+	ShareCacheAge = 3600
+
+	shareDescriptionLength = 200
+	// A game's card is embedded at this size, turned around for portrait
+	// games.
+	embedLongSide  = 640
+	embedShortSide = 360
+)
+
+// ShareService builds the link previews shown when games and profiles are
+// shared: OpenGraph and Twitter card pages, embeddable game cards and oEmbed.
+type ShareService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+	publicURL       string
+	webAppURL       string
+}
+
+func NewShareService(databaseHandler database.Handler, storageBackend storage.Backend, publicURL, webAppURL string) *ShareService {
+	return &ShareService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+		publicURL:       strings.TrimRight(publicURL, "/"),
+		webAppURL:       strings.TrimRight(webAppURL, "/"),
+	}
+}
+
+func (ss *ShareService) GameSharePage(gameId string) (*types.SharePage, error) {
+	game, err := ss.findGame(gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	shareURL := ss.gameShareURL(game.ID)
+	width, height := embedSize(game)
+	page := &types.SharePage{
+		Title:        game.Title,
+		Description:  excerpt(game.Description),
+		URL:          shareURL,
+		OEmbedURL:    ss.oEmbedURL(shareURL),
+		Type:         "website",
+		TwitterCard:  "summary",
+		PlayerURL:    shareURL + "/embed",
+		PlayerWidth:  width,
+		PlayerHeight: height,
+	}
+	if page.Description == "" {
+		page.Description = fmt.Sprintf("Play %s on %s", game.Title, ShareSiteName)
+	}
+	if ss.webAppURL != "" {
+		page.AppURL = ss.webAppURL + "/games/" + game.ID
+	}
+	// Twitter only shows a player card when it also has an image to show
+	// before the player loads.
+	if page.ImageURL, page.ImageWidth, page.ImageHeight = ss.largestThumbnail(game); page.ImageURL != "" {
+		page.TwitterCard = "player"
+	}
+	return page, nil
+}
+
+func (ss *ShareService) UserSharePage(username string) (*types.SharePage, error) {
+	user, err := ss.findUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	shareURL := ss.userShareURL(user.Username)
+	page := &types.SharePage{
+		Title:       userTitle(user),
+		Description: fmt.Sprintf("@%s on %s", user.Username, ShareSiteName),
+		URL:         shareURL,
+		OEmbedURL:   ss.oEmbedURL(shareURL),
+		Type:        "profile",
+		TwitterCard: "summary",
+	}
+	if user.Bio != nil && strings.TrimSpace(*user.Bio) != "" {
+		page.Description = excerpt(*user.Bio)
+	}
+	if user.ProfileImageURL != nil {
+		page.ImageURL = *user.ProfileImageURL
+	}
+	if ss.webAppURL != "" {
+		page.AppURL = ss.webAppURL + "/users/" + url.PathEscape(user.Username)
+	}
+	return page, nil
+}
+
+// GameEmbed returns what the embeddable card of a game shows.
+func (ss *ShareService) GameEmbed(gameId string) (*types.GameEmbed, error) {
+	game, err := ss.findGame(gameId)
+	if err != nil {
+		return nil, err
+	}
+	// Games from before embed links were validated, or migrated ones, may
+	// hold links the card must not load.
+	if err := ValidateEmbedLink(game.EmbedLink); err != nil {
+		return nil, fmt.Errorf("%w: game has no playable embed", types.ErrNotFound)
+	}
+
+	imageURL, _, _ := ss.largestThumbnail(game)
+	return &types.GameEmbed{
+		Title:         game.Title,
+		EmbedLink:     game.EmbedLink,
+		ShareURL:      ss.gameShareURL(game.ID),
+		ImageURL:      imageURL,
+		DominantColor: game.ThumbnailColor,
+	}, nil
+}
+
+// OEmbed describes a share URL for sites that embed it.
+func (ss *ShareService) OEmbed(query types.OEmbedQuery) (*types.OEmbedResponse, error) {
+	rest, ok := strings.CutPrefix(query.URL, ss.publicURL+"/share/")
+	if !ok {
+		return nil, fmt.Errorf("%w: not a shareable URL", types.ErrNotFound)
+	}
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	kind, id, _ := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		return nil, fmt.Errorf("%w: not a shareable URL", types.ErrNotFound)
+	}
+
+	res := &types.OEmbedResponse{
+		Version:      "1.0",
+		ProviderName: ShareSiteName,
+		ProviderURL:  ss.publicURL,
+		CacheAge:     ShareCacheAge,
+	}
+
+	switch kind {
+	case "games":
+		game, err := ss.findGame(id)
+		if err != nil {
+			return nil, err
+		}
+
+		width, height := embedSize(game)
+		width, height = fitWithin(width, height, query.MaxWidth, query.MaxHeight)
+		res.Type = "rich"
+		res.Title = game.Title
+		res.Width, res.Height = width, height
+		res.HTML = fmt.Sprintf(
+			`<iframe src="%s" width="%d" height="%d" title="%s" frameborder="0" allow="autoplay; fullscreen; gamepad" allowfullscreen></iframe>`,
+			html.EscapeString(ss.gameShareURL(game.ID)+"/embed"), width, height, html.EscapeString(game.Title),
+		)
+		res.ThumbnailURL, res.ThumbnailWidth, res.ThumbnailHeight = ss.largestThumbnail(game)
+		if game.Creator != nil {
+			res.AuthorName = game.Creator.Username
+			res.AuthorURL = ss.userShareURL(game.Creator.Username)
+		}
+	case "users":
+		username, err := url.PathUnescape(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: not a shareable URL", types.ErrNotFound)
+		}
+		user, err := ss.findUser(username)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Type = "link"
+		res.Title = userTitle(user)
+		res.AuthorName = user.Username
+		res.AuthorURL = ss.userShareURL(user.Username)
+		if user.ProfileImageURL != nil {
+			res.ThumbnailURL = *user.ProfileImageURL
+		}
+	default:
+		return nil, fmt.Errorf("%w: not a shareable URL", types.ErrNotFound)
+	}
+	return res, nil
+}
+
+func (ss *ShareService) findGame(gameId string) (models.Game, error) {
+	var game models.Game
+	err := ss.databaseHandler.DB.Scopes(database.ActiveGames).Preload("Creator").First(&game, "id = ?", gameId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return game, fmt.Errorf("%w: game not found", types.ErrNotFound)
+	}
+	return game, err
+}
+
+func (ss *ShareService) findUser(username string) (models.User, error) {
+	var user models.User
+	err := ss.databaseHandler.DB.First(&user, "username = ?", username).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, fmt.Errorf("%w: user not found", types.ErrNotFound)
+	}
+	return user, err
+}
+
+func (ss *ShareService) gameShareURL(gameId string) string {
+	return ss.publicURL + "/share/games/" + gameId
+}
+
+func (ss *ShareService) userShareURL(username string) string {
+	return ss.publicURL + "/share/users/" + url.PathEscape(username)
+}
+
+func (ss *ShareService) oEmbedURL(shareURL string) string {
+	return ss.publicURL + "/api/v1/oembed?format=json&url=" + url.QueryEscape(shareURL)
+}
+
+// largestThumbnail returns the URL and size of the game's largest thumbnail.
+// Thumbnails from before variants existed have no known size.
+func (ss *ShareService) largestThumbnail(game models.Game) (string, int, int) {
+	thumbnail := ResolveThumbnail(ss.storage, game)
+	if thumbnail == nil {
+		return "", 0, 0
+	}
+	if len(thumbnail.Variants) == 0 {
+		return thumbnail.URL, 0, 0
+	}
+	largest := thumbnail.Variants[len(thumbnail.Variants)-1]
+	return largest.URL, largest.Width, largest.Height
+}
+
+func embedSize(game models.Game) (int, int) {
+	if game.IsLandscape {
+		return embedLongSide, embedShortSide
+	}
+	return embedShortSide, embedLongSide
+}
+
+// fitWithin scales a size down to fit the consumer's limits, keeping its
+// aspect ratio. A zero limit means no limit.
+func fitWithin(width, height, maxWidth, maxHeight int) (int, int) {
+	if maxWidth > 0 && width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if maxHeight > 0 && height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	return width, height
+}
+
+func userTitle(user models.User) string {
+	if user.DisplayName != nil && strings.TrimSpace(*user.DisplayName) != "" {
+		return fmt.Sprintf("%s (@%s)", strings.TrimSpace(*user.DisplayName), user.Username)
+	}
+	return "@" + user.Username
+}
+
+// excerpt shortens text to a preview-sized description on a word boundary.
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= shareDescriptionLength {
+		return text
+	}
+	cut := string(runes[:shareDescriptionLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/capability"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	similarityRefreshInterval = time.Hour
+	// similarityLock keeps instances from rebuilding the table at once.
+	similarityLock = "game_similarities:rebuild"
+	// maxRelatedGames is how many matches are kept per game.
+	maxRelatedGames = 20
+	// maxGenrePeers limits same-genre candidates to the most played games in
+	// the genre, so a game with no tags or players still gets matches without
+	// pairing up every game in a large genre.
+	maxGenrePeers = 50
+)
+
+// A related game's score is a weighted blend of the three signals, each of
+// which is between 0 and 1.
+const (
+	similarityTagWeight    = 0.4
+	similarityGenreWeight  = 0.2
+	similarityCoPlayWeight = 0.4
+)
+
+type SimilarityService struct {
+	db      *gorm.DB
+	storage storage.Backend
+}
+
+func NewSimilarityService(databaseHandler database.Handler, storageBackend storage.Backend) *SimilarityService {
+	return &SimilarityService{
+		db:      databaseHandler.DB,
+		storage: storageBackend,
+	}
+}
+
+// GetRelatedGames returns the precomputed matches for a game, best first.
+// Matches the device can't play are left out and ones that don't fit its
+// screen come last.
+func (ss *SimilarityService) GetRelatedGames(ctx context.Context, gameId string, device capability.Device, limit int) ([]types.RelatedGame, error) {
+	var game models.Game
+	err := ss.db.WithContext(ctx).Scopes(database.ActiveGames).Select("id").Where("id = ?", gameId).First(&game).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: game not found", types.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+
+	// Games deleted since the last rebuild drop out.
+	var similarities []models.GameSimilarity
+	db := ss.db.WithContext(ctx).
+		Joins("JOIN games g ON g.id = game_similarities.related_game_id AND "+database.ActiveGameFilter("g")).
+		Preload("RelatedGame.Genre").Preload("RelatedGame.Tags").
+		Where("game_similarities.game_id = ?", gameId)
+	if playable := device.PlayableFilter("g"); playable != "" {
+		db = db.Where(playable)
+	}
+	if demotion := device.DemotionOrder("g"); demotion != "" {
+		db = db.Order(demotion)
+	}
+	err = db.Order("game_similarities.score DESC").
+		Limit(limit).
+		Find(&similarities).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get related games: %w", err)
+	}
+
+	results := make([]types.RelatedGame, 0, len(similarities))
+	for _, similarity := range similarities {
+		results = append(results, types.RelatedGame{
+			Game:      similarity.RelatedGame,
+			Thumbnail: ResolveThumbnail(ss.storage, similarity.RelatedGame),
+			Score:     similarity.Score,
+		})
+	}
+	return results, nil
+}
+
+// Run rebuilds the similarity table until the context is cancelled.
+func (ss *SimilarityService) Run(ctx context.Context) {
+	ticker := time.NewTicker(similarityRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if rows, rebuilt, err := ss.Rebuild(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to rebuild game similarities")
+		} else if rebuilt {
+			log.Info().Int64("pairs", rows).Msg("Rebuilt game similarities")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebuild replaces every game's matches in one transaction, so readers see
+// either the old table or the new one. Only one instance rebuilds at a time;
+// rebuilt is false when another one already is.
+//
+// Candidates are games sharing a tag, a player, or a genre with the game.
+// The tag score is the Jaccard index of the two tag sets, the genre score is
+// 1 for the same genre, and the co-play score is the cosine similarity of the
+// two games' player sets, where a player is anyone in recently_played or with
+// a play in user_game_interactions.
+func (ss *SimilarityService) Rebuild(ctx context.Context) (rows int64, rebuilt bool, err error) {
+	err = ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := database.TryAdvisoryXactLock(tx, similarityLock)
+		if err != nil {
+			return fmt.Errorf("failed to lock game similarities: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		if err := tx.Exec("DELETE FROM game_similarities").Error; err != nil {
+			return fmt.Errorf("failed to clear game similarities: %w", err)
+		}
+
+		result := tx.Exec(`
+			WITH players AS (
+				SELECT user_id, game_id FROM recently_played
+				UNION
+				SELECT user_id, game_id FROM user_game_interactions
+				WHERE play_count > 0 AND deleted_at IS NULL
+			),
+			player_counts AS (
+				SELECT game_id, COUNT(*) AS players FROM players GROUP BY game_id
+			),
+			co_play AS (
+				SELECT a.game_id, b.game_id AS related_game_id, COUNT(*) AS shared
+				FROM players a JOIN players b ON b.user_id = a.user_id AND b.game_id <> a.game_id
+				GROUP BY a.game_id, b.game_id
+			),
+			tag_counts AS (
+				SELECT game_id, COUNT(*) AS tags FROM game_tags GROUP BY game_id
+			),
+			shared_tags AS (
+				SELECT a.game_id, b.game_id AS related_game_id, COUNT(*) AS shared
+				FROM game_tags a JOIN game_tags b ON b.tag_id = a.tag_id AND b.game_id <> a.game_id
+				GROUP BY a.game_id, b.game_id
+			),
+			genre_peers AS (
+				SELECT g.id AS game_id, p.id AS related_game_id
+				FROM games g JOIN (
+					SELECT id, genre_id, ROW_NUMBER() OVER (PARTITION BY genre_id ORDER BY play_count DESC, id) AS rank
+					FROM games WHERE `+database.ActiveGameFilter("games")+`
+				) p ON p.genre_id = g.genre_id AND p.id <> g.id AND p.rank <= ?
+			),
+			candidates AS (
+				SELECT game_id, related_game_id FROM shared_tags
+				UNION
+				SELECT game_id, related_game_id FROM co_play
+				UNION
+				SELECT game_id, related_game_id FROM genre_peers
+			),
+			scored AS (
+				SELECT c.game_id, c.related_game_id,
+					COALESCE(st.shared::float / (ta.tags + tb.tags - st.shared), 0) AS tag_score,
+					CASE WHEN ga.genre_id = gb.genre_id THEN 1.0 ELSE 0.0 END AS genre_score,
+					COALESCE(cp.shared / SQRT(pa.players * pb.players), 0) AS co_play_score
+				FROM candidates c
+				JOIN games ga ON ga.id = c.game_id
+				JOIN games gb ON gb.id = c.related_game_id
+				LEFT JOIN shared_tags st ON st.game_id = c.game_id AND st.related_game_id = c.related_game_id
+				LEFT JOIN tag_counts ta ON ta.game_id = c.game_id
+				LEFT JOIN tag_counts tb ON tb.game_id = c.related_game_id
+				LEFT JOIN co_play cp ON cp.game_id = c.game_id AND cp.related_game_id = c.related_game_id
+				LEFT JOIN player_counts pa ON pa.game_id = c.game_id
+				LEFT JOIN player_counts pb ON pb.game_id = c.related_game_id
+				WHERE `+database.ActiveGameFilter("ga")+` AND `+database.ActiveGameFilter("gb")+`
+			),
+			ranked AS (
+				SELECT *, ROW_NUMBER() OVER (PARTITION BY game_id ORDER BY score DESC, related_game_id) AS rank
+				FROM (
+					SELECT *, ?::float8 * tag_score + ?::float8 * genre_score + ?::float8 * co_play_score AS score FROM scored
+				) s
+			)
+			INSERT INTO game_similarities
+				(game_id, related_game_id, score, tag_score, genre_score, co_play_score, updated_at)
+			SELECT game_id, related_game_id, score, tag_score, genre_score, co_play_score, NOW()
+			FROM ranked
+			WHERE rank <= ?
+		`,
+			maxGenrePeers,
+			similarityTagWeight, similarityGenreWeight, similarityCoPlayWeight,
+			maxRelatedGames,
+		)
+		if result.Error != nil {
+			return fmt.Errorf("failed to score game similarities: %w", result.Error)
+		}
+		rows, rebuilt = result.RowsAffected, true
+		return nil
+	})
+
+	return rows, rebuilt, err
+}
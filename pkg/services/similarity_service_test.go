@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"math"
+	"testing"
+)
+
+func TestRebuildScoresGenrePeers(t *testing.T) {
+	db := testDB(t)
+
+	genre := createTestGenre(t, db, "similarity-test")
+	first := createTestGame(t, db, genre.ID, "First")
+	second := createTestGame(t, db, genre.ID, "Second")
+
+	ss := &SimilarityService{db: db}
+	rows, rebuilt, err := ss.Rebuild(context.Background())
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if !rebuilt || rows != 2 {
+		t.Fatalf("Rebuild wrote %d pairs (rebuilt %v), want 2", rows, rebuilt)
+	}
+
+	var similarity models.GameSimilarity
+	if err := db.First(&similarity, "game_id = ? AND related_game_id = ?", first.ID, second.ID).Error; err != nil {
+		t.Fatalf("failed to get similarity: %v", err)
+	}
+	if similarity.GenreScore != 1 {
+		t.Errorf("genre score is %v, want 1", similarity.GenreScore)
+	}
+	if math.Abs(similarity.Score-similarityGenreWeight) > 1e-9 {
+		t.Errorf("score is %v, want %v", similarity.Score, similarityGenreWeight)
+	}
+}
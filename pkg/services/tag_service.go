@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/gamesearch"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"gorm.io/gorm"
+	"strings"
+)
+
+const maxTagsPerGame = 10
+
+type TagService struct {
+	databaseHandler database.Handler
+	storage         storage.Backend
+}
+
+func NewTagService(databaseHandler database.Handler, storageBackend storage.Backend) *TagService {
+	return &TagService{
+		databaseHandler: databaseHandler,
+		storage:         storageBackend,
+	}
+}
+
+// tagsWithCounts selects tags with the number of active games using them.
+func (ts *TagService) tagsWithCounts() *gorm.DB {
+	return ts.databaseHandler.DB.Table("tags").
+		Select("tags.id, tags.name, COUNT(games.id) AS game_count").
+		Joins("LEFT JOIN game_tags ON game_tags.tag_id = tags.id").
+		Joins("LEFT JOIN games ON games.id = game_tags.game_id AND " + database.ActiveGameFilter("games")).
+		Where("tags.deleted_at IS NULL").
+		Group("tags.id, tags.name")
+}
+
+func (ts *TagService) ListTags(query types.ListTagsQuery) (*types.PaginatedResponse, error) {
+	var totalItems int64
+	if err := ts.databaseHandler.DB.Model(&models.Tag{}).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+
+	var tags []types.TagWithCount
+	offset := (query.Page - 1) * query.PageSize
+	if err := ts.tagsWithCounts().
+		Order("game_count DESC, tags.name ASC").
+		Offset(offset).
+		Limit(query.PageSize).
+		Scan(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	totalPages := (int(totalItems) + query.PageSize - 1) / query.PageSize
+
+	return &types.PaginatedResponse{
+		Data:       tags,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+func (ts *TagService) AutocompleteTags(query types.AutocompleteTagsQuery) ([]types.TagWithCount, error) {
+	prefix := NormalizeTagName(query.Query)
+	prefix = strings.NewReplacer("%", `\%`, "_", `\_`).Replace(prefix)
+
+	tags := []types.TagWithCount{}
+	if err := ts.tagsWithCounts().
+		Where("LOWER(tags.name) LIKE ?", prefix+"%").
+		Order("game_count DESC, tags.name ASC").
+		Limit(query.Limit).
+		Scan(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to autocomplete tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (ts *TagService) GetGamesByTagName(tagName string, query types.TagGamesQuery) (*types.PaginatedResponse, error) {
+	var tag models.Tag
+	if err := ts.databaseHandler.DB.Where("LOWER(name) = ?", NormalizeTagName(tagName)).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: tag not found", types.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	byTag := func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN game_tags ON game_tags.game_id = games.id").
+			Where("game_tags.tag_id = ?", tag.ID).
+			Scopes(database.ActiveGames)
+	}
+
+	var totalItems int64
+	if err := ts.databaseHandler.DB.Model(&models.Game{}).Scopes(byTag).Count(&totalItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to count games: %w", err)
+	}
+
+	order := "games.play_count DESC, games.like_count DESC"
+	if query.Sort == "newest" {
+		order = "games.created_at DESC"
+	}
+
+	var games []models.Game
+	offset := (query.Page - 1) * query.PageSize
+	if err := ts.databaseHandler.DB.Scopes(byTag).
+		Order(order).
+		Offset(offset).
+		Limit(query.PageSize).
+		Find(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to get games for tag: %w", err)
+	}
+
+	totalPages := (int(totalItems) + query.PageSize - 1) / query.PageSize
+
+	return &types.PaginatedResponse{
+		Data:       GameSummaries(ts.storage, games),
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+func (ts *TagService) AttachTagsByGameId(gameId, userId string, names []string) (tags []models.Tag, err error) {
+	err = ts.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		newTags, err := findOrCreateTags(tx, names)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&game).Association("Tags").Append(newTags); err != nil {
+			return fmt.Errorf("failed to attach tags: %w", err)
+		}
+		if err := gamesearch.Enqueue(tx, gameId); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&game).Association("Tags").Find(&tags); err != nil {
+			return err
+		}
+		if len(tags) > maxTagsPerGame {
+			return fmt.Errorf("%w: a game can have at most %d tags", types.ErrInvalidInput, maxTagsPerGame)
+		}
+		return nil
+	})
+
+	return tags, err
+}
+
+func (ts *TagService) DetachTagByGameId(gameId, userId, tagName string) (tags []models.Tag, err error) {
+	err = ts.databaseHandler.DB.Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := findManagedGame(tx, gameId, userId, &game); err != nil {
+			return err
+		}
+
+		var tag models.Tag
+		if err := tx.Where("LOWER(name) = ?", NormalizeTagName(tagName)).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: tag not found", types.ErrNotFound)
+			}
+			return err
+		}
+		if err := tx.Model(&game).Association("Tags").Delete(&tag); err != nil {
+			return fmt.Errorf("failed to detach tag: %w", err)
+		}
+		if err := gamesearch.Enqueue(tx, gameId); err != nil {
+			return err
+		}
+
+		return tx.Model(&game).Association("Tags").Find(&tags)
+	})
+
+	return tags, err
+}
+
+// NameResolver finds genres and tags by name for bulk loads like the catalog
+// import and the Firestore migration, creating the ones that don't exist and
+// remembering every one it resolved.
+type NameResolver struct {
+	genres map[string]string
+	tags   map[string]models.Tag
+}
+
+func NewNameResolver() *NameResolver {
+	return &NameResolver{
+		genres: make(map[string]string),
+		tags:   make(map[string]models.Tag),
+	}
+}
+
+// Genre returns the ID of the genre with the name, ignoring case. created
+// reports whether the genre had to be created.
+func (nr *NameResolver) Genre(tx *gorm.DB, name string) (id string, created bool, err error) {
+	key := strings.ToLower(name)
+	if id, ok := nr.genres[key]; ok {
+		return id, false, nil
+	}
+
+	var genre models.Genre
+	err = tx.Where("LOWER(name) = ?", key).First(&genre).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		genre, created = models.Genre{Name: name}, true
+		err = tx.Create(&genre).Error
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to resolve genre %s: %w", name, err)
+	}
+
+	nr.genres[key] = genre.ID
+	return genre.ID, created, nil
+}
+
+// Tags returns the tags with the names, which must already be normalized.
+// created lists the names of the tags that had to be created.
+func (nr *NameResolver) Tags(tx *gorm.DB, names []string) (tags []models.Tag, created []string, err error) {
+	tags = make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag, ok := nr.tags[name]
+		if !ok {
+			var isNew bool
+			if tag, isNew, err = findOrCreateTag(tx, name); err != nil {
+				return nil, nil, err
+			}
+			if isNew {
+				created = append(created, name)
+			}
+			nr.tags[name] = tag
+		}
+		tags = append(tags, tag)
+	}
+	return tags, created, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
+	"github.com/PixelzOrg/PHOLE.git/pkg/database"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	trendingCacheKey        = "trending:%s"
+	trendingGenreCacheKey   = "trending:%s:genre:%s"
+	trendingRefreshInterval = 5 * time.Minute
+	// Entries outlive a missed refresh or two, after which readers compute
+	// the window themselves.
+	trendingCacheTTL = 3 * trendingRefreshInterval
+	maxTrendingGames = 100
+)
+
+// Weights match the ones the recommender uses for a user's own signal.
+const (
+	trendingPlayWeight     = 3.0
+	trendingLikeWeight     = 2.0
+	trendingCommentWeight  = 1.5
+	trendingBookmarkWeight = 1.0
+)
+
+// trendingWindow is how far back events are counted and how fast they fade.
+// An event loses half its weight every HalfLife.
+type trendingWindow struct {
+	Span     time.Duration
+	HalfLife time.Duration
+}
+
+var trendingWindows = map[string]trendingWindow{
+	"1h":  {Span: time.Hour, HalfLife: 15 * time.Minute},
+	"24h": {Span: 24 * time.Hour, HalfLife: 6 * time.Hour},
+	"7d":  {Span: 7 * 24 * time.Hour, HalfLife: 2 * 24 * time.Hour},
+}
+
+type trendingScore struct {
+	GameID  string  `json:"gameId"`
+	GenreID string  `json:"-"`
+	Score   float64 `json:"score"`
+}
+
+type TrendingService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	storage     storage.Backend
+}
+
+func NewTrendingService(databaseHandler database.Handler, redisClient *redis.Client, storageBackend storage.Backend) *TrendingService {
+	return &TrendingService{
+		db:          databaseHandler.DB,
+		redisClient: redisClient,
+		storage:     storageBackend,
+	}
+}
+
+// GetTrendingGames returns a page of the cached trending list for the window,
+// optionally limited to one genre.
+func (ts *TrendingService) GetTrendingGames(ctx context.Context, query types.TrendingGamesQuery) (*types.PaginatedResponse, error) {
+	if _, ok := trendingWindows[query.Window]; !ok {
+		return nil, fmt.Errorf("%w: unknown window %s", types.ErrInvalidInput, query.Window)
+	}
+
+	overallKey := fmt.Sprintf(trendingCacheKey, query.Window)
+	key := overallKey
+	if query.GenreID != "" {
+		key = fmt.Sprintf(trendingGenreCacheKey, query.Window, query.GenreID)
+	}
+
+	scores, err := ts.cachedScores(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		// Refreshes always write the overall ranking, so if it is there a
+		// missing genre ranking just means nothing in the genre is trending.
+		// Otherwise nothing has been cached yet, e.g. right after a deploy.
+		exists, existsErr := ts.redisClient.Exists(ctx, overallKey).Result()
+		if existsErr != nil {
+			return nil, existsErr
+		}
+		// Only one caller computes the window; the others get an empty page
+		// until it is cached.
+		if exists == 0 {
+			refreshed, err := ts.refreshWindow(ctx, query.Window)
+			if err != nil {
+				return nil, err
+			}
+			if refreshed {
+				scores, err = ts.cachedScores(ctx, key)
+			}
+		}
+		if errors.Is(err, redis.Nil) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trending games: %w", err)
+	}
+
+	totalItems := len(scores)
+	start := (query.Page - 1) * query.PageSize
+	if start > totalItems {
+		start = totalItems
+	}
+	end := start + query.PageSize
+	if end > totalItems {
+		end = totalItems
+	}
+	page := scores[start:end]
+
+	ids := make([]string, 0, len(page))
+	for _, score := range page {
+		ids = append(ids, score.GameID)
+	}
+
+	var games []models.Game
+	if len(ids) > 0 {
+		if err := ts.db.Scopes(database.ActiveGames).Preload("Genre").Preload("Tags").
+			Where("id IN ?", ids).Find(&games).Error; err != nil {
+			return nil, fmt.Errorf("failed to get trending games: %w", err)
+		}
+	}
+
+	// Keep the ranking; games deleted since the last refresh drop out.
+	gamesById := make(map[string]models.Game, len(games))
+	for _, game := range games {
+		gamesById[game.ID] = game
+	}
+	results := make([]types.TrendingGame, 0, len(page))
+	for _, score := range page {
+		if game, ok := gamesById[score.GameID]; ok {
+			results = append(results, types.TrendingGame{Game: game, Thumbnail: ResolveThumbnail(ts.storage, game), Score: score.Score})
+		}
+	}
+
+	return &types.PaginatedResponse{
+		Data:       results,
+		TotalItems: int64(totalItems),
+		TotalPages: (totalItems + query.PageSize - 1) / query.PageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	}, nil
+}
+
+// Run refreshes every window until the context is cancelled.
+func (ts *TrendingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(trendingRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		for window := range trendingWindows {
+			if _, err := ts.refreshWindow(ctx, window); err != nil {
+				log.Error().Err(err).Str("window", window).Msg("Failed to refresh trending games")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshWindow scores every game with events in the window and caches the
+// overall ranking and one ranking per genre. Only one instance refreshes a
+// window at a time, and not again if another one just did; refreshed is
+// false when the window was left alone.
+func (ts *TrendingService) refreshWindow(ctx context.Context, window string) (refreshed bool, err error) {
+	err = ts.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := database.TryAdvisoryXactLock(tx, "trending:"+window)
+		if err != nil {
+			return fmt.Errorf("failed to lock trending window: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		// A ranking written within the last half interval was refreshed by
+		// another instance.
+		ttl, err := ts.redisClient.TTL(ctx, fmt.Sprintf(trendingCacheKey, window)).Result()
+		if err != nil {
+			return err
+		}
+		if ttl > trendingCacheTTL-trendingRefreshInterval/2 {
+			return nil
+		}
+
+		if err := ts.cacheWindow(ctx, window); err != nil {
+			return err
+		}
+		refreshed = true
+		return nil
+	})
+	return refreshed, err
+}
+
+func (ts *TrendingService) cacheWindow(ctx context.Context, window string) error {
+	scores, err := ts.scoreWindow(ctx, trendingWindows[window])
+	if err != nil {
+		return err
+	}
+
+	rankings := map[string][]trendingScore{
+		fmt.Sprintf(trendingCacheKey, window): scores,
+	}
+	for _, score := range scores {
+		key := fmt.Sprintf(trendingGenreCacheKey, window, score.GenreID)
+		rankings[key] = append(rankings[key], score)
+	}
+
+	pipe := ts.redisClient.Pipeline()
+	for key, ranking := range rankings {
+		if len(ranking) > maxTrendingGames {
+			ranking = ranking[:maxTrendingGames]
+		}
+		payload, err := json.Marshal(ranking)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, key, payload, trendingCacheTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// scoreWindow sums the decayed weight of every play, like, bookmark and
+// comment in the window, highest score first. Plays come from play sessions,
+// plus recently played rows for clients that only report plain plays.
+// Weights are cast because Postgres types a bare parameter in a UNION as text.
+func (ts *TrendingService) scoreWindow(ctx context.Context, window trendingWindow) ([]trendingScore, error) {
+	since := time.Now().Add(-window.Span)
+
+	var scores []trendingScore
+	err := ts.db.WithContext(ctx).Raw(`
+		SELECT g.id AS game_id, g.genre_id AS genre_id,
+			SUM(e.weight * EXP(-LN(2) * EXTRACT(EPOCH FROM (NOW() - e.at)) / ?::float8)) AS score
+		FROM (
+			SELECT game_id, started_at AS at, ?::float8 AS weight FROM play_sessions WHERE started_at > ?
+			UNION ALL
+			SELECT rp.game_id, rp.last_played_at, ?::float8 FROM recently_played rp
+			WHERE rp.last_played_at > ? AND NOT EXISTS (
+				SELECT 1 FROM play_sessions ps
+				WHERE ps.user_id = rp.user_id AND ps.game_id = rp.game_id AND ps.started_at > ?
+			)
+			UNION ALL
+			SELECT game_id, created_at, ?::float8 FROM likes WHERE created_at > ?
+			UNION ALL
+			SELECT game_id, created_at, ?::float8 FROM bookmarks WHERE created_at > ?
+			UNION ALL
+			SELECT game_id, created_at, ?::float8 FROM comments WHERE created_at > ? AND is_deleted = false
+		) e
+		JOIN games g ON g.id = e.game_id
+		WHERE `+database.ActiveGameFilter("g")+`
+		GROUP BY g.id, g.genre_id
+		ORDER BY score DESC
+	`,
+		window.HalfLife.Seconds(),
+		trendingPlayWeight, since,
+		trendingPlayWeight, since, since,
+		trendingLikeWeight, since,
+		trendingBookmarkWeight, since,
+		trendingCommentWeight, since,
+	).Scan(&scores).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to score trending games: %w", err)
+	}
+
+	return scores, nil
+}
+
+func (ts *TrendingService) cachedScores(ctx context.Context, key string) ([]trendingScore, error) {
+	payload, err := ts.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []trendingScore
+	if err := json.Unmarshal(payload, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
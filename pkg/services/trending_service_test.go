@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScoreWindow(t *testing.T) {
+	db := testDB(t)
+
+	genre := createTestGenre(t, db, "trending-test")
+	player := createTestUser(t, db, "trending-player")
+	fan := createTestUser(t, db, "trending-fan")
+	played := createTestGame(t, db, genre.ID, "Played")
+	liked := createTestGame(t, db, genre.ID, "Liked")
+
+	// NOW() is the transaction's start, so events stamped with it have lost
+	// none of their weight.
+	var now time.Time
+	if err := db.Raw("SELECT NOW()").Scan(&now).Error; err != nil {
+		t.Fatal(err)
+	}
+	rows := []interface{}{
+		&models.PlaySession{UserID: player.UID, GameID: played.ID, StartedAt: now, LastHeartbeatAt: now},
+		// Counted once: the session above already covers this play.
+		&models.RecentlyPlayed{UserID: player.UID, GameID: played.ID, LastPlayedAt: now},
+		&models.RecentlyPlayed{UserID: fan.UID, GameID: played.ID, LastPlayedAt: now},
+		&models.Like{UserID: fan.UID, GameID: liked.ID, CreatedAt: now},
+		&models.Bookmark{UserID: fan.UID, GameID: liked.ID, CreatedAt: now},
+		&models.Comment{UserID: fan.UID, GameID: liked.ID, Content: "nice", CreatedAt: now},
+		&models.Comment{UserID: fan.UID, GameID: liked.ID, Content: "gone", CreatedAt: now, IsDeleted: true},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+
+	ts := &TrendingService{db: db}
+	scores, err := ts.scoreWindow(context.Background(), trendingWindows["24h"])
+	if err != nil {
+		t.Fatalf("scoreWindow: %v", err)
+	}
+
+	want := []trendingScore{
+		{GameID: played.ID, GenreID: genre.ID, Score: 2 * trendingPlayWeight},
+		{GameID: liked.ID, GenreID: genre.ID, Score: trendingLikeWeight + trendingBookmarkWeight + trendingCommentWeight},
+	}
+	if len(scores) != len(want) {
+		t.Fatalf("got %d scores, want %d: %+v", len(scores), len(want), scores)
+	}
+	for i := range want {
+		if scores[i].GameID != want[i].GameID || scores[i].GenreID != want[i].GenreID {
+			t.Errorf("score %d is for game %s in genre %s, want game %s in genre %s",
+				i, scores[i].GameID, scores[i].GenreID, want[i].GameID, want[i].GenreID)
+		}
+		if math.Abs(scores[i].Score-want[i].Score) > 1e-6 {
+			t.Errorf("score %d is %v, want %v", i, scores[i].Score, want[i].Score)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/PixelzOrg/PHOLE.git/pkg/api/types"
 	"github.com/PixelzOrg/PHOLE.git/pkg/database"
 	"github.com/PixelzOrg/PHOLE.git/pkg/models"
+	"github.com/PixelzOrg/PHOLE.git/pkg/utils/storage"
 	"github.com/PixelzOrg/PHOLE.git/pkg/utils/supabase"
 	"gorm.io/gorm"
 	"time"
@@ -14,12 +15,14 @@ import (
 type UserService struct {
 	databaseHandler database.Handler
 	supabaseAuth    *supabase.SupabaseAuth
+	storage         storage.Backend
 }
 
-func NewUserService(databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth) *UserService {
+func NewUserService(databaseHandler database.Handler, supabaseAuth *supabase.SupabaseAuth, storageBackend storage.Backend) *UserService {
 	return &UserService{
 		databaseHandler: databaseHandler,
 		supabaseAuth:    supabaseAuth,
+		storage:         storageBackend,
 	}
 }
 
@@ -63,12 +66,14 @@ func (us *UserService) GetUserProfileById(userId string) (*types.GetUserProfileR
 	}
 
 	return &types.GetUserProfileResponse{
-		Username:        user.Username,
-		DisplayName:     user.DisplayName,
-		ProfileImageURL: user.ProfileImageURL,
-		Bio:             user.Bio,
-		FollowersCount:  user.FollowersCount,
-		FollowingCount:  user.FollowingCount,
+		Username:          user.Username,
+		DisplayName:       user.DisplayName,
+		ProfileImageURL:   user.ProfileImageURL,
+		Bio:               user.Bio,
+		FollowersCount:    user.FollowersCount,
+		FollowingCount:    user.FollowingCount,
+		AchievementPoints: user.AchievementPoints,
+		AchievementCount:  user.AchievementCount,
 	}, nil
 }
 
@@ -105,12 +110,12 @@ func (us *UserService) GetGamesCreatedByUserId(userId string, pagination types.P
 	var games []models.Game
 	var totalItems int64
 
-	if err := us.databaseHandler.DB.Model(&models.Game{}).Where("creator_id = ?", userId).Count(&totalItems).Error; err != nil {
+	if err := us.databaseHandler.DB.Model(&models.Game{}).Scopes(database.ActiveGames).Where("creator_id = ?", userId).Count(&totalItems).Error; err != nil {
 		return nil, fmt.Errorf("Failed to count games: %w", err)
 	}
 
 	offset := (pagination.Page - 1) * pagination.PageSize
-	if err := us.databaseHandler.DB.Where("creator_id = ?", userId).
+	if err := us.databaseHandler.DB.Scopes(database.ActiveGames).Where("creator_id = ?", userId).
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(pagination.PageSize).
@@ -121,7 +126,7 @@ func (us *UserService) GetGamesCreatedByUserId(userId string, pagination types.P
 	totalPages := (int(totalItems) + pagination.PageSize - 1) / pagination.PageSize
 
 	return &types.PaginatedResponse{
-		Data:       games,
+		Data:       GameSummaries(us.storage, games),
 		TotalItems: totalItems,
 		TotalPages: totalPages,
 		Page:       pagination.Page,
@@ -313,13 +318,18 @@ func (us *UserService) GetLikedGamesByUserId(userId string, requesterId string,
 			return err
 		}
 
-		if err := tx.Model(&models.Like{}).Where("user_id = ?", userId).Count(&totalItems).Error; err != nil {
+		if err := tx.Model(&models.Like{}).
+			Joins("JOIN games ON games.id = likes.game_id").
+			Scopes(database.ActiveGames).
+			Where("likes.user_id = ?", userId).
+			Count(&totalItems).Error; err != nil {
 			return err
 		}
 
 		offset := (pagination.Page - 1) * pagination.PageSize
 		err := tx.Table("games").
 			Joins("JOIN likes ON games.id = likes.game_id").
+			Scopes(database.ActiveGames).
 			Where("likes.user_id = ?", userId).
 			Offset(offset).
 			Limit(pagination.PageSize).
@@ -338,7 +348,7 @@ func (us *UserService) GetLikedGamesByUserId(userId string, requesterId string,
 	totalPages := (int(totalItems) + pagination.PageSize - 1) / pagination.PageSize
 
 	return &types.PaginatedResponse{
-		Data:       likedGames,
+		Data:       GameSummaries(us.storage, likedGames),
 		TotalItems: totalItems,
 		TotalPages: totalPages,
 		Page:       pagination.Page,
@@ -362,13 +372,18 @@ func (us *UserService) GetBookmarkedGamesByUserId(userId string, requesterId str
 			return err
 		}
 
-		if err := tx.Model(&models.Bookmark{}).Where("user_id = ?", userId).Count(&totalItems).Error; err != nil {
+		if err := tx.Model(&models.Bookmark{}).
+			Joins("JOIN games ON games.id = bookmarks.game_id").
+			Scopes(database.ActiveGames).
+			Where("bookmarks.user_id = ?", userId).
+			Count(&totalItems).Error; err != nil {
 			return err
 		}
 
 		offset := (pagination.Page - 1) * pagination.PageSize
 		err := tx.Table("games").
 			Joins("JOIN bookmarks ON games.id = bookmarks.game_id").
+			Scopes(database.ActiveGames).
 			Where("bookmarks.user_id = ?", userId).
 			Offset(offset).
 			Limit(pagination.PageSize).
@@ -387,7 +402,7 @@ func (us *UserService) GetBookmarkedGamesByUserId(userId string, requesterId str
 	totalPages := (int(totalItems) + pagination.PageSize - 1) / pagination.PageSize
 
 	return &types.PaginatedResponse{
-		Data:       bookmarkedGames,
+		Data:       GameSummaries(us.storage, bookmarkedGames),
 		TotalItems: totalItems,
 		TotalPages: totalPages,
 		Page:       pagination.Page,
@@ -411,13 +426,18 @@ func (us *UserService) GetRecentlyPlayedGamesByUserId(userId string, requesterId
 			return err
 		}
 
-		if err := tx.Model(&models.RecentlyPlayed{}).Where("user_id = ?", userId).Count(&totalItems).Error; err != nil {
+		if err := tx.Model(&models.RecentlyPlayed{}).
+			Joins("JOIN games ON games.id = recently_played.game_id").
+			Scopes(database.ActiveGames).
+			Where("recently_played.user_id = ?", userId).
+			Count(&totalItems).Error; err != nil {
 			return err
 		}
 
 		offset := (pagination.Page - 1) * pagination.PageSize
 		err := tx.Table("games").
 			Joins("JOIN recently_played ON games.id = recently_played.game_id").
+			Scopes(database.ActiveGames).
 			Where("recently_played.user_id = ?", userId).
 			Order("recently_played.played_at DESC").
 			Offset(offset).
@@ -437,7 +457,7 @@ func (us *UserService) GetRecentlyPlayedGamesByUserId(userId string, requesterId
 	totalPages := (int(totalItems) + pagination.PageSize - 1) / pagination.PageSize
 
 	return &types.PaginatedResponse{
-		Data:       recentlyPlayedGames,
+		Data:       GameSummaries(us.storage, recentlyPlayedGames),
 		TotalItems: totalItems,
 		TotalPages: totalPages,
 		Page:       pagination.Page,
@@ -0,0 +1,159 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	maxBodySize  = 64 << 10
+	maxRedirects = 5
+)
+
+var (
+	ErrNotFound = errors.New("document not found")
+	// ErrForbiddenAddress is returned for hosts that resolve to loopback,
+	// private, link-local or other non-public addresses.
+	ErrForbiddenAddress = errors.New("address is not public")
+	ErrForeignRedirect  = errors.New("redirect leaves the origin")
+)
+
+// nonPublicPrefixes are the special-purpose ranges netip has no predicate for.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Fetcher retrieves small text documents from third party origins, used to
+// check verification tokens that developers place on their own hosts.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (string, error)
+}
+
+type HTTP struct {
+	client *http.Client
+}
+
+// NewHTTP returns a fetcher for hosts we don't control. It only connects to
+// public addresses and only follows redirects within the requested origin, so
+// a claimed game can't point it at internal services.
+func NewHTTP(timeout time.Duration) *HTTP {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the host, bypassing dialPublic.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &HTTP{client: &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: sameOrigin,
+	}}
+}
+
+func sameOrigin(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if origin := via[0].URL; req.URL.Scheme != origin.Scheme || req.URL.Host != origin.Host {
+		return fmt.Errorf("%w: %s", ErrForeignRedirect, req.URL.Redacted())
+	}
+	return nil
+}
+
+// dialPublic runs after the host is resolved, so it sees the address that is
+// actually connected to.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *HTTP) Fetch(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return string(body), nil
+}
+
+type expectedKey struct{}
+
+// WithExpected records the document the caller expects to find. Real fetchers
+// ignore it; Stub serves it for URLs it has no canned document for.
+func WithExpected(ctx context.Context, body string) context.Context {
+	return context.WithValue(ctx, expectedKey{}, body)
+}
+
+// Stub serves canned documents so claims can be exercised locally without
+// hosting anything. URLs without a canned document answer with the expected
+// document from the context, so every claim verifies unless Documents says
+// otherwise.
+type Stub struct {
+	Documents map[string]string
+}
+
+func NewStub() *Stub {
+	return &Stub{Documents: make(map[string]string)}
+}
+
+func (s *Stub) Fetch(ctx context.Context, url string) (string, error) {
+	if body, ok := s.Documents[url]; ok {
+		return body, nil
+	}
+	if body, ok := ctx.Value(expectedKey{}).(string); ok {
+		return body, nil
+	}
+	return "", ErrNotFound
+}
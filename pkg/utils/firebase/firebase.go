@@ -3,7 +3,6 @@ package firebase
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -13,11 +12,12 @@ import (
 	"google.golang.org/api/option"
 )
 
+const StorageBucket = "joystick-database.appspot.com"
+
 type FirebaseApp struct {
-	App       *firebase.App
-	Auth      *auth.Client
-	Firestore *firestore.Client
-	Storage   *storage.Client
+	App     *firebase.App
+	Auth    *auth.Client
+	Storage *storage.Client
 }
 
 func NewFirebaseApp(credentialsFile string, backupPath string) (*FirebaseApp, error) {
@@ -28,7 +28,7 @@ func NewFirebaseApp(credentialsFile string, backupPath string) (*FirebaseApp, er
 		opt := option.WithCredentialsFile(path)
 
 		config := &firebase.Config{
-			StorageBucket: "joystick-database.appspot.com",
+			StorageBucket: StorageBucket,
 		}
 
 		app, err := firebase.NewApp(ctx, config, opt)
@@ -46,16 +46,10 @@ func NewFirebaseApp(credentialsFile string, backupPath string) (*FirebaseApp, er
 			return nil, fmt.Errorf("error getting Auth client: %v", err)
 		}
 
-		firestore, err := app.Firestore(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("error getting Firestore client: %v", err)
-		}
-
 		return &FirebaseApp{
-			App:       app,
-			Auth:      auth,
-			Firestore: firestore,
-			Storage:   storage,
+			App:     app,
+			Auth:    auth,
+			Storage: storage,
 		}, nil
 	}
 
@@ -72,10 +66,12 @@ func NewFirebaseApp(credentialsFile string, backupPath string) (*FirebaseApp, er
 	return nil, err
 }
 
-func (fa *FirebaseApp) Close() {
-	if fa.Firestore != nil {
-		if err := fa.Firestore.Close(); err != nil {
-			log.Printf("Error closing Firestore client: %v", err)
-		}
+// Firestore opens a Firestore client. Only the legacy data migration reads
+// Firestore, so it is not opened on boot. The caller closes the client.
+func (fa *FirebaseApp) Firestore(ctx context.Context) (*firestore.Client, error) {
+	client, err := fa.App.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Firestore client: %v", err)
 	}
+	return client, nil
 }
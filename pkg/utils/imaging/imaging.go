@@ -0,0 +1,168 @@
+// Package imaging turns uploaded images into resized variants and a
+// placeholder that clients can show while the real image loads.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MinWidth and MinHeight keep out images too small to fill a game card.
+	MinWidth  = 256
+	MinHeight = 144
+	// MaxDimension and MaxPixels bound decoding, since a small file can
+	// declare a huge canvas. Decoding takes about 4 bytes per pixel, so
+	// MaxPixels keeps it near 64 MB however the sides are proportioned.
+	MaxDimension = 8192
+	MaxPixels    = 16 << 20
+
+	jpegQuality = 85
+	webpQuality = 80
+	// placeholderWidth is the size the placeholder is computed from. Blurhash
+	// only keeps a few components, so more pixels would just cost time.
+	placeholderWidth = 32
+	blurhashX        = 4
+	blurhashY        = 3
+)
+
+// ErrInvalidImage is returned for uploads that can't be processed.
+var ErrInvalidImage = errors.New("invalid image")
+
+// Variant is one encoded size and format of an image. Every size is encoded
+// as WebP, which is smaller, and as JPEG for clients that can't decode WebP.
+type Variant struct {
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+type Processed struct {
+	Width    int
+	Height   int
+	Variants []Variant
+	// Blurhash is a compact blurred preview of the image.
+	Blurhash string
+	// DominantColor is the image's average color as #rrggbb, for clients
+	// that only paint a solid placeholder.
+	DominantColor string
+}
+
+// Process validates an image and renders WebP and JPEG variants for each
+// width that is not larger than the image itself, smallest first. An image
+// narrower than every width gets variants at its own size.
+func Process(data []byte, widths []int) (*Processed, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported or corrupt image", ErrInvalidImage)
+	}
+	if config.Width < MinWidth || config.Height < MinHeight {
+		return nil, fmt.Errorf("%w: image must be at least %dx%d, got %dx%d", ErrInvalidImage, MinWidth, MinHeight, config.Width, config.Height)
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, fmt.Errorf("%w: image can be at most %dx%d, got %dx%d", ErrInvalidImage, MaxDimension, MaxDimension, config.Width, config.Height)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: image can have at most %d pixels, got %dx%d", ErrInvalidImage, MaxPixels, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported or corrupt image", ErrInvalidImage)
+	}
+	bounds := src.Bounds()
+
+	processed := &Processed{Width: bounds.Dx(), Height: bounds.Dy()}
+	for _, width := range variantWidths(bounds.Dx(), widths) {
+		variants, err := encodeVariants(src, width)
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants = append(processed.Variants, variants...)
+	}
+
+	small := resize(src, placeholderWidth)
+	if processed.Blurhash, err = blurhash.Encode(blurhashX, blurhashY, small); err != nil {
+		return nil, fmt.Errorf("failed to compute blurhash: %w", err)
+	}
+	processed.DominantColor = averageColor(small)
+
+	return processed, nil
+}
+
+func variantWidths(sourceWidth int, widths []int) []int {
+	var result []int
+	for _, width := range widths {
+		if width <= sourceWidth {
+			result = append(result, width)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, sourceWidth)
+	}
+	return result
+}
+
+func encodeVariants(src image.Image, width int) ([]Variant, error) {
+	dst := resize(src, width)
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+
+	lossy, err := webp.EncodeRGB(dst, webpQuality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webp variant: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg variant: %w", err)
+	}
+
+	return []Variant{
+		{Width: w, Height: h, ContentType: "image/webp", Data: lossy},
+		{Width: w, Height: h, ContentType: "image/jpeg", Data: buf.Bytes()},
+	}, nil
+}
+
+// resize scales the image to the given width, keeping its aspect ratio.
+// Transparent areas are flattened onto white since JPEG has no alpha, and
+// the WebP variant matches it.
+func resize(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+func averageColor(img *image.RGBA) string {
+	var r, g, b, n uint64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			r, g, b, n = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), n+1
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	avg := color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n)}
+	return fmt.Sprintf("#%02x%02x%02x", avg.R, avg.G, avg.B)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	gcs "cloud.google.com/go/storage"
+)
+
+// Bucket stores objects in a Google Cloud Storage bucket, which is what
+// Firebase Storage uses under the hood.
+type Bucket struct {
+	bucket *gcs.BucketHandle
+	name   string
+}
+
+func NewBucket(bucket *gcs.BucketHandle, name string) *Bucket {
+	return &Bucket{bucket: bucket, name: name}
+}
+
+func (b *Bucket) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	w := b.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+	return w.Close()
+}
+
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := b.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return rc, err
+}
+
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	err := b.bucket.Object(key).Delete(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (b *Bucket) URL(key string) string {
+	return fmt.Sprintf("https://firebasestorage.googleapis.com/v0/b/%s/o/%s?alt=media", b.name, url.QueryEscape(key))
+}
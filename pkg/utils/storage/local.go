@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalDisk stores objects on the local filesystem, handy for development
+// without Firebase Storage. Files are expected to be served from BaseURL.
+type LocalDisk struct {
+	BaseDir string
+	BaseURL string
+}
+
+func NewLocalDisk(baseDir, baseURL string) (*LocalDisk, error) {
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalDisk{
+		BaseDir: baseDir,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (ld *LocalDisk) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return filepath.Join(ld.BaseDir, cleaned), nil
+}
+
+func (ld *LocalDisk) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	p, err := ld.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (ld *LocalDisk) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := ld.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+func (ld *LocalDisk) Delete(ctx context.Context, key string) error {
+	p, err := ld.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (ld *LocalDisk) URL(key string) string {
+	return ld.BaseURL + "/" + strings.TrimLeft(key, "/")
+}
@@ -24,6 +24,7 @@ USERNAME=${USERNAME}
 REDIS_PATH=${REDIS_PATH}
 ALGOLIA_KEY=${ALGOLIA_KEY}
 ALGOLIA_APP_ID=${ALGOLIA_APP_ID}
+STORAGE_BACKEND=${STORAGE_BACKEND}
 EOF
 
 ## Print contents of prod.env (make sure to mask sensitive data)